@@ -0,0 +1,247 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Analyzer sessions. All the state of a measurement, the options, the
+// settings of the -config file with their rule tables and the loaded program,
+// belongs to an Analyzer; the built-in tables are only read. A server
+// embedding the tool can thus measure several repositories on parallel
+// goroutines, with one Analyzer per configuration.
+
+// Analyzer is a measurement session. Measure may be called concurrently.
+type Analyzer struct {
+	cfg      analysisConfig
+	settings settings
+	prune    bool
+	workers  int
+	// changedFiles and cacheFile re-measure only the processes reaching the
+	// changed files, reusing the others from a previous output.
+	changedFiles string
+	cacheFile    string
+	compose      bool
+	anonymize    bool
+	functions    bool
+	// demangle names the built-in demanglers of -demangle; demanglers are
+	// those and the ones added with AddDemangler.
+	demangle   string
+	demanglers []Demangler
+	// rulesVersion pins the version of the built-in rules; digest is that
+	// of the options and settings (see ruleSet).
+	rulesVersion int
+	digest       string
+}
+
+// NewAnalyzer returns a session configured with the flags of the command
+// line, as in NewAnalyzer([]string{"-dedupe", "-config", "cosmic.json"}).
+func NewAnalyzer(args []string) (*Analyzer, error) {
+	fs := flag.NewFlagSet("cosmic", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	an := &Analyzer{}
+	an.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if err := an.init(); err != nil {
+		return nil, err
+	}
+	return an, nil
+}
+
+// register defines the measurement flags on fs.
+func (an *Analyzer) register(fs *flag.FlagSet) {
+	an.cfg.register(fs)
+	fs.BoolVar(&an.prune, "prune", false, "skip subtrees without classifiable movements (functions_included then only counts functions leading to movements)")
+	fs.IntVar(&an.workers, "workers", runtime.GOMAXPROCS(0), "number of processes traversed in parallel")
+	fs.StringVar(&an.changedFiles, "changed-files", "", "only re-measure processes reaching these files (whitespace- or comma-separated, or - for stdin); requires -cache")
+	fs.StringVar(&an.cacheFile, "cache", "", "previous JSON output whose processes are reused with -changed-files")
+	fs.BoolVar(&an.compose, "compose", false, "link processes running another binary of the analyzed code (os/exec) to its main process and report the composed chains")
+	fs.IntVar(&an.rulesVersion, "rules-version", 0, "fail unless the built-in classification rules are of this version, to re-measure the baselines when they change")
+	fs.BoolVar(&an.functions, "functions", false, "list the functions included in each process in the output")
+	fs.StringVar(&an.demangle, "demangle", "", "name generated code after its source artifact with these demanglers (comma-separated: mockery, protoc, wire, or all)")
+	fs.BoolVar(&an.anonymize, "anonymize", false, "replace the names of processes, functions, files and data groups of the analyzed code by consistent hashes, to share the output without revealing the code")
+}
+
+// init checks the flags once parsed and reads the -config file.
+func (an *Analyzer) init() error {
+	if an.changedFiles != "" && an.cacheFile == "" {
+		return fmt.Errorf("-changed-files requires -cache")
+	}
+	if err := an.cfg.validate(); err != nil {
+		return err
+	}
+	if an.rulesVersion != 0 && an.rulesVersion != RulesVersion {
+		return fmt.Errorf("-rules-version: the built-in rules are of version %d, not %d; measure the baselines again and update the pin", RulesVersion, an.rulesVersion)
+	}
+	ds, err := parseDemanglers(an.demangle)
+	if err != nil {
+		return fmt.Errorf("-demangle: %v", err)
+	}
+	an.demanglers = append(ds, an.demanglers...)
+	conf, err := readSettings(an.cfg.configFile)
+	if err != nil {
+		return fmt.Errorf("-config: %v", err)
+	}
+	an.settings = conf
+	an.cfg.heuristicFallback = true
+	an.digest = rulesDigest(an.cfg, conf)
+	return nil
+}
+
+// Measure measures the code at root, a module root or a package pattern.
+func (an *Analyzer) Measure(root string) (Output, error) {
+	a, err := an.load(root)
+	if err != nil {
+		return Output{}, err
+	}
+	return an.measure(a)
+}
+
+// load loads and scans the code at root.
+func (an *Analyzer) load(root string) (*analysis, error) {
+	return loadAnalysis(root, an.cfg, an.settings)
+}
+
+// measure measures the loaded code a. It does not modify a, which can be
+// measured again.
+func (an *Analyzer) measure(a *analysis) (Output, error) {
+	cfg := an.cfg
+	if a.untyped != "" {
+		out, code, err := heuristicMeasure(a.dir, a.untyped, cfg, an.settings, a.owners)
+		if err != nil {
+			return Output{}, err
+		}
+		out.RulesVersion, out.RulesDigest, out.Tool = RulesVersion, an.digest, toolVersion()
+		out.redact(a.redactions)
+		if an.anonymize {
+			out.anonymize(code)
+		}
+		return out, nil
+	}
+	entryFuncs, entryFuncsSet, localCounts, succ := a.entries, a.entryPoints, a.localCounts, a.succ
+
+	// Shared subgraphs are summarized once; the per-process reports are then
+	// independent and built on bounded workers in a deterministic order.
+	graph := newCallGraph(entryFuncs, succ)
+	measured := entryFuncs
+	// reused holds the cached reports of unaffected processes, one per HTTP method.
+	reused := map[*ssa.Function][]ProcessReport{}
+	if an.changedFiles != "" {
+		changed, full, err := parseChangedFiles(an.changedFiles, os.Stdin)
+		if err != nil {
+			return Output{}, fmt.Errorf("-changed-files: %v", err)
+		}
+		cache, err := readCache(an.cacheFile)
+		if err != nil {
+			return Output{}, fmt.Errorf("-cache: %v", err)
+		}
+		if rs, err := readRuleSet(an.cacheFile); err == nil && (rs != ruleSet{RulesVersion, an.digest}) {
+			log.Printf("-cache: %s was measured with other rules; measuring everything", an.cacheFile)
+			full = true
+		}
+		if !full {
+			affected := affectedEntries(graph, entryFuncs, inFiles(changed))
+			measured = nil
+			for _, fn := range entryFuncs {
+				if prs := cachedReports(cache, stableSource(fn, entryFuncsSet), entryFuncsSet[fn]); prs != nil && !affected[fn] {
+					reused[fn] = prs
+				} else {
+					measured = append(measured, fn)
+				}
+			}
+			graph = newCallGraph(measured, succ)
+		}
+		log.Printf("changed files: re-measuring %d of %d processes", len(measured), len(entryFuncs))
+	}
+	summaries := summarize(graph, measured, localCounts, an.prune)
+	var upperSummaries *movementSummaries
+	if a.upper != nil {
+		upperSummaries = summarize(newCallGraph(measured, a.upper), measured, localCounts, an.prune)
+	}
+	traverse := func(fn *ssa.Function) ProcessReport {
+		if prs, ok := reused[fn]; ok {
+			return prs[0]
+		}
+		pr := summaries.report(fn, localCounts)
+		pr.dedupe(cfg.uncounted(), cfg.dedupe)
+		pr.Generated = a.generated.declares(fn)
+		if an.functions {
+			pr.Functions = summaries.functions(fn)
+		}
+		pr.Handwritten = pr.handwritten(cfg)
+		if upperSummaries != nil {
+			up := upperSummaries.report(fn, localCounts)
+			up.dedupe(cfg.uncounted(), cfg.dedupe)
+			pr.CFPUpperBound = up.Entries + up.Exits + up.Reads + up.Writes
+		}
+		return pr
+	}
+
+	var out Output
+	var handwritten MovementTotals
+	for i, pr := range traverseAll(entryFuncs, an.workers, traverse) {
+		fn := entryFuncs[i]
+		ep := entryFuncsSet[fn]
+		if ep.trigger == startupTrigger && pr.Entries+pr.Exits+pr.Reads+pr.Writes == 0 {
+			// Package initialization without movements is not a process.
+			continue
+		}
+		// A reused report only contributes its counts: the route, trigger
+		// and name of an unchanged process change with its registration.
+		cached := reused[fn]
+		owners := a.owners.owns(fn)
+		var prs []ProcessReport
+		for i, v := range ep.variants() {
+			pr := pr
+			if cached != nil {
+				pr = cached[i]
+			}
+			pr.Trigger = v.trigger
+			pr.Schedule = v.schedule
+			pr.Method = v.method()
+			pr.Name = processName(fn, v, entryFuncsSet)
+			pr.Source = stableSource(fn, entryFuncsSet)
+			prs = append(prs, pr)
+		}
+		for _, pr := range prs {
+			pr.ID = processID(pr.Source, pr.Method)
+			pr.Owners = owners
+			out.Processes = append(out.Processes, pr)
+			out.TotalEntries += pr.Entries
+			out.TotalExits += pr.Exits
+			out.TotalReads += pr.Reads
+			out.TotalWrites += pr.Writes
+			out.TotalSystemEntries += pr.SystemEntries
+			handwritten.addHandwritten(pr)
+			out.CFPUpperBound += pr.CFPUpperBound
+			if pr.Handwritten != nil {
+				out.Handwritten = &handwritten
+			}
+		}
+	}
+
+	out.Teams = teamTotals(out.Processes)
+	out.RulesVersion, out.RulesDigest, out.Tool = RulesVersion, an.digest, toolVersion()
+	if an.compose {
+		out.compose(binaries(entryFuncs))
+	}
+	if len(an.demanglers) > 0 {
+		out.demangle(newDemangler(a.prog, an.demanglers))
+	}
+	out.redact(a.redactions)
+	if an.anonymize {
+		out.anonymize(a.code)
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestMeasureConcurrent measures two repositories on parallel goroutines with
+// one Analyzer, as serve does, and checks that every measurement equals the
+// sequential one: the repositories discovered in one must not leak into the
+// settings shared with the other. Run with -race.
+func TestMeasureConcurrent(t *testing.T) {
+	an, err := NewAnalyzer([]string{"-config", "testdata/repos/cosmic.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spare capacity lets an append to the shared ports write in place.
+	an.settings.Ports = append(make([]portInterface, 0, 8), an.settings.Ports...)
+	roots := []string{"testdata/repos/users", "testdata/repos/orders"}
+	want := make([]Output, len(roots))
+	for i, root := range roots {
+		if want[i], err = an.Measure(root); err != nil {
+			t.Fatalf("%s: %v", root, err)
+		}
+	}
+	const rounds = 4
+	got := make([]Output, rounds*len(roots))
+	errs := make([]error, len(got))
+	var wg sync.WaitGroup
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], errs[i] = an.Measure(roots[i%len(roots)])
+		}(i)
+	}
+	wg.Wait()
+	for i := range got {
+		root := roots[i%len(roots)]
+		if errs[i] != nil {
+			t.Fatalf("%s: %v", root, errs[i])
+		}
+		if !reflect.DeepEqual(got[i], want[i%len(roots)]) {
+			t.Errorf("%s: concurrent measurement differs from the sequential one:\n got %+v\nwant %+v", root, got[i].Processes, want[i%len(roots)].Processes)
+		}
+	}
+	if n := len(an.settings.Ports); n != 1 {
+		t.Errorf("the settings have %d ports after measuring, want the 1 of the -config file", n)
+	}
+	// The race detector misses the appends that the go list runs of the
+	// loads happen to order, so check that none wrote past the ports.
+	for _, p := range an.settings.Ports[len(an.settings.Ports):cap(an.settings.Ports)] {
+		if !reflect.DeepEqual(p, portInterface{}) {
+			t.Errorf("a measurement appended port %s.%s to the shared settings", p.Package, p.Interface)
+		}
+	}
+}
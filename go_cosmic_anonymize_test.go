@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAnonymizeDotlessModule checks that the code of a module whose path has
+// neither a dot nor a slash is hashed too, measured with types and from
+// syntax.
+func TestAnonymizeDotlessModule(t *testing.T) {
+	untyped := copyFixture(t, "shop")
+	editFile(t, filepath.Join(untyped, "main.go"), "return b", "return undefined(b)")
+	for _, root := range []string{"testdata/shop", untyped} {
+		an, err := NewAnalyzer([]string{"-anonymize", "-functions"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := an.Measure(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"shop.", "chargeCustomer", "loadSecretPricing", "main.go"} {
+			if strings.Contains(string(data), name) {
+				t.Errorf("the output of %s (grade %s) contains %s:\n%s", root, out.Grade, name, data)
+			}
+		}
+		if !strings.Contains(string(data), "os.ReadFile") {
+			t.Errorf("the output of %s (grade %s) lacks the standard library function os.ReadFile", root, out.Grade)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// AWS storage support. S3 objects and DynamoDB items are persistent storage:
+// their operations are Reads and Writes of the service clients, the v1
+// interfaces (s3iface) and the transfer managers. The data group is the
+// bucket or table named in the input struct, as in
+// &s3.GetObjectInput{Bucket: aws.String("invoices"), ...}.
+
+var (
+	// s3Reads and s3Writes are the S3 object operations, with their
+	// aws-sdk-go WithContext and Pages variants.
+	s3Reads = map[string]bool{
+		"GetObject": true, "GetObjectWithContext": true,
+		"HeadObject": true, "HeadObjectWithContext": true,
+		"ListObjects": true, "ListObjectsWithContext": true,
+		"ListObjectsPages": true, "ListObjectsPagesWithContext": true,
+		"ListObjectsV2": true, "ListObjectsV2WithContext": true,
+		"ListObjectsV2Pages": true, "ListObjectsV2PagesWithContext": true,
+		"SelectObjectContent": true, "SelectObjectContentWithContext": true,
+		"NextPage": true, // aws-sdk-go-v2 paginators
+	}
+	s3Writes = map[string]bool{
+		"PutObject": true, "PutObjectWithContext": true,
+		"CopyObject": true, "CopyObjectWithContext": true,
+		"DeleteObject": true, "DeleteObjectWithContext": true,
+		"DeleteObjects": true, "DeleteObjectsWithContext": true,
+		"CompleteMultipartUpload": true, "CompleteMultipartUploadWithContext": true,
+	}
+	// s3TransferReads and s3TransferWrites are the transfer manager operations.
+	s3TransferReads = map[string]bool{
+		"Download": true, "DownloadWithContext": true,
+	}
+	s3TransferWrites = map[string]bool{
+		"Upload": true, "UploadWithContext": true,
+	}
+
+	// dynamoDBReads and dynamoDBWrites are the DynamoDB item operations.
+	dynamoDBReads = map[string]bool{
+		"GetItem": true, "GetItemWithContext": true,
+		"BatchGetItem": true, "BatchGetItemWithContext": true,
+		"Query": true, "QueryWithContext": true, "QueryPages": true, "QueryPagesWithContext": true,
+		"Scan": true, "ScanWithContext": true, "ScanPages": true, "ScanPagesWithContext": true,
+		"TransactGetItems": true, "TransactGetItemsWithContext": true,
+		"NextPage": true,
+	}
+	dynamoDBWrites = map[string]bool{
+		"PutItem": true, "PutItemWithContext": true,
+		"UpdateItem": true, "UpdateItemWithContext": true,
+		"DeleteItem": true, "DeleteItemWithContext": true,
+		"BatchWriteItem": true, "BatchWriteItemWithContext": true,
+		"TransactWriteItems": true, "TransactWriteItemsWithContext": true,
+	}
+)
+
+// awsPkgPrefixes are the path prefixes of the AWS SDKs.
+var awsPkgPrefixes = []string{"github.com/aws/aws-sdk-go/", "github.com/aws/aws-sdk-go-v2/"}
+
+// isAWSCall reports whether call calls a function or method of the AWS SDKs.
+func isAWSCall(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return call.Method.Pkg() != nil && hasAnyPrefix(call.Method.Pkg().Path(), awsPkgPrefixes)
+	}
+	sc := call.StaticCallee()
+	return sc != nil && sc.Pkg != nil && sc.Pkg.Pkg != nil && hasAnyPrefix(sc.Pkg.Pkg.Path(), awsPkgPrefixes)
+}
+
+// awsResource returns the bucket or table an AWS operation works on, from
+// the Bucket or TableName field of its input, also given to the constructor
+// of a paginator, or "".
+func awsResource(args []ssa.Value, depth int) string {
+	for _, arg := range args {
+		for _, field := range []string{"Bucket", "TableName"} {
+			for _, v := range fieldStores(arg, field) {
+				if s := awsString(v); s != "" {
+					return s
+				}
+			}
+		}
+		// s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{...}).NextPage(ctx)
+		if call, ok := arg.(*ssa.Call); ok && depth < 2 {
+			if sc := call.Call.StaticCallee(); sc != nil && strings.HasSuffix(sc.Name(), "Paginator") {
+				if s := awsResource(call.Call.Args, depth+1); s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// awsString returns the constant string of v or of aws.String(v), or "".
+func awsString(v ssa.Value) string {
+	if s, ok := constString(v); ok {
+		return s
+	}
+	call, ok := v.(*ssa.Call)
+	if !ok || len(call.Call.Args) != 1 {
+		return ""
+	}
+	if sc := call.Call.StaticCallee(); sc != nil && sc.Name() == "String" && isAWSCall(&call.Call) {
+		s, _ := constString(call.Call.Args[0])
+		return s
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Batch ingestion. A batch job walking a directory and reading every file is
+// one functional process moving one data group, the files under the walked
+// root. The walk callbacks are called by the walk on behalf of the caller, so
+// they are added to its callees, and their unnamed file reads are named after
+// the root. With -dedupe the reads of the group are then counted once.
+
+// callbackFuncs are functions calling a function argument for each element
+// they visit. Map of package path -> function name -> index of the callback
+// argument; the first argument is the root being visited.
+var callbackFuncs = map[string]map[string]int{
+	"path/filepath": {
+		"Walk":    1,
+		"WalkDir": 1,
+	},
+	"io/fs": {
+		"WalkDir": 2,
+	},
+	// afero.Walk(fs, root, fn) and Afero.Walk(root, fn)
+	aferoPkgPath: {
+		"Walk": 2,
+	},
+}
+
+// batchFileGroup is the data group of files read under a non-constant root.
+const batchFileGroup = "files"
+
+// collectCallbacks records, for the walks fn performs, the callbacks as extra
+// callees of fn and the data group of each callback's file reads. The
+// callbacks of the transactions fn runs are extra callees too.
+func collectCallbacks(fn *ssa.Function, routes *routeTable, callees map[*ssa.Function][]*ssa.Function, groups map[*ssa.Function]string) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			common := call.Common()
+			sc := common.StaticCallee()
+			if sc == nil || sc.Pkg == nil || sc.Pkg.Pkg == nil {
+				continue
+			}
+			if idx, ok := transactionFuncs[sc.Pkg.Pkg.Path()][sc.Name()]; ok && idx < len(common.Args) {
+				callees[fn] = append(callees[fn], extractFunctionsFromValue(common.Args[idx], routes)...)
+				continue
+			}
+			idx, ok := callbackFuncs[sc.Pkg.Pkg.Path()][sc.Name()]
+			if !ok || idx >= len(common.Args) {
+				continue
+			}
+			group := batchFileGroup
+			if root, ok := constString(common.Args[idx-1]); ok {
+				group = root + "/*"
+			}
+			for _, cb := range extractFunctionsFromValue(common.Args[idx], routes) {
+				callees[fn] = append(callees[fn], cb)
+				groups[cb] = group
+			}
+		}
+	}
+}
+
+// nameFileReads names the unnamed file reads recorded so far after group.
+func (c *Counts) nameFileReads(group string) {
+	for i, m := range c.Movements {
+		if m.Type == MovementRead && m.DataGroup == "" && isFileAccess(m.Callee) {
+			c.Movements[i].DataGroup = group
+		}
+	}
+}
+
+// isFileAccess reports whether callee, as recorded in a movement, is a file
+// access of the standard library or a filesystem abstraction.
+func isFileAccess(callee string) bool {
+	for _, prefix := range []string{"os.", "io/ioutil.", "(*os.File).", "io/fs.", "(io/fs.", aferoPkgPath + ".", "(" + aferoPkgPath + ".", "(*" + aferoPkgPath + "."} {
+		if strings.HasPrefix(callee, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe applies the COSMIC rule that a process moves a data group once per
+// movement type: repeated movements of a named data group are kept in the
+// movement list but counted once. Movements carrying an uncounted tag
+// (-exclude-infra, -system-entries) stay uncounted. Unless all is set, the
+// rule is only applied to the reads of the request (TagRequest).
+func (pr *ProcessReport) dedupe(uncounted []string, all bool) {
+	type key struct {
+		typ, group string
+		counted    bool
+	}
+	seen := map[key]bool{}
+	for _, m := range pr.Movements {
+		if m.DataGroup == "" || !all && !hasTag(m, TagRequest) {
+			continue
+		}
+		k := key{m.Type, m.DataGroup, !hasAnyTag(m, uncounted)}
+		if !seen[k] {
+			seen[k] = true
+			continue
+		}
+		if hasTag(m, TagSystem) {
+			pr.SystemEntries--
+		}
+		if !k.counted {
+			continue
+		}
+		switch m.Type {
+		case MovementEntry:
+			pr.Entries--
+		case MovementExit:
+			pr.Exits--
+		case MovementRead:
+			pr.Reads--
+		case MovementWrite:
+			pr.Writes--
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Team budgets. In a monorepo, the growth of the functional size is a
+// budget of each team rather than of the whole code: "budgets" attributes the
+// processes of a baseline and a current measurement to the teams owning them
+// (see codeOwners) and checks the growth of each team's size against the
+// budgets of the -config file, so that CI fails the teams whose processes
+// grew past their budget and no other.
+
+// teamBudget is the accepted growth of the size of the processes of Team, or
+// of every team without a budget of its own when Team is "*".
+type teamBudget struct {
+	Team string `json:"team"`
+	// Growth is in CFP (5) or relative to the baseline size (10%).
+	Growth string `json:"growth"`
+}
+
+// defaultBudgetTeam names the budget of the teams without one.
+const defaultBudgetTeam = "*"
+
+// compileBudgets returns the growths of the budgets by team.
+func compileBudgets(budgets []teamBudget) (map[string]tolerance, error) {
+	res := map[string]tolerance{}
+	for _, b := range budgets {
+		if b.Team == "" {
+			return nil, fmt.Errorf("budget: team is required")
+		}
+		if _, ok := res[b.Team]; ok {
+			return nil, fmt.Errorf("budget of %s: given twice", b.Team)
+		}
+		var t tolerance
+		if err := t.Set(b.Growth); err != nil {
+			return nil, fmt.Errorf("budget of %s: growth: %v", b.Team, err)
+		}
+		res[b.Team] = t
+	}
+	return res, nil
+}
+
+// budgetOf returns the budget of team, or false if it has none.
+func (s settings) budgetOf(team string) (tolerance, bool) {
+	if t, ok := s.budgets[team]; ok {
+		return t, true
+	}
+	t, ok := s.budgets[defaultBudgetTeam]
+	return t, ok
+}
+
+// allowsGrowth reports whether growing from size a to size b stays within
+// t, relative to a when t is.
+func (t tolerance) allowsGrowth(a, b int) bool {
+	growth := float64(b - a)
+	if t.relative {
+		return growth*100 <= t.value*float64(a)
+	}
+	return growth <= t.value
+}
+
+// runBudgets implements "budgets -config <file> <baseline.json>
+// <current.json>": it prints for every team owning processes of either
+// measurement the growth of their size and whether it is within the team's
+// budget, and exits with status 1 if a team exceeded its budget. Teams
+// without a budget are reported and not checked.
+func runBudgets(args []string) {
+	fs := flag.NewFlagSet("budgets", flag.ExitOnError)
+	configFile := fs.String("config", "", "JSON file with the budgets of the teams")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s budgets -config <file> <baseline.json> <current.json>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 || *configFile == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	conf, err := readSettings(*configFile)
+	if err != nil {
+		log.Fatalf("-config: %v", err)
+	}
+	if len(conf.budgets) == 0 {
+		log.Fatalf("-config: %s has no budgets", *configFile)
+	}
+	baseline, err := readTeamTotals(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("budgets: %v", err)
+	}
+	current, err := readTeamTotals(fs.Arg(1))
+	if err != nil {
+		log.Fatalf("budgets: %v", err)
+	}
+	warnRules(fs.Arg(0), fs.Arg(1))
+	if len(baseline)+len(current) == 0 {
+		log.Fatalf("budgets: no process is owned by a team; see CODEOWNERS and the owners of -config")
+	}
+
+	var teams []string
+	for team := range baseline {
+		teams = append(teams, team)
+	}
+	for team := range current {
+		if _, ok := baseline[team]; !ok {
+			teams = append(teams, team)
+		}
+	}
+	sort.Strings(teams)
+	var exceeded []string
+	for _, team := range teams {
+		a, b := baseline[team].CFP, current[team].CFP
+		budget, ok := conf.budgetOf(team)
+		switch {
+		case !ok:
+			fmt.Printf("unbudgeted\t%s\t%d\t%d\t%+d\n", team, a, b, b-a)
+		case budget.allowsGrowth(a, b):
+			fmt.Printf("within\t%s\t%d\t%d\t%+d\t%s\n", team, a, b, b-a, budget.String())
+		default:
+			fmt.Printf("exceeded\t%s\t%d\t%d\t%+d\t%s\n", team, a, b, b-a, budget.String())
+			exceeded = append(exceeded, team)
+		}
+	}
+	if len(exceeded) > 0 {
+		log.Printf("%d of %d teams exceeded their budget: %s", len(exceeded), len(teams), strings.Join(exceeded, ", "))
+		os.Exit(1)
+	}
+	log.Printf("no team of %d exceeded its budget", len(teams))
+}
+
+// readTeamTotals reads a JSON measurement and rolls its processes up by
+// owning team.
+func readTeamTotals(path string) (map[string]TeamTotal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out Output
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	res := map[string]TeamTotal{}
+	for _, t := range teamTotals(out.Processes) {
+		res[t.Team] = t
+	}
+	return res, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestBudgetAllowsGrowth(t *testing.T) {
+	for _, c := range []struct {
+		growth string
+		a, b   int
+		want   bool
+	}{
+		{"5", 100, 105, true},
+		{"5", 100, 106, false},
+		{"0", 100, 100, true},
+		{"0", 100, 101, false},
+		// shrinking is always within the budget
+		{"0", 100, 50, true},
+		{"10%", 100, 110, true},
+		{"10%", 100, 111, false},
+		{"29%", 100, 129, true},
+		{"29%", 100, 130, false},
+		// relative to the baseline, not to the larger size
+		{"10%", 10, 11, true},
+		{"10%", 10, 12, false},
+		// a relative budget of a team without a baseline allows no growth
+		{"50%", 0, 0, true},
+		{"50%", 0, 1, false},
+		{"3", 0, 3, true},
+	} {
+		var budget tolerance
+		if err := budget.Set(c.growth); err != nil {
+			t.Fatal(err)
+		}
+		if got := budget.allowsGrowth(c.a, c.b); got != c.want {
+			t.Errorf("budget %s allowsGrowth(%d, %d) = %v, want %v", c.growth, c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestBudgetOf(t *testing.T) {
+	budgets, err := compileBudgets([]teamBudget{{Team: "@shop/payments", Growth: "2"}, {Team: defaultBudgetTeam, Growth: "10%"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := settings{budgets: budgets}
+	if b, ok := s.budgetOf("@shop/payments"); !ok || b.String() != "2" {
+		t.Errorf("the budget of @shop/payments is %s, %v", b.String(), ok)
+	}
+	if b, ok := s.budgetOf("@shop/catalog"); !ok || b.String() != "10%" {
+		t.Errorf("the budget of @shop/catalog is %s, %v, want the default", b.String(), ok)
+	}
+	budgets, err = compileBudgets([]teamBudget{{Team: "@shop/payments", Growth: "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (settings{budgets: budgets}).budgetOf("@shop/catalog"); ok {
+		t.Errorf("a team has a budget without a default")
+	}
+	for desc, budgets := range map[string][]teamBudget{
+		"no team":        {{Growth: "2"}},
+		"a team twice":   {{Team: "@shop/payments", Growth: "2"}, {Team: "@shop/payments", Growth: "3"}},
+		"no growth":      {{Team: "@shop/payments"}},
+		"not a growth":   {{Team: "@shop/payments", Growth: "a little"}},
+		"a negative one": {{Team: "@shop/payments", Growth: "-2"}},
+	} {
+		if _, err := compileBudgets(budgets); err == nil {
+			t.Errorf("%s: no error", desc)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// In-process caches. A bigcache, ristretto or groupcache cache, or a sync.Map
+// used as one, holds data inside the software: under COSMIC it is transient
+// data, and by default its Gets and Sets move nothing. A code base treating
+// its cache as storage of its own (persisted, or shared with other
+// processes) sets "caches": "storage" in the -config file: a hit is then a
+// Read and a Set a Write of the cache, named after the variable or field
+// holding it.
+
+// cacheGroup is the data group of a cache held by no named variable or field.
+const cacheGroup = "cache"
+
+var (
+	// cacheReads and cacheWrites are the reads and writes of the in-process
+	// cache libraries, by package path.
+	cacheReads = map[string]map[string]bool{
+		"github.com/allegro/bigcache":                  bigcacheReads,
+		"github.com/allegro/bigcache/v2":               bigcacheReads,
+		"github.com/allegro/bigcache/v3":               bigcacheReads,
+		"github.com/dgraph-io/ristretto":               ristrettoReads,
+		"github.com/dgraph-io/ristretto/v2":            ristrettoReads,
+		"github.com/golang/groupcache":                 {"Get": true},
+		"github.com/patrickmn/go-cache":                {"Get": true, "GetWithExpiration": true, "Items": true},
+		"github.com/coocood/freecache":                 {"Get": true, "GetWithExpiration": true, "Peek": true},
+		"github.com/hashicorp/golang-lru":              lruReads,
+		"github.com/hashicorp/golang-lru/v2":           lruReads,
+		"github.com/hashicorp/golang-lru/v2/expirable": lruReads,
+		"sync": {"Load": true, "Range": true},
+	}
+	cacheWrites = map[string]map[string]bool{
+		"github.com/allegro/bigcache":       bigcacheWrites,
+		"github.com/allegro/bigcache/v2":    bigcacheWrites,
+		"github.com/allegro/bigcache/v3":    bigcacheWrites,
+		"github.com/dgraph-io/ristretto":    ristrettoWrites,
+		"github.com/dgraph-io/ristretto/v2": ristrettoWrites,
+		"github.com/patrickmn/go-cache": {
+			"Set": true, "SetDefault": true, "Add": true, "Replace": true, "Delete": true,
+			"Increment": true, "Decrement": true, "Flush": true,
+		},
+		"github.com/coocood/freecache":                 {"Set": true, "SetAndGet": true, "Del": true, "Touch": true, "Clear": true},
+		"github.com/hashicorp/golang-lru":              lruWrites,
+		"github.com/hashicorp/golang-lru/v2":           lruWrites,
+		"github.com/hashicorp/golang-lru/v2/expirable": lruWrites,
+		"sync": {
+			"Store": true, "LoadOrStore": true, "LoadAndDelete": true, "Delete": true,
+			"Swap": true, "CompareAndSwap": true, "CompareAndDelete": true, "Clear": true,
+		},
+	}
+
+	bigcacheReads   = map[string]bool{"Get": true, "GetWithInfo": true, "Iterator": true}
+	bigcacheWrites  = map[string]bool{"Set": true, "Append": true, "Delete": true, "Reset": true}
+	ristrettoReads  = map[string]bool{"Get": true, "GetTTL": true}
+	ristrettoWrites = map[string]bool{"Set": true, "SetWithTTL": true, "Del": true, "Clear": true}
+	lruReads        = map[string]bool{"Get": true, "Peek": true, "Contains": true, "Keys": true, "Values": true}
+	lruWrites       = map[string]bool{"Add": true, "ContainsOrAdd": true, "PeekOrAdd": true, "Remove": true, "RemoveOldest": true, "Purge": true, "Resize": true}
+)
+
+// isCacheCall reports whether fn reads or writes an in-process cache; of
+// package sync, only the methods of sync.Map do.
+func isCacheCall(fn *ssa.Function) bool {
+	if !matchesTable(fn, cacheReads) && !matchesTable(fn, cacheWrites) {
+		return false
+	}
+	recv := fn.Signature.Recv()
+	return fn.Pkg.Pkg.Path() != "sync" || recv != nil && isNamedType(recv.Type(), "sync", "Map")
+}
+
+// cacheMovement returns the movement of the cache call of fn under the caches
+// policy, with the cache's data group, or "" if the cache is internal data.
+func (s settings) cacheMovement(fn *ssa.Function, call *ssa.CallCommon) (string, string) {
+	if s.Caches != "storage" {
+		return "", ""
+	}
+	typ := MovementWrite
+	if matchesTable(fn, cacheReads) {
+		typ = MovementRead
+	}
+	group := cacheGroup
+	if len(call.Args) > 0 {
+		if name := cacheName(call.Args[0]); name != "" {
+			group = name
+		}
+	}
+	return typ, group
+}
+
+// cacheName returns the name of the variable or field holding the cache v,
+// or the constant name it was created with (groupcache.NewGroup("avatars",
+// ...)), or "".
+func cacheName(v ssa.Value) string {
+	switch vv := v.(type) {
+	case *ssa.UnOp:
+		if g, ok := vv.X.(*ssa.Global); ok {
+			return g.Name()
+		}
+		return cacheName(vv.X)
+	case *ssa.Global:
+		return vv.Name()
+	case *ssa.FieldAddr:
+		if f := fieldOf(vv); f != nil {
+			return f.Name()
+		}
+	case *ssa.Field:
+		if st, ok := vv.X.Type().Underlying().(*types.Struct); ok {
+			return st.Field(vv.Field).Name()
+		}
+	case *ssa.Call:
+		return receiverName(vv)
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Incremental measurement for CI. Given the files changed since the cached
+// measurement, only the processes whose call graph reaches a function defined
+// in one of those files are measured again; all other processes are taken from
+// the cache. Processes are matched by source and HTTP method, which are stable
+// across route renames; only the counts of a process are taken from the
+// cache, its name, trigger and ID are those of the current registration.
+
+// parseChangedFiles parses the -changed-files value: a whitespace- or
+// comma-separated list of paths relative to the working directory, or "-" to
+// read the list from stdin (e.g. piped from git diff --name-only). full is true
+// when a changed file affects the whole build (go.mod, go.sum), in which case
+// everything must be measured again.
+func parseChangedFiles(value string, stdin io.Reader) (files map[string]bool, full bool, err error) {
+	var names []string
+	if value == "-" {
+		sc := bufio.NewScanner(stdin)
+		for sc.Scan() {
+			names = append(names, strings.Fields(sc.Text())...)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, false, err
+		}
+	} else {
+		names = strings.Fields(strings.ReplaceAll(value, ",", " "))
+	}
+	files = map[string]bool{}
+	for _, name := range names {
+		switch filepath.Base(name) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			full = true
+		}
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, false, err
+		}
+		files[abs] = true
+	}
+	return files, full, nil
+}
+
+// inFiles returns a seed matching the functions defined in one of files.
+func inFiles(files map[string]bool) func(*ssa.Function) bool {
+	return func(fn *ssa.Function) bool {
+		return files[filepath.Clean(fn.Prog.Fset.Position(fn.Pos()).Filename)]
+	}
+}
+
+// readCache reads a previous JSON measurement and indexes its processes by
+// source and method.
+func readCache(path string) (map[string]ProcessReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out Output
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	cache := make(map[string]ProcessReport, len(out.Processes))
+	for _, pr := range out.Processes {
+		if pr.Source != "" {
+			cache[cacheKey(pr.Source, pr.Method)] = pr
+		}
+	}
+	return cache, nil
+}
+
+// cacheKey identifies a process in the cache.
+func cacheKey(source, method string) string {
+	if method == "" {
+		return source
+	}
+	return method + " " + source
+}
+
+// cachedReports returns the cached reports of the processes of the entry of
+// source, one per HTTP method of ep, or nil unless all of them are cached.
+func cachedReports(cache map[string]ProcessReport, source string, ep entryPoint) []ProcessReport {
+	var prs []ProcessReport
+	for _, v := range ep.variants() {
+		pr, ok := cache[cacheKey(source, v.method())]
+		if !ok {
+			return nil
+		}
+		pr.Cached = true
+		prs = append(prs, pr)
+	}
+	return prs
+}
+
+// affectedEntries returns the entries of g from which a function matching seed
+// is reachable, by walking the graph backwards from those functions.
+func affectedEntries(g *callGraph, entries []*ssa.Function, seed func(*ssa.Function) bool) map[*ssa.Function]bool {
+	preds := make([][]int32, len(g.funcs))
+	for v, succs := range g.succs {
+		for _, w := range succs {
+			preds[w] = append(preds[w], int32(v))
+		}
+	}
+	seen := make([]bool, len(g.funcs))
+	var queue []int32
+	for v, fn := range g.funcs {
+		if seed(fn) {
+			seen[v] = true
+			queue = append(queue, int32(v))
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, p := range preds[v] {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	affected := map[*ssa.Function]bool{}
+	for _, fn := range entries {
+		if seen[g.index[fn]] {
+			affected[fn] = true
+		}
+	}
+	return affected
+}
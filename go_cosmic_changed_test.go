@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyFixture copies the fixture module testdata/name into a temporary
+// directory, for the tests that edit it.
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join("testdata", name)
+	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, 0o644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// editFile replaces old by new in the file at path.
+func editFile(t *testing.T, path, old, new string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), old) {
+		t.Fatalf("%s does not contain %q", path, old)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), old, new, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestChangedFilesRenamedRoute renames the route of a handler whose file is
+// unchanged: its counts come from the cache, but its name and ID follow the
+// new route.
+func TestChangedFilesRenamedRoute(t *testing.T) {
+	dir := copyFixture(t, "changed")
+	an, err := NewAnalyzer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := an.Measure(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(t.TempDir(), "base.json")
+	if err := os.WriteFile(cache, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mainFile := filepath.Join(dir, "main.go")
+	editFile(t, mainFile, `"GET /orders"`, `"GET /v2/orders"`)
+	an, err = NewAnalyzer([]string{"-cache", cache, "-changed-files", mainFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := an.Measure(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got *ProcessReport
+	for i, pr := range out.Processes {
+		if pr.Source == "example.com/changed.getOrder" {
+			got = &out.Processes[i]
+		}
+	}
+	if got == nil {
+		t.Fatalf("no process of getOrder in %+v", out.Processes)
+	}
+	if !got.Cached {
+		t.Errorf("getOrder was measured again, want its counts from the cache")
+	}
+	if want := "GET /v2/orders -> example.com/changed.getOrder"; got.Name != want {
+		t.Errorf("name = %q, want %q", got.Name, want)
+	}
+	if want := processID(got.Source, "GET"); got.ID != want || got.Method != "GET" {
+		t.Errorf("ID, method = %s, %s, want %s, GET", got.ID, got.Method, want)
+	}
+	if got.Reads != 1 || got.Exits != 1 {
+		t.Errorf("reads, exits = %d, %d, want the cached 1, 1", got.Reads, got.Exits)
+	}
+}
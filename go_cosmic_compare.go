@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Measurement comparison. Validating the tool means measuring the same code
+// twice (AST against SSA mode, one tool version against the next) and asking
+// whether the measurements agree. "compare" matches the processes of two JSON
+// outputs by source and method and checks their sizes, and the total, against
+// a tolerance; given a manual measurement in CSV instead of one of the
+// outputs, it aligns them by name (see compareManual).
+
+// tolerance is the largest accepted difference of two sizes, in CFP or, when
+// relative, in percent of the larger size. Percentages are kept as given so
+// that a difference right at the tolerance is accepted: 29% is not 0.29
+// times 100 CFP in floating point.
+type tolerance struct {
+	value    float64
+	relative bool
+}
+
+// String implements flag.Value.
+func (t *tolerance) String() string {
+	if t.relative {
+		return strconv.FormatFloat(t.value, 'g', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(t.value, 'g', -1, 64)
+}
+
+// Set implements flag.Value for "5%" or an absolute "2".
+func (t *tolerance) Set(s string) error {
+	num, relative := strings.CutSuffix(s, "%")
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return fmt.Errorf("not a CFP difference or percentage: %q", s)
+	}
+	*t = tolerance{v, relative}
+	return nil
+}
+
+// accepts reports whether sizes a and b differ by at most t.
+func (t tolerance) accepts(a, b int) bool {
+	diff := math.Abs(float64(a - b))
+	if t.relative {
+		return diff*100 <= t.value*float64(max(a, b))
+	}
+	return diff <= t.value
+}
+
+// runCompare implements "compare <a.json> <b.json>": it prints for every
+// process whether its sizes in the two measurements agree within -tolerance,
+// then the totals, and exits with status 1 unless all agree. Either file may
+// be a manual measurement, <manual.csv>.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	tol := tolerance{}
+	fs.Var(&tol, "tolerance", "accepted difference of the sizes of a process and of the totals, in CFP (2) or relative to the larger size (5%)")
+	minSimilarity := fs.Float64("min-similarity", 0.5, "least similarity (0 to 1) of the words of two process names aligned with a manual measurement")
+	patch := fs.String("patch", "", "also write the RFC 6902 JSON Patch turning <a.json> into <b.json> to this file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare [-tolerance 5%%] [-patch file] <a.json|manual.csv> <b.json>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	// flags may follow the files: compare a.json b.json --tolerance 5%
+	var files []string
+	for fs.Parse(args); fs.NArg() > 0; fs.Parse(args) {
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if isCSV(files[1]) && !isCSV(files[0]) {
+		files[0], files[1] = files[1], files[0]
+	}
+	if isCSV(files[0]) {
+		if isCSV(files[1]) {
+			log.Fatalf("compare: one of the files must be a JSON output")
+		}
+		if *patch != "" {
+			log.Fatalf("compare: -patch requires two JSON outputs")
+		}
+		if !compareManual(files[0], files[1], tol, *minSimilarity) {
+			os.Exit(1)
+		}
+		return
+	}
+	a, err := readCache(files[0])
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+	b, err := readCache(files[1])
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+	warnRules(files[0], files[1])
+	if *patch != "" {
+		n, err := writePatch(*patch, files[0], files[1])
+		if err != nil {
+			log.Fatalf("compare: -patch: %v", err)
+		}
+		log.Printf("wrote %d JSON Patch operations to %s", n, *patch)
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	agreeing, totalA, totalB := 0, 0, 0
+	for _, k := range keys {
+		pa, inA := a[k]
+		pb, inB := b[k]
+		switch {
+		case !inB:
+			totalA += pa.cfp()
+			fmt.Printf("only in %s\t%s\t%d\n", files[0], pa.Name, pa.cfp())
+		case !inA:
+			totalB += pb.cfp()
+			fmt.Printf("only in %s\t%s\t%d\n", files[1], pb.Name, pb.cfp())
+		default:
+			ca, cb := pa.cfp(), pb.cfp()
+			totalA, totalB = totalA+ca, totalB+cb
+			verdict := "differ"
+			if tol.accepts(ca, cb) {
+				verdict = "agree"
+				agreeing++
+			}
+			fmt.Printf("%s\t%s\t%d\t%d\t%+d\n", verdict, pa.Name, ca, cb, cb-ca)
+		}
+	}
+	overall := "differ"
+	if tol.accepts(totalA, totalB) {
+		overall = "agree"
+	}
+	fmt.Printf("%s\ttotal\t%d\t%d\t%+d\n", overall, totalA, totalB, totalB-totalA)
+	within := tol.String()
+	if !tol.relative {
+		within += " CFP"
+	}
+	log.Printf("%d of %d processes agree within %s; the totals %s", agreeing, len(keys), within, overall)
+	if agreeing != len(keys) || overall != "agree" {
+		os.Exit(1)
+	}
+}
+
+// isCSV reports whether path names a CSV file.
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// cfp returns the size of the process.
+func (pr ProcessReport) cfp() int {
+	return pr.Entries + pr.Exits + pr.Reads + pr.Writes
+}
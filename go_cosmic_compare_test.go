@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestToleranceAccepts(t *testing.T) {
+	for _, c := range []struct {
+		tolerance string
+		a, b      int
+		want      bool
+	}{
+		{"0", 10, 10, true},
+		{"0", 10, 11, false},
+		{"2", 10, 12, true},
+		{"2", 12, 10, true},
+		{"2", 10, 13, false},
+		{"1.5", 10, 11, true},
+		{"1.5", 10, 12, false},
+		// relative to the larger size, which either may be
+		{"10%", 9, 10, true},
+		{"10%", 10, 9, true},
+		{"10%", 8, 10, false},
+		{"29%", 71, 100, true},
+		{"29%", 70, 100, false},
+		{"5%", 0, 0, true},
+		{"5%", 0, 1, false},
+		{"100%", 0, 7, true},
+	} {
+		var tol tolerance
+		if err := tol.Set(c.tolerance); err != nil {
+			t.Fatal(err)
+		}
+		if got := tol.accepts(c.a, c.b); got != c.want {
+			t.Errorf("tolerance %s accepts(%d, %d) = %v, want %v", c.tolerance, c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestToleranceSet(t *testing.T) {
+	for s, want := range map[string]string{"5%": "5%", "2": "2", "0.5": "0.5", "12.5%": "12.5%"} {
+		var tol tolerance
+		if err := tol.Set(s); err != nil {
+			t.Errorf("Set(%q): %v", s, err)
+		} else if tol.String() != want {
+			t.Errorf("Set(%q) is %s", s, tol.String())
+		}
+	}
+	for _, s := range []string{"", "%", "-1", "five", "5%%"} {
+		var tol tolerance
+		if err := tol.Set(s); err == nil {
+			t.Errorf("Set(%q) accepted %s", s, tol.String())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Subprocess composition. A CLI of a pipeline may run other binaries built
+// from the same code, as in exec.Command("./bin/worker", ...). With -compose
+// the process launching a binary is linked to that binary's main process, and
+// each chain of linked processes is reported with its end-to-end size.
+
+// ProcessChain is a process followed by the processes of the binaries it runs,
+// transitively, with their summed counts.
+type ProcessChain struct {
+	Processes []string `json:"processes"`
+	Entries   int      `json:"entries"`
+	Exits     int      `json:"exits"`
+	Reads     int      `json:"reads"`
+	Writes    int      `json:"writes"`
+}
+
+// execProgram returns the base name of the program run by a subprocess
+// launch such as exec.Command("./bin/worker"), or "" if fn launches none or
+// the program is not a constant.
+func execProgram(fn *ssa.Function, args []ssa.Value) string {
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return ""
+	}
+	idx, ok := execFuncs[fn.Pkg.Pkg.Path()][fn.Name()]
+	if !ok || idx >= len(args) {
+		return ""
+	}
+	prog, ok := constString(args[idx])
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(path.Base(strings.ReplaceAll(prog, `\`, "/")), ".exe")
+}
+
+// binaries maps the binary name of each main package among entries (the last
+// element of its import path, as go build names it) to its main process.
+func binaries(entries []*ssa.Function) map[string]string {
+	bins := map[string]string{}
+	for _, fn := range entries {
+		if isMainFunc(fn) {
+			bins[path.Base(fn.Pkg.Pkg.Path())] = processName(fn, entryPoint{}, nil)
+		}
+	}
+	return bins
+}
+
+// compose links the processes running one of bins to its main process and
+// adds the chains starting at every linking process no other process runs.
+func (out *Output) compose(bins map[string]string) {
+	index := map[string]int{}
+	for i, pr := range out.Processes {
+		index[pr.Name] = i
+	}
+	invoked := map[string]bool{}
+	for i := range out.Processes {
+		pr := &out.Processes[i]
+		for _, m := range pr.Movements {
+			if !strings.HasPrefix(m.Callee, "os/exec.") || m.Type != MovementExit {
+				continue
+			}
+			if target, ok := bins[m.DataGroup]; ok && target != pr.Name {
+				pr.Invokes = appendUnique(pr.Invokes, target)
+				invoked[target] = true
+			}
+		}
+	}
+	for _, pr := range out.Processes {
+		if len(pr.Invokes) == 0 || invoked[pr.Name] {
+			continue
+		}
+		var chain ProcessChain
+		seen := map[string]bool{pr.Name: true}
+		queue := []string{pr.Name}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			chain.Processes = append(chain.Processes, name)
+			i, ok := index[name]
+			if !ok {
+				continue
+			}
+			p := out.Processes[i]
+			chain.Entries += p.Entries
+			chain.Exits += p.Exits
+			chain.Reads += p.Reads
+			chain.Writes += p.Writes
+			for _, next := range p.Invokes {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		out.Chains = append(out.Chains, chain)
+	}
+}
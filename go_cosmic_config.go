@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Settings adjust the classification policies to a code base. They are read
+// from the JSON file given with -config; without one every policy has its
+// default.
+
+// settings is the content of the -config file.
+type settings struct {
+	// Codecs is the policy for compression and archive packages: "local" (the
+	// default) treats their reader and writer calls as data manipulation, so
+	// only the underlying source or sink (the file, the connection) counts;
+	// "movements" classifies them like any other call.
+	Codecs string `json:"codecs,omitempty"`
+	// CodecPackages are further compression or archive packages, by path prefix.
+	CodecPackages []string `json:"codec_packages,omitempty"`
+	// Caches is the policy for in-process caches: "internal" (the default)
+	// treats them as transient data, so their calls move nothing; "storage"
+	// counts a hit as a Read and a Set as a Write of the cache.
+	Caches string `json:"caches,omitempty"`
+	// ContextValues is the policy for ctx.Value reads: "internal" (the
+	// default) counts none; "entries" counts the reads of a key declared in
+	// another package as Entries (see contextValueEntry).
+	ContextValues string `json:"context_values,omitempty"`
+	// Rules classify the calls of packages without built-in support; they
+	// take precedence over the built-in tables (see "rules suggest").
+	Rules []classificationRule `json:"rules,omitempty"`
+	// Ports are the port interfaces of a hexagonal architecture, classified
+	// where the domain calls them.
+	Ports []portInterface `json:"ports,omitempty"`
+	// Repositories is the policy for repository interfaces: "discover" (the
+	// default) classifies the calls of those found in the code like ports;
+	// "declared" only classifies the declared Ports.
+	Repositories string `json:"repositories,omitempty"`
+	// Entries declare entry points the built-in detection misses, such as the
+	// handlers of an in-house framework; NotEntries are the functions
+	// rejected in "entries -assist", which are not proposed again.
+	Entries    []declaredEntry `json:"entries,omitempty"`
+	NotEntries []string        `json:"not_entries,omitempty"`
+	// EntrySignatures are function types whose matching functions are entry
+	// points, as func(context.Context, *XRequest) (*XResponse, error).
+	EntrySignatures []string `json:"entry_signatures,omitempty"`
+	// LoggerPackages are further logging packages, by path prefix, whose
+	// movements -no-log-exits leaves out of the counts.
+	LoggerPackages []string `json:"logger_packages,omitempty"`
+	// MetricsPackages are further metrics instrumentation packages, by path
+	// prefix, whose movements only count with -metrics.
+	MetricsPackages []string `json:"metrics_packages,omitempty"`
+	// Redact are regular expressions of secrets to replace in the output,
+	// in addition to the built-in ones (see parseRedactions).
+	Redact []string `json:"redact,omitempty"`
+	// Owners assign the files of the code to teams, in addition to and
+	// taking precedence over the CODEOWNERS file.
+	Owners []ownerMapping `json:"owners,omitempty"`
+	// Budgets are the accepted growths of the sizes of the teams, checked by
+	// "budgets".
+	Budgets []teamBudget `json:"budgets,omitempty"`
+
+	// rules indexes Rules by package path and function name.
+	rules map[string]map[string][]classificationRule
+	// signatures are the parsed EntrySignatures.
+	signatures []entrySignature
+	// redactions are the compiled Redact patterns.
+	redactions []*regexp.Regexp
+	// owners are the compiled Owners.
+	owners []ownerRule
+	// budgets are the compiled Budgets, by team.
+	budgets map[string]tolerance
+}
+
+// codecPackages are the compression and archive packages, by path prefix.
+var codecPackages = []string{
+	"compress/",
+	"archive/",
+	"github.com/klauspost/compress/",
+	"github.com/klauspost/pgzip",
+	"github.com/pierrec/lz4",
+	"github.com/golang/snappy",
+	"github.com/andybalholm/brotli",
+	"github.com/ulikunitz/xz",
+	"github.com/mholt/archiver",
+}
+
+// readSettings reads the -config file; an empty path gives the defaults.
+func readSettings(path string) (settings, error) {
+	var s settings
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	switch s.Codecs {
+	case "", "local", "movements":
+	default:
+		return s, fmt.Errorf("%s: codecs must be \"local\" or \"movements\", not %q", path, s.Codecs)
+	}
+	switch s.Caches {
+	case "", "internal", "storage":
+	default:
+		return s, fmt.Errorf("%s: caches must be \"internal\" or \"storage\", not %q", path, s.Caches)
+	}
+	switch s.ContextValues {
+	case "", "internal", "entries":
+	default:
+		return s, fmt.Errorf("%s: context_values must be \"internal\" or \"entries\", not %q", path, s.ContextValues)
+	}
+	switch s.Repositories {
+	case "", "discover", "declared":
+	default:
+		return s, fmt.Errorf("%s: repositories must be \"discover\" or \"declared\", not %q", path, s.Repositories)
+	}
+	if err := checkPorts(s.Ports); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.signatures, err = parseEntrySignatures(s.EntrySignatures); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.redactions, err = parseRedactions(s.Redact); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.owners, err = compileOwnerMappings(s.Owners); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.budgets, err = compileBudgets(s.Budgets); err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, e := range s.Entries {
+		if e.Function == "" {
+			return s, fmt.Errorf("%s: entry %q: function is required", path, e.Trigger)
+		}
+	}
+	s.rules = map[string]map[string][]classificationRule{}
+	for _, r := range s.Rules {
+		switch r.Movement {
+		case MovementEntry, MovementExit, MovementRead, MovementWrite, movementNone:
+		default:
+			return s, fmt.Errorf("%s: rule for %s.%s: movement must be E, X, R, W or none, not %q", path, r.Package, r.Function, r.Movement)
+		}
+		if r.scope, err = compileScope(r.Scope); err != nil {
+			return s, fmt.Errorf("%s: rule for %s.%s: %v", path, r.Package, r.Function, err)
+		}
+		if s.rules[r.Package] == nil {
+			s.rules[r.Package] = map[string][]classificationRule{}
+		}
+		s.rules[r.Package][r.Function] = append(s.rules[r.Package][r.Function], r)
+	}
+	return s, nil
+}
+
+// isLocalCodec reports whether calls into pkg are data manipulation under the
+// codecs policy.
+func (s settings) isLocalCodec(pkg *ssa.Package) bool {
+	if s.Codecs == "movements" || pkg == nil || pkg.Pkg == nil {
+		return false
+	}
+	p := pkg.Pkg.Path()
+	for _, prefix := range append(codecPackages[:len(codecPackages):len(codecPackages)], s.CodecPackages...) {
+		if strings.HasPrefix(p, prefix) || p == strings.TrimSuffix(prefix, "/") {
+			return true
+		}
+	}
+	return false
+}
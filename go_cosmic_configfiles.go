@@ -0,0 +1,116 @@
+package main
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// Configuration files. Loading a configuration file (viper.ReadInConfig,
+// koanf.Load of a file provider, toml.DecodeFile) reads it from persistent
+// storage: a Read of the process triggering the load, named after the file
+// when its name is constant. Decoding YAML or TOML read with os.ReadFile only
+// manipulates the data; the os.ReadFile is the Read.
+
+// configFileLoads are the functions and methods loading a configuration file,
+// by package path, with the index of the argument naming the file (the
+// receiver not counted), or -1 when it is named by an earlier call.
+var configFileLoads = map[string]map[string]int{
+	viperPkgPath:                            {"ReadInConfig": -1, "MergeInConfig": -1},
+	"github.com/knadh/koanf":                {"Load": 0},
+	"github.com/knadh/koanf/v2":             {"Load": 0},
+	"github.com/BurntSushi/toml":            {"DecodeFile": 0},
+	"github.com/pelletier/go-toml":          {"LoadFile": 0},
+	"gopkg.in/ini.v1":                       {"Load": 0, "LooseLoad": 0, "ShadowLoad": 0},
+	"github.com/hashicorp/hcl/v2/hclsimple": {"DecodeFile": 0},
+}
+
+// koanfFileProviders are the packages of the koanf providers reading a file.
+var koanfFileProviders = []string{
+	"github.com/knadh/koanf/providers/file",
+	"github.com/knadh/koanf/providers/fs",
+}
+
+// viperFileNamers are the viper functions and methods naming the file
+// ReadInConfig reads.
+var viperFileNamers = map[string]bool{"SetConfigFile": true, "SetConfigName": true}
+
+// configFileLoad returns whether the call of sc in fn loads a configuration
+// file, and the file.
+func configFileLoad(fn, sc *ssa.Function, call *ssa.CallCommon) (file string, ok bool) {
+	if sc.Pkg == nil {
+		return "", false
+	}
+	idx, ok := configFileLoads[sc.Pkg.Pkg.Path()][sc.Name()]
+	if !ok {
+		return "", false
+	}
+	args := call.Args
+	if sc.Signature.Recv() != nil && len(args) > 0 {
+		args = args[1:]
+	}
+	switch {
+	case idx < 0:
+		var recv ssa.Value
+		if sc.Signature.Recv() != nil && len(call.Args) > 0 {
+			recv = call.Args[0]
+		}
+		return viperConfigFile(fn, recv), true
+	case idx >= len(args):
+		return "", true
+	case sc.Name() == "Load" && sc.Pkg.Pkg.Path() != "gopkg.in/ini.v1":
+		// koanf: k.Load(file.Provider("config.yaml"), yaml.Parser())
+		return koanfFile(args[idx])
+	}
+	file, _ = constString(unwrapInterface(args[idx]))
+	return file, true
+}
+
+// koanfFile returns whether the provider p reads a file, and the file.
+func koanfFile(p ssa.Value) (string, bool) {
+	call, ok := unwrapInterface(p).(*ssa.Call)
+	if !ok {
+		return "", false
+	}
+	sc := call.Call.StaticCallee()
+	if sc == nil || sc.Pkg == nil || !inPaths(sc.Pkg.Pkg.Path(), koanfFileProviders) {
+		return "", false
+	}
+	var file string
+	if args := call.Call.Args; len(args) > 0 {
+		file, _ = constString(args[len(args)-1])
+	}
+	return file, true
+}
+
+// viperConfigFile returns the constant file name fn sets on the viper instance
+// recv, or the global one if nil, before reading the configuration, or "".
+func viperConfigFile(fn *ssa.Function, recv ssa.Value) string {
+	var file string
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			sc := call.Call.StaticCallee()
+			if sc == nil || sc.Pkg == nil || sc.Pkg.Pkg.Path() != viperPkgPath || !viperFileNamers[sc.Name()] {
+				continue
+			}
+			args := call.Call.Args
+			if (sc.Signature.Recv() != nil) != (recv != nil) || recv != nil && args[0] != recv {
+				continue
+			}
+			if name, ok := constString(args[len(args)-1]); ok {
+				file = name
+			}
+		}
+	}
+	return file
+}
+
+// unwrapInterface returns the value converted to an interface by v, or v.
+func unwrapInterface(v ssa.Value) ssa.Value {
+	if mi, ok := v.(*ssa.MakeInterface); ok {
+		return mi.X
+	}
+	return v
+}
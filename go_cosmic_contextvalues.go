@@ -0,0 +1,100 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Context values. A value read with ctx.Value(key) is most often internal
+// data of the package that stored it, a request ID or a logger passed down a
+// call chain. When the key is declared in another package, the value crosses
+// a boundary instead: the claims an authentication middleware injected, the
+// tenant of a gateway. The context_values policy of the -config file decides:
+// "internal" (the default) counts no ctx.Value read, "entries" counts the
+// reads of a key declared in another package than the reading function as an
+// Entry of the key's data group, named after the key variable or type.
+
+// contextValueEntry returns the data group of the Entry of the ctx.Value call
+// in fn under the context values policy, or false if it counts none.
+func (s settings) contextValueEntry(fn *ssa.Function, call *ssa.CallCommon) (string, bool) {
+	if s.ContextValues != "entries" || !call.IsInvoke() || call.Method.Name() != "Value" || len(call.Args) != 1 {
+		return "", false
+	}
+	if !isNamedType(call.Value.Type(), "context", "Context") || fn.Pkg == nil {
+		return "", false
+	}
+	pkg, name := contextKey(call.Args[0])
+	if pkg == nil || pkg == fn.Pkg.Pkg {
+		return "", false
+	}
+	return name, true
+}
+
+// contextKey returns the package declaring the context key v and its name:
+// that of the variable holding it, or else of its named type. Keys of basic
+// types, such as string literals, have no package.
+func contextKey(v ssa.Value) (*types.Package, string) {
+	if mi, ok := v.(*ssa.MakeInterface); ok {
+		v = mi.X
+	}
+	if load, ok := v.(*ssa.UnOp); ok {
+		if g, ok := load.X.(*ssa.Global); ok {
+			return g.Pkg.Pkg, g.Name()
+		}
+	}
+	if named, ok := derefType(v.Type()).(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg(), named.Obj().Name()
+	}
+	return nil, ""
+}
+
+// contextValueEntry returns the data group of the Entry of the call
+// ctx.Value(pkg.Key) in fn measured from syntax, where ctx is a parameter
+// declared as a context.Context or the Context() of another, or false.
+func (fn *heuristicFunc) contextValueEntry(f heuristicFile, s settings, call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if s.ContextValues != "entries" || !ok || sel.Sel.Name != "Value" || len(call.Args) != 1 {
+		return "", false
+	}
+	switch x := sel.X.(type) {
+	case *ast.Ident:
+		if !fn.contexts[x.Name] {
+			return "", false
+		}
+	case *ast.CallExpr:
+		if m, ok := x.Fun.(*ast.SelectorExpr); !ok || m.Sel.Name != "Context" || len(x.Args) != 0 {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	key, ok := call.Args[0].(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if pkg, ok := key.X.(*ast.Ident); !ok || f.imports[pkg.Name] == "" {
+		return "", false
+	}
+	return key.Sel.Name, true
+}
+
+// contextParams returns the names of the parameters of fd declared as a
+// context.Context, in file f measured from its syntax.
+func contextParams(f heuristicFile, fd *ast.FuncDecl) map[string]bool {
+	names := map[string]bool{}
+	for _, field := range fd.Type.Params.List {
+		sel, ok := field.Type.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Context" {
+			continue
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || f.imports[x.Name] != "context" {
+			continue
+		}
+		for _, name := range field.Names {
+			names[name.Name] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Demangled names. Code generators name their functions after the artifact
+// they generate them from, in ways that read poorly in a report: a gRPC
+// handler is _Greeter_SayHello_Handler, a client call
+// (*example.com/app/pb.greeterClient).SayHello, a mock
+// (*example.com/app/mocks.MockStore).Get. -demangle selects the demanglers
+// mapping these back to their artifact, the RPC, the mocked interface or the
+// provider set, in the names of the processes, the callees of the movements
+// and the functions listed with -functions: grpc client /helloworld.Greeter/SayHello,
+// mock Store.Get. The sources of the processes keep the Go symbols, so that
+// their IDs and the matching of the processes across measurements do not
+// depend on the demanglers. A server embedding the tool adds its own with
+// AddDemangler. Code measured from its syntax alone is not demangled.
+
+// Demangler maps the functions a code generator wrote back to the artifact
+// it generated them from.
+type Demangler interface {
+	// Demangle returns the name of the artifact of fn, declared in a file
+	// of fset, or false if the generator did not write fn.
+	Demangle(fset *token.FileSet, fn *types.Func) (string, bool)
+}
+
+// builtinDemanglers are the demanglers -demangle selects by name, in the
+// order they are tried with all.
+var builtinDemanglers = []struct {
+	name string
+	d    Demangler
+}{
+	{"mockery", mockeryDemangler{}},
+	{"protoc", protocDemangler{}},
+	{"wire", wireDemangler{}},
+}
+
+// parseDemanglers returns the built-in demanglers of the comma-separated
+// names, or all of them for "all".
+func parseDemanglers(names string) ([]Demangler, error) {
+	var ds []Demangler
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, b := range builtinDemanglers {
+			if name == b.name || name == "all" {
+				ds = append(ds, b.d)
+				found = true
+			}
+		}
+		if !found {
+			var known []string
+			for _, b := range builtinDemanglers {
+				known = append(known, b.name)
+			}
+			return nil, fmt.Errorf("unknown demangler %q (want all or %s)", name, strings.Join(known, ", "))
+		}
+	}
+	return ds, nil
+}
+
+// AddDemangler adds d to the demanglers of the session's measurements. It
+// must not be called concurrently with Measure.
+func (an *Analyzer) AddDemangler(d Demangler) {
+	an.demanglers = append(an.demanglers, d)
+}
+
+// demangler names the symbols of a program, in the form of the output, with
+// the first of its demanglers that knows them.
+type demangler struct {
+	fset       *token.FileSet
+	demanglers []Demangler
+	symbols    map[string]*types.Func
+	names      map[string]string
+}
+
+// newDemangler returns a demangler of the functions and methods declared in
+// the packages of prog.
+func newDemangler(prog *ssa.Program, demanglers []Demangler) *demangler {
+	d := &demangler{fset: prog.Fset, demanglers: demanglers, symbols: map[string]*types.Func{}, names: map[string]string{}}
+	for _, pkg := range prog.AllPackages() {
+		scope := pkg.Pkg.Scope()
+		for _, name := range scope.Names() {
+			switch obj := scope.Lookup(name).(type) {
+			case *types.Func:
+				d.symbols[obj.FullName()] = obj
+			case *types.TypeName:
+				named, ok := obj.Type().(*types.Named)
+				if !ok || obj.IsAlias() {
+					continue
+				}
+				for i := 0; i < named.NumMethods(); i++ {
+					d.symbols[named.Method(i).FullName()] = named.Method(i)
+				}
+				if iface, ok := named.Underlying().(*types.Interface); ok {
+					for i := 0; i < iface.NumExplicitMethods(); i++ {
+						d.symbols[iface.ExplicitMethod(i).FullName()] = iface.ExplicitMethod(i)
+					}
+				}
+			}
+		}
+	}
+	return d
+}
+
+// name returns the demangled name of symbol, or false if no demangler knows
+// it.
+func (d *demangler) name(symbol string) (string, bool) {
+	if name, ok := d.names[symbol]; ok {
+		return name, name != ""
+	}
+	name := ""
+	if fn := d.symbols[symbol]; fn != nil {
+		for _, dm := range d.demanglers {
+			if n, ok := dm.Demangle(d.fset, fn); ok {
+				name = n
+				break
+			}
+		}
+	}
+	d.names[symbol] = name
+	return name, name != ""
+}
+
+// demangle replaces the generated names of the processes, callees and
+// functions in out by their demangled names.
+func (out *Output) demangle(d *demangler) {
+	renamed := map[string]string{}
+	for i := range out.Processes {
+		pr := &out.Processes[i]
+		if name, ok := d.name(pr.Source); ok {
+			if route, _, ok := strings.Cut(pr.Name, " -> "); ok {
+				name = route + " -> " + name
+			}
+			renamed[pr.Name] = name
+			pr.Name = name
+		}
+		for j := range pr.Movements {
+			if name, ok := d.name(pr.Movements[j].Callee); ok {
+				pr.Movements[j].Callee = name
+			}
+		}
+		for j := range pr.Functions {
+			if name, ok := d.name(pr.Functions[j].Name); ok {
+				pr.Functions[j].Name = name
+			}
+		}
+	}
+	rename := func(names []string) {
+		for i, name := range names {
+			if r, ok := renamed[name]; ok {
+				names[i] = r
+			}
+		}
+	}
+	for i := range out.Processes {
+		rename(out.Processes[i].Invokes)
+	}
+	for i := range out.Chains {
+		rename(out.Chains[i].Processes)
+	}
+}
+
+// methodReceiver returns the name of the named receiver type of method fn and
+// the type, or "" for a function.
+func methodReceiver(fn *types.Func) (string, *types.Named) {
+	sig, _ := fn.Type().(*types.Signature)
+	if sig == nil || sig.Recv() == nil {
+		return "", nil
+	}
+	named, ok := derefType(sig.Recv().Type()).(*types.Named)
+	if !ok {
+		return "", nil
+	}
+	return named.Obj().Name(), named
+}
+
+// protocDemangler names the gRPC and Connect code of protoc-gen-go-grpc and
+// protoc-gen-connect-go after the RPCs: the handlers, clients, server
+// interfaces and streams of /helloworld.Greeter/SayHello.
+type protocDemangler struct{}
+
+var (
+	grpcHandler = regexp.MustCompile(`^_([A-Za-z0-9]+)_(\w+)_Handler$`)
+	grpcStream  = regexp.MustCompile(`^([A-Za-z0-9]+)_(\w+)(Client|Server)$`)
+	grpcService = regexp.MustCompile(`^(?:Unimplemented|Unsafe)?([A-Za-z0-9]+)(Client|Server|Handler)$`)
+)
+
+func (protocDemangler) Demangle(_ *token.FileSet, fn *types.Func) (string, bool) {
+	if fn.Pkg() == nil {
+		return "", false
+	}
+	scope := fn.Pkg().Scope()
+	recv, _ := methodReceiver(fn)
+	if recv == "" {
+		m := grpcHandler.FindStringSubmatch(fn.Name())
+		if m == nil || !isProtocService(scope, m[1]) {
+			return "", false
+		}
+		return "grpc handler " + rpcName(fn.Pkg(), m[1], m[2]), true
+	}
+	if m := grpcStream.FindStringSubmatch(recv); m != nil && isProtocService(scope, m[1]) {
+		return "grpc " + strings.ToLower(m[3]) + " " + rpcName(fn.Pkg(), m[1], m[2]) + " " + fn.Name(), true
+	}
+	m := grpcService.FindStringSubmatch(recv)
+	if m == nil {
+		return "", false
+	}
+	service := upperFirst(m[1])
+	if !isProtocService(scope, service) {
+		return "", false
+	}
+	role := "client"
+	if m[2] != "Client" {
+		role = "server"
+	}
+	return "grpc " + role + " " + rpcName(fn.Pkg(), service, fn.Name()), true
+}
+
+// isProtocService reports whether the package of scope has the generated
+// constructor of a client or server registration of service.
+func isProtocService(scope *types.Scope, service string) bool {
+	for _, name := range []string{"New" + service + "Client", "Register" + service + "Server", "New" + service + "Handler"} {
+		if _, ok := scope.Lookup(name).(*types.Func); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rpcName returns the full name of the RPC method of service, recorded by
+// the generated constants (Greeter_SayHello_FullMethodName,
+// GreeterServiceName) or else qualified with the Go package name.
+func rpcName(pkg *types.Package, service, method string) string {
+	scope := pkg.Scope()
+	for _, name := range []string{service + "_" + method + "_FullMethodName", service + method + "Procedure"} {
+		if s, ok := stringConst(scope, name); ok {
+			return s
+		}
+	}
+	for _, name := range []string{service + "Name", service + "_ServiceName"} {
+		if s, ok := stringConst(scope, name); ok {
+			return "/" + s + "/" + method
+		}
+	}
+	return "/" + pkg.Name() + "." + service + "/" + method
+}
+
+// stringConst returns the value of the string constant name of scope.
+func stringConst(scope *types.Scope, name string) (string, bool) {
+	c, ok := scope.Lookup(name).(*types.Const)
+	if !ok || c.Val().Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(c.Val()), true
+}
+
+// upperFirst returns s with its first letter in upper case.
+func upperFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// mockeryDemangler names the mocks of mockery and testify after the mocked
+// interface: mock Store.Get for (*mocks.MockStore).Get, the expectations of
+// mock Store.Get for the expecter methods.
+type mockeryDemangler struct{}
+
+var mockCall = regexp.MustCompile(`^(\w+)_(\w+)_Call$`)
+
+func (mockeryDemangler) Demangle(_ *token.FileSet, fn *types.Func) (string, bool) {
+	recv, named := methodReceiver(fn)
+	if named == nil {
+		return "", false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case embedsMock(st, "Mock"):
+		return "mock " + mockedInterface(recv) + "." + fn.Name(), true
+	case embedsMock(st, "Call"):
+		if m := mockCall.FindStringSubmatch(recv); m != nil {
+			return "mock " + mockedInterface(m[1]) + "." + m[2] + " " + fn.Name(), true
+		}
+	case strings.HasSuffix(recv, "_Expecter"):
+		return "mock " + mockedInterface(strings.TrimSuffix(recv, "_Expecter")) + "." + fn.Name() + " expectation", true
+	}
+	return "", false
+}
+
+// embedsMock reports whether st embeds the type name of testify's mock
+// package.
+func embedsMock(st *types.Struct, name string) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && isNamedType(f.Type(), "github.com/stretchr/testify/mock", name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mockedInterface returns the name of the interface of the mock type recv,
+// without the Mock prefix of mockery's in-package mocks.
+func mockedInterface(recv string) string {
+	if rest := strings.TrimPrefix(recv, "Mock"); rest != recv && rest != "" {
+		if r, _ := utf8.DecodeRuneInString(rest); unicode.IsUpper(r) {
+			return rest
+		}
+	}
+	return recv
+}
+
+// wireDemangler names the injectors generated by Wire after the provider
+// sets they are built from, read from the wire.Build call of the injector
+// declared in the wireinject files next to it: wire injector
+// app.InitializeApp [AppSet, NewConfig].
+type wireDemangler struct{}
+
+func (wireDemangler) Demangle(fset *token.FileSet, fn *types.Func) (string, bool) {
+	if r, _ := methodReceiver(fn); r != "" || fn.Pkg() == nil || !fn.Pos().IsValid() {
+		return "", false
+	}
+	file := fset.Position(fn.Pos()).Filename
+	if !hasHeader(file, "// Code generated by Wire. DO NOT EDIT.") {
+		return "", false
+	}
+	sets, ok := wireSets(filepath.Dir(file), fn.Name())
+	if !ok {
+		return "", false
+	}
+	return "wire injector " + fn.Pkg().Name() + "." + fn.Name() + " [" + strings.Join(sets, ", ") + "]", true
+}
+
+// hasHeader reports whether one of the lines before the package clause of
+// the Go file is header.
+func hasHeader(file, header string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == header {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false
+}
+
+// wireSets returns the arguments of the wire.Build call of the injector
+// name, declared in a file of dir built with the wireinject tag.
+func wireSets(dir, name string) ([]string, bool) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if !hasHeader(file, "//go:build wireinject") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil || fd.Name.Name != name || fd.Body == nil {
+				continue
+			}
+			var sets []string
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Build" {
+					for _, arg := range call.Args {
+						sets = append(sets, types.ExprString(arg))
+					}
+					return false
+				}
+				return true
+			})
+			return sets, sets != nil
+		}
+	}
+	return nil, false
+}
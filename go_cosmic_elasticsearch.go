@@ -0,0 +1,212 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Elasticsearch and OpenSearch support. The clients expose each REST API in
+// several styles: as function-typed fields, es.Search(es.Search.WithIndex("orders")),
+// as request structs, esapi.IndexRequest{Index: "orders"}.Do(ctx, es), as the
+// typed builders of go-elasticsearch v8, es.Search().Index("orders").Do(ctx),
+// and as the client methods of opensearch-go v4. All are classified by the
+// API, and the data group is the index.
+
+var (
+	// elasticsearchAPIPkgPaths are the request API packages of the clients.
+	elasticsearchAPIPkgPaths = []string{
+		"github.com/elastic/go-elasticsearch/v6/esapi",
+		"github.com/elastic/go-elasticsearch/v7/esapi",
+		"github.com/elastic/go-elasticsearch/v8/esapi",
+		"github.com/elastic/go-elasticsearch/v9/esapi",
+		"github.com/opensearch-project/opensearch-go/opensearchapi",
+		"github.com/opensearch-project/opensearch-go/v2/opensearchapi",
+		"github.com/opensearch-project/opensearch-go/v3/opensearchapi",
+		"github.com/opensearch-project/opensearch-go/v4/opensearchapi",
+	}
+	// elasticsearchTypedAPIPrefixes are the path prefixes of the typed API
+	// packages, one per API (typedapi/core/search).
+	elasticsearchTypedAPIPrefixes = []string{
+		"github.com/elastic/go-elasticsearch/v8/typedapi/",
+		"github.com/elastic/go-elasticsearch/v9/typedapi/",
+	}
+	// elasticsearchBulkIndexerPkgPaths are the helper packages whose
+	// BulkIndexer.Add queues a document write.
+	elasticsearchBulkIndexerPkgPaths = []string{
+		"github.com/elastic/go-elasticsearch/v7/esutil",
+		"github.com/elastic/go-elasticsearch/v8/esutil",
+		"github.com/elastic/go-elasticsearch/v9/esutil",
+		"github.com/opensearch-project/opensearch-go/v2/opensearchutil",
+		"github.com/opensearch-project/opensearch-go/v4/opensearchutil",
+	}
+
+	// elasticsearchAPIs are the document APIs moving data, by lower-case name.
+	elasticsearchAPIs = map[string]string{
+		"search": MovementRead, "msearch": MovementRead, "searchtemplate": MovementRead,
+		"msearchtemplate": MovementRead, "scroll": MovementRead, "get": MovementRead,
+		"mget": MovementRead, "getsource": MovementRead, "exists": MovementRead,
+		"existssource": MovementRead, "count": MovementRead, "explain": MovementRead,
+		"termvectors": MovementRead, "mtermvectors": MovementRead,
+
+		"index": MovementWrite, "create": MovementWrite, "update": MovementWrite,
+		"delete": MovementWrite, "bulk": MovementWrite, "updatebyquery": MovementWrite,
+		"deletebyquery": MovementWrite, "reindex": MovementWrite,
+	}
+
+	// opensearchClients are the opensearch-go v4 client types whose methods
+	// are document APIs; the others (indicesClient) administer the cluster.
+	opensearchClients = map[string]bool{"Client": true, "documentClient": true, "scrollClient": true}
+)
+
+// isElasticsearchClient reports whether fn belongs to an Elasticsearch or
+// OpenSearch client package; only their API calls move data.
+func isElasticsearchClient(fn *ssa.Function) bool {
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return false
+	}
+	p := fn.Pkg.Pkg.Path()
+	return inPaths(p, elasticsearchAPIPkgPaths) || inPaths(p, elasticsearchBulkIndexerPkgPaths) ||
+		hasAnyPrefix(p, elasticsearchTypedAPIPrefixes)
+}
+
+// elasticsearchCall returns the movement type, description and index of an
+// Elasticsearch or OpenSearch API call.
+func elasticsearchCall(call *ssa.CallCommon) (typ, via, index string) {
+	recv, args := ssa.Value(nil), call.Args
+	var api string
+	switch sc := call.StaticCallee(); {
+	case call.IsInvoke():
+		// esutil.BulkIndexer.Add(ctx, esutil.BulkIndexerItem{Index: ...})
+		m := call.Method
+		if m.Pkg() == nil || !inPaths(m.Pkg().Path(), elasticsearchBulkIndexerPkgPaths) || m.Name() != "Add" {
+			return "", "", ""
+		}
+		if len(args) > 1 {
+			index = structField(args[1], "Index")
+		}
+		return MovementWrite, m.FullName(), index
+	case sc == nil:
+		// es.Search(...): a call of a function-typed field of the client
+		named, ok := call.Value.Type().(*types.Named)
+		if !ok || named.Obj().Pkg() == nil || !inPaths(named.Obj().Pkg().Path(), elasticsearchAPIPkgPaths) {
+			return "", "", ""
+		}
+		api, via = named.Obj().Name(), named.Obj().Pkg().Path()+"."+named.Obj().Name()
+	case sc.Pkg == nil || sc.Pkg.Pkg == nil || sc.Signature.Recv() == nil || len(args) == 0:
+		return "", "", ""
+	default:
+		p := sc.Pkg.Pkg.Path()
+		recv, args = args[0], args[1:]
+		recvName := namedTypeName(sc.Signature.Recv().Type())
+		switch {
+		case sc.Name() == "Do" && inPaths(p, elasticsearchAPIPkgPaths) && strings.HasSuffix(recvName, "Request"):
+			// esapi.IndexRequest{Index: "orders"}.Do(ctx, es)
+			api = strings.TrimSuffix(recvName, "Request")
+			index = structField(recv, "Index")
+		case sc.Name() == "Do" && hasAnyPrefix(p, elasticsearchTypedAPIPrefixes):
+			// es.Search().Index("orders").Do(ctx), in typedapi/core/search
+			api = recvName
+			index = builderIndex(recv, 0)
+		case inPaths(p, elasticsearchAPIPkgPaths) && opensearchClients[recvName]:
+			// client.Search(ctx, &opensearchapi.SearchReq{Indices: ...}), v4
+			api = sc.Name()
+			for _, arg := range args {
+				if index = structField(arg, "Index"); index == "" {
+					index = structField(arg, "Indices")
+				}
+				if index != "" {
+					break
+				}
+			}
+		default:
+			return "", "", ""
+		}
+		via = sc.String()
+	}
+	typ = elasticsearchAPIs[strings.ToLower(api)]
+	if typ == "" {
+		return "", "", ""
+	}
+	if index == "" {
+		index = elasticsearchIndex(args)
+	}
+	return typ, via, index
+}
+
+// elasticsearchIndex returns the index named by the arguments of a
+// function-typed API field: a leading constant, es.Index("orders", body), or
+// an option, es.Search.WithIndex("orders").
+func elasticsearchIndex(args []ssa.Value) string {
+	for _, arg := range args {
+		if s, ok := constString(arg); ok {
+			return s
+		}
+		for _, opt := range variadicArgs(arg) {
+			call, ok := opt.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			if sc := call.Call.StaticCallee(); sc != nil && sc.Name() == "WithIndex" && len(call.Call.Args) > 1 {
+				if names := variadicStrings(call.Call.Args[1]); len(names) > 0 {
+					return strings.Join(names, ",")
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// builderIndex returns the index set on a typed API builder chain, by its
+// Index method or the constructor, es.Index("orders"), or "".
+func builderIndex(v ssa.Value, depth int) string {
+	call, ok := v.(*ssa.Call)
+	if !ok || depth > 16 {
+		return ""
+	}
+	args := call.Call.Args
+	sc := call.Call.StaticCallee()
+	if sc == nil || sc.Signature.Recv() == nil {
+		// the constructor, a function-typed field of the client
+		if len(args) > 0 {
+			s, _ := constString(args[0])
+			return s
+		}
+		return ""
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	if sc.Name() == "Index" && len(args) > 1 {
+		if s, ok := constString(args[1]); ok {
+			return s
+		}
+	}
+	return builderIndex(args[0], depth+1)
+}
+
+// structField returns the constant string or strings stored into field name
+// of the struct literal v, as in esapi.SearchRequest{Index: []string{"orders"}}.
+func structField(v ssa.Value, name string) string {
+	for _, val := range fieldStores(v, name) {
+		if s, ok := constString(val); ok {
+			return s
+		}
+		if names := variadicStrings(val); len(names) > 0 {
+			return strings.Join(names, ",")
+		}
+	}
+	return ""
+}
+
+// namedTypeName returns the name of the named type t or *t, or "".
+func namedTypeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return ""
+}
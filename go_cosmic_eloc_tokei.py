@@ -13,6 +13,9 @@ MAX_WORKERS = 8
 BASE_DIR = "data/go_repos"
 RESULTS_FILE = "results/go_eloc_fp.csv"
 AST_BINARY = "./go_cosmic_ast"
+AST_SOURCE_DIR = "go_cosmic_ast_src"
+# Type-check the repos in AST mode (their dependencies are downloaded).
+AST_TYPED = os.getenv("COSMIC_AST_TYPED") == "1"
 
 # ---------------- FETCH TOP REPOS ----------------
 def fetch_top_go_repos(top_n=TOP_N):
@@ -80,13 +83,18 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"golang.org/x/tools/go/packages"
 )
 
 type Result struct {
@@ -96,109 +104,389 @@ type Result struct {
 	Writes  int `json:"writes"`
 }
 
+// hint is a call name counted as a movement, only in packages importing one
+// of pkgs (by path prefix): a Query call in a package without a database
+// driver is not a read. A qualified name (os.ReadFile) names a function of
+// that package, a bare one (Read) the functions and methods of that name.
+type hint struct {
+	name string
+	pkgs []string
+}
+
 var (
-	entryFuncs = []string{
-		"http.HandleFunc",
-		"ListenAndServe",
-		"Run",
-		"Serve",
+	httpPkgs = []string{
+		"net/http",
+		"github.com/gin-gonic/gin",
+		"github.com/labstack/echo",
+		"github.com/gofiber/fiber",
+		"github.com/go-chi/chi",
+		"github.com/gorilla/mux",
+		"google.golang.org/grpc",
+	}
+
+	dbPkgs = []string{
+		"database/sql",
+		"github.com/jmoiron/sqlx",
+		"gorm.io/gorm",
+		"github.com/jackc/pgx",
+		"github.com/lib/pq",
+		"github.com/go-sql-driver/mysql",
+		"github.com/mattn/go-sqlite3",
+		"go.mongodb.org/mongo-driver",
+		"github.com/redis/go-redis",
+		"github.com/go-redis/redis",
+	}
+
+	ioPkgs = []string{"os", "io", "bufio", "net", "encoding", "compress", "archive"}
+
+	entryFuncs = []hint{
+		{"http.HandleFunc", []string{"net/http"}},
+		{"ListenAndServe", httpPkgs},
+		{"ListenAndServeTLS", httpPkgs},
+		{"Run", httpPkgs},
+		{"Serve", httpPkgs},
+	}
+
+	readFuncs = []hint{
+		{"os.Open", []string{"os"}},
+		{"os.OpenFile", []string{"os"}},
+		{"os.ReadFile", []string{"os"}},
+		{"Read", append(ioPkgs, dbPkgs...)},
+		{"ReadAll", ioPkgs},
+		{"Query", dbPkgs},
+		{"QueryRow", dbPkgs},
+		{"QueryContext", dbPkgs},
+		{"QueryRowContext", dbPkgs},
+		{"Scan", append(dbPkgs, "bufio", "fmt")},
 	}
 
-	readFuncs = []string{
-		"os.Open",
-		"os.ReadFile",
-		"Read",
-		"Query",
-		"Scan",
+	writeFuncs = []hint{
+		{"os.Create", []string{"os"}},
+		{"os.WriteFile", []string{"os"}},
+		{"Write", append(ioPkgs, httpPkgs...)},
+		{"WriteString", ioPkgs},
+		{"Print", []string{"fmt", "log"}},
+		{"Printf", []string{"fmt", "log"}},
+		{"Println", []string{"fmt", "log"}},
+		{"Encode", []string{"encoding"}},
+		{"Respond", httpPkgs},
 	}
 
-	writeFuncs = []string{
-		"os.Create",
-		"os.WriteFile",
-		"Write",
-		"Print",
-		"Printf",
-		"Encode",
-		"Respond",
+	exitFuncs = []hint{
+		{"os.Exit", []string{"os"}},
 	}
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	typed := flag.Bool("typed", false, "type-check the packages (go/packages, no SSA) and classify calls by the function they resolve to")
+	flag.Parse()
+	if flag.NArg() < 1 {
 		fmt.Println(`{"entries":0,"exits":0,"reads":0,"writes":0}`)
 		return
 	}
 
-	root := os.Args[1]
+	root := flag.Arg(0)
+	var result Result
+	if *typed {
+		result = countTyped(root)
+	} else {
+		result = countSyntactic(root)
+	}
+
+	out, _ := json.Marshal(result)
+	fmt.Println(string(out))
+}
+
+// countSyntactic classifies the calls of the files under root by their text.
+func countSyntactic(root string) Result {
 	fset := token.NewFileSet()
 	result := Result{}
 
+	// Read the imports first: the hints of a file are those of the packages
+	// imported by its package (directory), as a handle declared in one file
+	// is often used in another.
+	var files []string
+	imports := map[string]map[string]bool{}
 	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil || !strings.HasSuffix(path, ".go") {
 			return nil
 		}
 
-		node, err := parser.ParseFile(fset, path, nil, 0)
+		node, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
 		if err != nil {
 			return nil
 		}
 
+		dir := filepath.Dir(path)
+		if imports[dir] == nil {
+			imports[dir] = map[string]bool{}
+		}
+		for _, imp := range node.Imports {
+			if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+				imports[dir][p] = true
+			}
+		}
+		files = append(files, path)
+		return nil
+	})
+
+	for _, path := range files {
+		pkgImports := imports[filepath.Dir(path)]
+		entries := active(entryFuncs, pkgImports)
+		reads := active(readFuncs, pkgImports)
+		writes := active(writeFuncs, pkgImports)
+		exits := active(exitFuncs, pkgImports)
+		if len(entries)+len(reads)+len(writes)+len(exits) == 0 {
+			continue
+		}
+
+		node, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			continue
+		}
+
+		aliases := importAliases(node)
 		ast.Inspect(node, func(n ast.Node) bool {
 			call, ok := n.(*ast.CallExpr)
 			if !ok {
 				return true
 			}
 
-			name := getCallName(call.Fun)
-
-			for _, f := range entryFuncs {
-				if strings.Contains(name, f) {
-					result.Entries++
-				}
+			// the calls a chain is built with are classified on their own:
+			// json.NewEncoder(w).Encode(v) is a NewEncoder and an Encode call;
+			// those in the arguments, as in w.Write(must(os.ReadFile(name))),
+			// are visited after it
+			name := getCallName(call.Fun, aliases)
+			if i := strings.LastIndex(name, "()"); i >= 0 {
+				name = name[i+len("()"):]
 			}
+			result.Entries += matches(name, entries)
+			result.Reads += matches(name, reads)
+			result.Writes += matches(name, writes)
+			result.Exits += matches(name, exits)
+
+			return true
+		})
+	}
+
+	return result
+}
+
+// countTyped classifies the calls of the packages of the module at root by the
+// function they resolve to, so a local variable named http or db is not taken
+// for a package. The module's dependencies must be available to the go command.
+func countTyped(root string) Result {
+	result := Result{}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  root,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return result
+	}
 
-			for _, f := range readFuncs {
-				if strings.Contains(name, f) {
-					result.Reads++
+	for _, pkg := range pkgs {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
 				}
-			}
 
-			for _, f := range writeFuncs {
-				if strings.Contains(name, f) {
-					result.Writes++
+				fn := callee(pkg.TypesInfo, call.Fun)
+				if fn == nil || fn.Pkg() == nil {
+					return true
 				}
-			}
+				result.Entries += typedMatches(fn, entryFuncs)
+				result.Reads += typedMatches(fn, readFuncs)
+				result.Writes += typedMatches(fn, writeFuncs)
+				result.Exits += typedMatches(fn, exitFuncs)
+
+				return true
+			})
+		}
+	}
 
-			if strings.Contains(name, "os.Exit") {
-				result.Exits++
+	return result
+}
+
+// callee returns the function or method called by a call of fun, or nil for
+// calls of function values, conversions and builtins.
+func callee(info *types.Info, fun ast.Expr) *types.Func {
+	for {
+		paren, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = paren.X
+	}
+	var id *ast.Ident
+	switch e := fun.(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	default:
+		return nil
+	}
+	fn, _ := info.Uses[id].(*types.Func)
+	return fn
+}
+
+// typedMatches counts the hints naming fn (as in net/http.HandleFunc or
+// (*database/sql.DB).Query) that apply to its package.
+func typedMatches(fn *types.Func, hints []hint) int {
+	qual := ""
+	if fn.Type().(*types.Signature).Recv() == nil {
+		qual = fn.Pkg().Name()
+	}
+	n := 0
+	for _, h := range hints {
+		if imported(fn.Pkg().Path(), h.pkgs) && named(qual, fn.Name(), h.name) {
+			n++
+		}
+	}
+	return n
+}
+
+// active returns the names of the hints applying to a package importing pkgImports.
+func active(hints []hint, pkgImports map[string]bool) []string {
+	var names []string
+	for _, h := range hints {
+		for p := range pkgImports {
+			if imported(p, h.pkgs) {
+				names = append(names, h.name)
+				break
 			}
+		}
+	}
+	return names
+}
 
+// imported reports whether import path p is one of prefixes or below one.
+func imported(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
 			return true
-		})
+		}
+	}
+	return false
+}
 
-		return nil
-	})
+// matches counts the names naming the call name (as in os.ReadFile or
+// s.db.Query) by its last identifier: json.NewEncoder is not an Encode call.
+func matches(name string, names []string) int {
+	qual, fn := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		qual, fn = name[:i], name[i+1:]
+	}
+	n := 0
+	for _, f := range names {
+		if named(qual, fn, f) {
+			n++
+		}
+	}
+	return n
+}
 
-	out, _ := json.Marshal(result)
-	fmt.Println(string(out))
+// named reports whether the hint name names the function fn qualified by
+// qual, its package or the operand of its selector.
+func named(qual, fn, name string) bool {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return qual == name[:i] && fn == name[i+1:]
+	}
+	return fn == name
 }
 
-func getCallName(expr ast.Expr) string {
+// getCallName returns the text of a call's function, with renamed imports
+// under the name of their package: h.HandleFunc for import h "net/http" is
+// http.HandleFunc. Calls on call results keep the inner call, as in
+// json.NewEncoder().Encode, and nested selectors (s.db.Query) all elements.
+func getCallName(expr ast.Expr, aliases map[string]string) string {
 	switch e := expr.(type) {
 	case *ast.SelectorExpr:
-		return getCallName(e.X) + "." + e.Sel.Name
+		return getCallName(e.X, aliases) + "." + e.Sel.Name
 	case *ast.Ident:
+		if name, ok := aliases[e.Name]; ok {
+			return name
+		}
 		return e.Name
+	case *ast.CallExpr:
+		return getCallName(e.Fun, aliases) + "()"
+	case *ast.ParenExpr:
+		return getCallName(e.X, aliases)
+	case *ast.StarExpr:
+		return getCallName(e.X, aliases)
+	case *ast.IndexExpr:
+		// a generic instantiation, Get[T], or an element, handlers[i]
+		return getCallName(e.X, aliases)
+	case *ast.IndexListExpr:
+		return getCallName(e.X, aliases)
+	case *ast.TypeAssertExpr:
+		return getCallName(e.X, aliases)
 	default:
 		return ""
 	}
 }
+
+// importAliases maps the names of the renamed imports of a file to the
+// names of their packages.
+func importAliases(file *ast.File) map[string]string {
+	aliases := map[string]string{}
+	for _, imp := range file.Imports {
+		if imp.Name == nil || imp.Name.Name == "_" || imp.Name.Name == "." {
+			continue
+		}
+		if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+			aliases[imp.Name.Name] = packageName(p)
+		}
+	}
+	return aliases
+}
+
+// packageName returns the conventional name of the package at import path p:
+// its last element, without a major version (redis for .../redis/v8,
+// yaml for gopkg.in/yaml.v3).
+func packageName(p string) string {
+	elems := strings.Split(p, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return name
+}
+
+// isMajorVersion reports whether s is a major version suffix such as v2.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
 '''
 
-    with open("go_cosmic_ast.go", "w", encoding="utf-8") as f:
+    # The analyzer is a module of its own: the typed mode needs go/packages.
+    os.makedirs(AST_SOURCE_DIR, exist_ok=True)
+    with open(os.path.join(AST_SOURCE_DIR, "main.go"), "w", encoding="utf-8") as f:
         f.write(go_source)
 
-    subprocess.run(["go", "build", "-o", "go_cosmic_ast", "go_cosmic_ast.go"], check=True)
+    if not os.path.exists(os.path.join(AST_SOURCE_DIR, "go.mod")):
+        subprocess.run(["go", "mod", "init", "go_cosmic_ast"], cwd=AST_SOURCE_DIR, check=True)
+    subprocess.run(["go", "mod", "tidy"], cwd=AST_SOURCE_DIR, check=True)
+    subprocess.run(["go", "build", "-o", os.path.abspath(AST_BINARY), "."], cwd=AST_SOURCE_DIR, check=True)
 
 # ---------------- TOKEI ELOC ----------------
 def get_eloc_with_tokei(repo_path):
@@ -222,7 +510,7 @@ def get_eloc_with_tokei(repo_path):
 def run_ast_analyzer(repo_path):
     try:
         output = subprocess.check_output(
-            [AST_BINARY, repo_path],
+            [AST_BINARY] + (["-typed"] if AST_TYPED else []) + [repo_path],
             stderr=subprocess.DEVNULL,
         ).decode("utf-8")
 
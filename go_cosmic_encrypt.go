@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Encrypted output. Batch pipelines write the reports to shared storage; with
+// -encrypt-recipient the JSON output and the -reqif file are encrypted to age
+// (X25519) recipients, and only the holders of their identities can read
+// them: age -d -i key.txt report.json.age.
+
+// recipientsFlag collects the age recipients of -encrypt-recipient.
+type recipientsFlag []age.Recipient
+
+// String implements flag.Value.
+func (f *recipientsFlag) String() string {
+	var s []string
+	for _, r := range *f {
+		if x, ok := r.(*age.X25519Recipient); ok {
+			s = append(s, x.String())
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+// Set implements flag.Value for an age1... public key.
+func (f *recipientsFlag) Set(s string) error {
+	r, err := age.ParseX25519Recipient(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
+
+// encryptingWriter returns a writer encrypting to recipients into w, or w
+// itself without recipients. The encryption is only complete once it is
+// closed.
+func encryptingWriter(w io.Writer, recipients []age.Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nopWriteCloser{w}, nil
+	}
+	return age.Encrypt(w, recipients...)
+}
+
+// nopWriteCloser is a writer whose Close does nothing.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// closingSink closes a writer after the sink writing into it.
+type closingSink struct {
+	Sink
+	w io.Closer
+}
+
+func (s closingSink) Close() error {
+	err := s.Sink.Close()
+	if cerr := s.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeEncrypted writes data to the file path, encrypted to recipients if any.
+func writeEncrypted(path string, data []byte, recipients []age.Recipient) error {
+	if len(recipients) == 0 {
+		return os.WriteFile(path, data, 0o644)
+	}
+	var b bytes.Buffer
+	w, err := age.Encrypt(&b, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// decrypt decrypts data with identity, or fails.
+func decrypt(t *testing.T, data []byte, identity age.Identity) []byte {
+	t.Helper()
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	var identities []*age.X25519Identity
+	var recipients recipientsFlag
+	for range 2 {
+		id, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		identities = append(identities, id)
+		if err := recipients.Set(id.Recipient().String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := identities[0].Recipient().String() + "," + identities[1].Recipient().String(); recipients.String() != want {
+		t.Errorf("recipients %s, want %s", recipients.String(), want)
+	}
+	out := Output{Processes: []ProcessReport{{Name: "example.com/shop.main", ID: "1a2b3c4d5e6f", Reads: 1}}}
+
+	// the JSON output, streamed
+	var b bytes.Buffer
+	w, err := encryptingWriter(&b, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSinks(out, closingSink{NewJSONSink(w, false), w}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b.Bytes(), []byte("example.com/shop.main")) {
+		t.Fatalf("the JSON output is not encrypted")
+	}
+	for _, id := range identities {
+		var got Output
+		if err := json.Unmarshal(decrypt(t, b.Bytes(), id), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Processes) != 1 || got.Processes[0].Name != "example.com/shop.main" || got.Processes[0].Reads != 1 {
+			t.Errorf("decrypted %+v, want the processes of %+v", got, out)
+		}
+	}
+
+	// the ReqIF file, written at once
+	path := filepath.Join(t.TempDir(), "out.reqif.age")
+	if err := writeReqIF(path, out, recipients); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain := decrypt(t, data, identities[1]); !strings.Contains(string(plain), `THE-VALUE="example.com/shop.main"`) {
+		t.Errorf("the decrypted ReqIF lacks the process:\n%s", plain)
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := age.Decrypt(bytes.NewReader(data), other); err == nil {
+		t.Errorf("an identity that is not a recipient decrypted the output")
+	}
+	if err := recipients.Set("age1notakey"); err == nil {
+		t.Errorf("Set accepted an invalid recipient")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// ent support. entgo.io/ent generates, in the code base itself, a client
+// package with builders per entity: client.User.Query().Where(...).All(ctx)
+// reads users, client.User.Create().SetName(n).Save(ctx) writes one. Only the
+// builder methods taking a context move data; the data group is the entity.
+// The generated package is not scanned: its calls are classified where the
+// code calls it, like those of any other database library.
+
+// entPkgPath is the import path of the ent runtime.
+const entPkgPath = "entgo.io/ent"
+
+var (
+	// entReadBuilders are the suffixes of the generated reading builder types,
+	// after the entity name (UserQuery, UserClient for Get).
+	entReadBuilders = []string{"Query", "Select", "GroupBy", "Client"}
+	// entWriteBuilders are the suffixes of the generated mutation builder types.
+	entWriteBuilders = []string{"CreateBulk", "Create", "UpdateOne", "Update", "DeleteOne", "Delete", "UpsertOne", "UpsertBulk"}
+)
+
+// isEntGenerated reports whether pkg is a client package generated by ent:
+// it imports the ent runtime and declares the Client type.
+func isEntGenerated(pkg *ssa.Package) bool {
+	if pkg == nil || pkg.Pkg == nil {
+		return false
+	}
+	if _, ok := pkg.Members["Client"].(*ssa.Type); !ok {
+		return false
+	}
+	for _, imp := range pkg.Pkg.Imports() {
+		if imp.Path() == entPkgPath || strings.HasPrefix(imp.Path(), entPkgPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// entMovement returns the movement type of a call to fn if it is a method of a
+// generated ent builder taking a context (All, Only, Count, Save, Exec and
+// their X variants), with the entity it operates on.
+func entMovement(fn *ssa.Function) (typ, entity string) {
+	recv := fn.Signature.Recv()
+	if recv == nil || !isEntGenerated(fn.Pkg) {
+		return "", ""
+	}
+	params := fn.Signature.Params()
+	if params.Len() == 0 || !isNamedType(params.At(0).Type(), "context", "Context") {
+		return "", ""
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return "", ""
+	}
+	name := named.Obj().Name()
+	for _, b := range []struct {
+		typ      string
+		suffixes []string
+	}{{MovementRead, entReadBuilders}, {MovementWrite, entWriteBuilders}} {
+		for _, suffix := range b.suffixes {
+			entity, ok := strings.CutSuffix(name, suffix)
+			if !ok || entity == "" {
+				continue
+			}
+			// the entity has a client of its own: UserClient for UserQuery
+			if _, ok := fn.Pkg.Members[entity+"Client"].(*ssa.Type); ok {
+				return b.typ, entity
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/types"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Declared entry points. Handlers registered through a framework without
+// built-in support are not found as entry points, and their processes are
+// missing from the measurement. "entries -assist" proposes the functions that
+// look like handlers but are not entry points, and writes the ones the user
+// confirms into the entries of the -config file.
+
+// declaredEntry declares an entry function in the -config file.
+type declaredEntry struct {
+	// Function is the function or method as in the source of a process:
+	// example.com/app/api.GetUser or (*example.com/app/api.Server).GetUser.
+	Function string `json:"function"`
+	// Trigger describes the triggering event.
+	Trigger string `json:"trigger,omitempty"`
+}
+
+// trigger returns the process trigger of e.
+func (e declaredEntry) trigger() string {
+	if e.Trigger == "" {
+		return "declared in -config"
+	}
+	return "declared in -config: " + e.Trigger
+}
+
+// declared returns the declared entry of fn.
+func (s settings) declared(fn *ssa.Function) (declaredEntry, bool) {
+	name := fn.String()
+	for _, e := range s.Entries {
+		if e.Function == name {
+			return e, true
+		}
+	}
+	return declaredEntry{}, false
+}
+
+// handlerCandidate is a function looking like a handler.
+type handlerCandidate struct {
+	fn    *ssa.Function
+	shape string
+}
+
+// runEntries implements "entries [root]": it lists the entry functions and
+// their triggers, or with -assist proposes the handler-like functions that
+// are not entry points for confirmation.
+func runEntries(args []string) {
+	fs := flag.NewFlagSet("entries", flag.ExitOnError)
+	var cfg analysisConfig
+	cfg.register(fs)
+	assist := fs.Bool("assist", false, "propose the functions shaped like handlers that are not entry points one by one, and write the confirmed and rejected ones into the -config file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s entries [-assist] [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	path := cfg.configFile
+	if *assist && path == "" {
+		log.Fatalf("entries -assist: -config is required to write the confirmations into")
+	}
+	conf, err := readSettings(path)
+	if errors.Is(err, os.ErrNotExist) && *assist {
+		// written once the first function is confirmed or rejected
+		cfg.configFile = ""
+	} else if err != nil {
+		log.Fatalf("-config: %v", err)
+	}
+
+	a := analyze(root, cfg)
+	if !*assist {
+		for _, fn := range a.entries {
+			fmt.Printf("%s\t%s\n", fn, a.entryPoints[fn].trigger)
+		}
+		return
+	}
+	candidates := handlerCandidates(a, conf)
+	if len(candidates) == 0 {
+		log.Printf("no handler-like functions outside the entry points")
+		return
+	}
+	in := bufio.NewScanner(os.Stdin)
+	confirmed, rejected := 0, 0
+ask:
+	for i, c := range candidates {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s\n  %s at %s\n  entry point? [y]es, [n]o, [s]kip, [q]uit: ",
+			i+1, len(candidates), c.fn, c.shape, a.prog.Fset.Position(c.fn.Pos()))
+		if !in.Scan() {
+			break
+		}
+		switch strings.ToLower(strings.TrimSpace(in.Text())) {
+		case "y", "yes":
+			conf.Entries = append(conf.Entries, declaredEntry{Function: c.fn.String(), Trigger: c.shape})
+			confirmed++
+		case "n", "no":
+			conf.NotEntries = append(conf.NotEntries, c.fn.String())
+			rejected++
+		case "q", "quit":
+			break ask
+		}
+	}
+	if confirmed+rejected == 0 {
+		return
+	}
+	data, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		log.Fatalf("write %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("write %s: %v", path, err)
+	}
+	log.Printf("%s: %d entry points declared, %d functions rejected", path, confirmed, rejected)
+}
+
+// handlerCandidates returns the functions of the analyzed code shaped like
+// handlers that are neither entry points nor called directly by the code,
+// nor declared or rejected in conf, ordered by name.
+func handlerCandidates(a *analysis, conf settings) []handlerCandidate {
+	called := map[*ssa.Function]bool{}
+	var fns []*ssa.Function
+	for _, pkg := range a.pkgs {
+		for _, fn := range packageFunctions(a.prog, pkg) {
+			fns = append(fns, fn)
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					if call, ok := instr.(ssa.CallInstruction); ok {
+						called[call.Common().StaticCallee()] = true
+					}
+				}
+			}
+		}
+	}
+	rejected := map[string]bool{}
+	for _, name := range conf.NotEntries {
+		rejected[name] = true
+	}
+	var candidates []handlerCandidate
+	for _, fn := range fns {
+		if _, isEntry := a.entryPoints[fn]; isEntry || called[fn] || fn.Parent() != nil || rejected[fn.String()] {
+			continue
+		}
+		if _, ok := conf.declared(fn); ok {
+			continue
+		}
+		if shape := handlerShape(fn); shape != "" {
+			candidates = append(candidates, handlerCandidate{fn, shape})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].fn.String() < candidates[j].fn.String() })
+	return candidates
+}
+
+// handlerShape describes the signature of fn if it is one of a handler, or
+// returns "": that of an http.HandlerFunc, of an RPC method taking a context
+// and a request struct and returning a response struct and an error, or of a
+// framework handler taking the framework's context.
+func handlerShape(fn *ssa.Function) string {
+	params, results := fn.Signature.Params(), fn.Signature.Results()
+	switch {
+	case params.Len() == 2 && isNamedType(params.At(0).Type(), "net/http", "ResponseWriter") &&
+		isNamedType(params.At(1).Type(), "net/http", "Request"):
+		return "net/http handler (w http.ResponseWriter, r *http.Request)"
+	case params.Len() == 2 && isNamedType(params.At(0).Type(), "context", "Context") && isEntityPointer(params.At(1).Type()) &&
+		results.Len() == 2 && isEntityPointer(results.At(0).Type()) && types.Identical(results.At(1).Type(), types.Universe.Lookup("error").Type()):
+		return "RPC handler (ctx, *request) (*response, error)"
+	case params.Len() == 1 && isFrameworkContext(params.At(0).Type()):
+		return fmt.Sprintf("framework handler (%s)", types.TypeString(params.At(0).Type(), func(p *types.Package) string { return p.Name() }))
+	}
+	return ""
+}
+
+// isEntityPointer reports whether t is a pointer to an entity struct.
+func isEntityPointer(t types.Type) bool {
+	p, ok := t.(*types.Pointer)
+	return ok && entityName(p.Elem()) != ""
+}
+
+// isFrameworkContext reports whether t is the request context of a web
+// framework outside the standard library, as *gin.Context or echo.Context.
+func isFrameworkContext(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || isStdlib(named.Obj().Pkg().Path()) {
+		return false
+	}
+	name := named.Obj().Name()
+	return name == "Context" || name == "Ctx"
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Configuration ingestion. Reading environment variables (os.Getenv,
+// envconfig) and configuration (viper) moves the settings of the operator
+// into the software. The reads are recorded and tagged TagConfiguration; with
+// -configuration they count, as Reads of stored settings (read) or as
+// Entries from the operator as a functional user (entry).
+
+var (
+	// configurationFuncs are the functions and methods reading configuration,
+	// by package path.
+	configurationFuncs = map[string]map[string]bool{
+		"os":                                   {"Getenv": true, "LookupEnv": true, "Environ": true, "ExpandEnv": true},
+		"github.com/kelseyhightower/envconfig": {"Process": true, "MustProcess": true},
+		"github.com/sethvargo/go-envconfig":    {"Process": true, "ProcessWith": true, "MustProcess": true},
+		"github.com/caarlos0/env/v6":           caarlosEnvParses,
+		"github.com/caarlos0/env/v7":           caarlosEnvParses,
+		"github.com/caarlos0/env/v8":           caarlosEnvParses,
+		"github.com/caarlos0/env/v9":           caarlosEnvParses,
+		"github.com/caarlos0/env/v10":          caarlosEnvParses,
+		"github.com/caarlos0/env/v11":          caarlosEnvParses,
+		viperPkgPath:                           {"Unmarshal": true, "UnmarshalKey": true, "UnmarshalExact": true, "AllSettings": true},
+	}
+	caarlosEnvParses = map[string]bool{"Parse": true, "ParseWithOptions": true, "ParseAs": true}
+)
+
+// viperPkgPath is the import path of viper, whose Get functions and methods
+// (Get, GetString, GetDuration) read a setting.
+const viperPkgPath = "github.com/spf13/viper"
+
+// configurationSource returns the data group read by fn, "environment" or
+// "configuration", or "" if fn reads no configuration.
+func configurationSource(fn *ssa.Function) string {
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return ""
+	}
+	p := fn.Pkg.Pkg.Path()
+	if p == viperPkgPath && (strings.HasPrefix(fn.Name(), "Get") && fn.Name() != "GetViper" || configurationFuncs[p][fn.Name()]) {
+		return "configuration"
+	}
+	if configurationFuncs[p][fn.Name()] {
+		if p == "os" && fn.Signature.Recv() != nil {
+			return ""
+		}
+		return "environment"
+	}
+	return ""
+}
+
+// configurationMovement returns the movement type of a configuration read.
+func (c analysisConfig) configurationMovement() string {
+	if c.configuration == "entry" {
+		return MovementEntry
+	}
+	return MovementRead
+}
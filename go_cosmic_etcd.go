@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// etcd support. The clientv3 KV and Watcher interfaces, embedded in the
+// Client, hold the state of infrastructure services: Get and Watch read keys,
+// Put, Delete and Txn write them. The data group is the top-level element of
+// the key, as keys are hierarchical ("/services/api/node1" is a service).
+
+var (
+	// etcdPkgPaths are the import paths of the etcd v3 client.
+	etcdPkgPaths = []string{
+		"go.etcd.io/etcd/client/v3",
+		"go.etcd.io/etcd/clientv3",
+		"github.com/coreos/etcd/clientv3",
+	}
+
+	etcdReads  = map[string]bool{"Get": true, "Watch": true}
+	etcdWrites = map[string]bool{"Put": true, "Delete": true, "Txn": true}
+)
+
+// isEtcdCall reports whether call calls a method of the etcd client.
+func isEtcdCall(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return call.Method.Pkg() != nil && inPaths(call.Method.Pkg().Path(), etcdPkgPaths)
+	}
+	sc := call.StaticCallee()
+	return sc != nil && sc.Pkg != nil && sc.Pkg.Pkg != nil && inPaths(sc.Pkg.Pkg.Path(), etcdPkgPaths)
+}
+
+// etcdKeyGroup returns the data group of the constant key among args, its
+// first path element, or "".
+func etcdKeyGroup(args []ssa.Value) string {
+	for _, arg := range args {
+		if key, ok := constString(arg); ok {
+			group, _, _ := strings.Cut(strings.TrimPrefix(key, "/"), "/")
+			return group
+		}
+	}
+	return ""
+}
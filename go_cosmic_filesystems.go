@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Filesystem abstractions. Code written for testability opens and writes its
+// files through an afero.Fs or an fs.FS instead of package os: their Open,
+// ReadFile and Create are Reads and Writes of the files, as the os functions
+// are, also when called through the interfaces. The filesystems held in
+// memory (afero.MemMapFs, fstest.MapFS) or compiled into the program
+// (embed.FS) are not persistent storage, and their files move nothing.
+
+const (
+	aferoPkgPath = "github.com/spf13/afero"
+	ioFSPkgPath  = "io/fs"
+)
+
+var (
+	// aferoReads and aferoWrites are the file operations of afero: the
+	// methods of Fs and Afero and the functions taking an Fs.
+	aferoReads = map[string]bool{
+		"Open": true, "ReadFile": true, "ReadDir": true, "Glob": true,
+	}
+	aferoWrites = map[string]bool{
+		"Create": true, "WriteFile": true, "WriteReader": true, "SafeWriteReader": true,
+		"Remove": true, "RemoveAll": true, "Rename": true, "Mkdir": true, "MkdirAll": true,
+	}
+	// ioFSReads are the file operations of io/fs: the methods of FS,
+	// ReadFileFS, ReadDirFS and GlobFS and the functions taking an FS.
+	ioFSReads = map[string]bool{
+		"Open": true, "ReadFile": true, "ReadDir": true, "Glob": true,
+	}
+)
+
+// memoryFilesystems are the filesystems held in memory or compiled into the
+// program, by package path.
+var memoryFilesystems = map[string]map[string]bool{
+	"embed":          {"FS": true},
+	"testing/fstest": {"MapFS": true},
+	aferoPkgPath:     {"MemMapFs": true},
+}
+
+// isFilesystemPackage reports whether p is a filesystem abstraction.
+func isFilesystemPackage(p *types.Package) bool {
+	return p != nil && (p.Path() == aferoPkgPath || p.Path() == ioFSPkgPath)
+}
+
+// memoryFilesystemConstructors are the functions returning a filesystem
+// held in memory, by package path.
+var memoryFilesystemConstructors = map[string]map[string]bool{
+	aferoPkgPath: {"NewMemMapFs": true},
+}
+
+// inMemoryFilesystem reports whether the filesystem used by call, the
+// receiver of its method or the first argument of a function, is held in
+// memory or compiled into the program.
+func inMemoryFilesystem(call *ssa.CallCommon) bool {
+	var fsys ssa.Value
+	switch {
+	case call.IsInvoke():
+		fsys = unwrapFilesystem(call.Value)
+	case len(call.Args) > 0:
+		fsys = unwrapFilesystem(call.Args[0])
+	default:
+		return false
+	}
+	if c, ok := fsys.(*ssa.Call); ok && matchesTable(c.Call.StaticCallee(), memoryFilesystemConstructors) {
+		return true
+	}
+	named, ok := derefType(fsys.Type()).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && memoryFilesystems[named.Obj().Pkg().Path()][named.Obj().Name()]
+}
+
+// unwrapFilesystem returns the filesystem converted to an interface by v, or
+// v.
+func unwrapFilesystem(v ssa.Value) ssa.Value {
+	for {
+		switch vv := v.(type) {
+		case *ssa.MakeInterface:
+			v = vv.X
+		case *ssa.ChangeInterface:
+			v = vv.X
+		default:
+			return v
+		}
+	}
+}
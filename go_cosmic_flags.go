@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Flag audit. The command-line flags of a CLI are its inputs: a flag that is
+// declared but never read by code reachable from an entry point is either dead
+// configuration or a sign that the traversal misses the code reading it.
+
+// flagPackages are the packages whose functions and FlagSet methods declare
+// flags; cobra commands declare theirs on the pflag FlagSet of Flags().
+var flagPackages = map[string]bool{
+	"flag":                   true,
+	"github.com/spf13/pflag": true,
+}
+
+// cobraRunFields are the fields of cobra.Command holding the functions cobra
+// calls to run a command; they are roots of the flag audit.
+var cobraRunFields = map[string]bool{
+	"Run": true, "RunE": true,
+	"PreRun": true, "PreRunE": true, "PersistentPreRun": true, "PersistentPreRunE": true,
+	"PostRun": true, "PostRunE": true, "PersistentPostRun": true, "PersistentPostRunE": true,
+}
+
+// commandLineGroup is the data group of the flags and arguments of a CLI.
+const commandLineGroup = "command line"
+
+// parsesCommandLine reports whether fn parses or declares command-line flags,
+// or returns the command-line arguments: flag.Parse, flag.String, pflag.IntP,
+// (*flag.FlagSet).BoolVar, flag.Args. With -cli each such call is an Entry of
+// the human user's command line.
+func parsesCommandLine(fn *ssa.Function) bool {
+	if fn.Pkg == nil || !flagPackages[fn.Pkg.Pkg.Path()] {
+		return false
+	}
+	name := strings.TrimSuffix(fn.Name(), "P")
+	switch {
+	case name == "Parse" || name == "Arg" || name == "Args" || name == "Func" || name == "BoolFunc":
+		return true
+	case strings.HasSuffix(name, "Var"):
+		return true
+	}
+	// a declaration returns a pointer to the value, not to a flag package type
+	res := fn.Signature.Results()
+	if res.Len() != 1 {
+		return false
+	}
+	ptr, ok := res.At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	return !ok || named.Obj().Pkg() == nil || !flagPackages[named.Obj().Pkg().Path()]
+}
+
+// flagDecl is a flag declaration: the flag name and the value holding it,
+// the pointer returned by flag.String or the one passed to flag.StringVar.
+type flagDecl struct {
+	name string
+	pos  token.Position
+	val  ssa.Value
+	call ssa.Instruction
+}
+
+// runFlags implements "flags [root]": it lists the flags declared with flag or
+// pflag (including cobra commands) whose value is never read by the code
+// reachable from an entry point, nor looked up by name (Lookup, GetString).
+func runFlags(args []string) {
+	fs := flag.NewFlagSet("flags", flag.ExitOnError)
+	var cfg analysisConfig
+	cfg.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s flags [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+
+	a := analyze(root, cfg)
+	var decls []flagDecl
+	roots := append([]*ssa.Function(nil), a.entries...)
+	for _, pkg := range a.pkgs {
+		for _, fn := range packageFunctions(a.prog, pkg) {
+			decls = append(decls, flagDeclarations(fn)...)
+			roots = append(roots, cobraRunFuncs(fn)...)
+		}
+	}
+	if len(decls) == 0 {
+		log.Printf("no flags declared")
+		return
+	}
+	g := newCallGraph(roots, a.succ)
+	reachable := map[*ssa.Function]bool{}
+	for _, fn := range g.funcs {
+		reachable[fn] = true
+	}
+	r := collectFlagReads(g.funcs)
+
+	sort.Slice(decls, func(i, j int) bool {
+		if decls[i].pos.Filename != decls[j].pos.Filename {
+			return decls[i].pos.Filename < decls[j].pos.Filename
+		}
+		return decls[i].pos.Line < decls[j].pos.Line
+	})
+	unused := 0
+	for _, d := range decls {
+		if r.names[d.name] || r.isRead(d, reachable) {
+			continue
+		}
+		unused++
+		fmt.Printf("-%s\t%s:%d\n", d.name, d.pos.Filename, d.pos.Line)
+	}
+	log.Printf("%d of %d declared flags are never read", unused, len(decls))
+}
+
+// flagDeclarations returns the flags declared by the calls of fn.
+func flagDeclarations(fn *ssa.Function) []flagDecl {
+	var decls []flagDecl
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			sc := call.Call.StaticCallee()
+			if sc == nil || sc.Pkg == nil || !flagPackages[sc.Pkg.Pkg.Path()] {
+				continue
+			}
+			args := call.Call.Args
+			if sc.Signature.Recv() != nil && len(args) > 0 {
+				args = args[1:]
+			}
+			name := strings.TrimSuffix(sc.Name(), "P")
+			var val ssa.Value
+			nameArg := 0
+			switch {
+			case name == "Func" || name == "BoolFunc":
+				continue // the function is called with the value
+			case strings.HasSuffix(name, "Var"):
+				// StringVar(&p, "name", ...), Var(value, "name", ...)
+				if len(args) < 2 {
+					continue
+				}
+				val, nameArg = args[0], 1
+				if mi, ok := val.(*ssa.MakeInterface); ok {
+					val = mi.X
+				}
+			default:
+				// p := String("name", ...), but not Lookup("name") or NewFlagSet("name", ...)
+				res := sc.Signature.Results()
+				if res.Len() != 1 {
+					continue
+				}
+				ptr, ok := res.At(0).Type().(*types.Pointer)
+				if !ok {
+					continue
+				}
+				if named, ok := ptr.Elem().(*types.Named); ok && named.Obj().Pkg() != nil && flagPackages[named.Obj().Pkg().Path()] {
+					continue
+				}
+				val = call
+			}
+			if nameArg >= len(args) {
+				continue
+			}
+			if s, ok := constString(args[nameArg]); ok {
+				decls = append(decls, flagDecl{name: s, pos: fn.Prog.Fset.Position(call.Pos()), val: val, call: call})
+			}
+		}
+	}
+	return decls
+}
+
+// cobraRunFuncs returns the run functions fn stores into cobra commands.
+func cobraRunFuncs(fn *ssa.Function) []*ssa.Function {
+	var fns []*ssa.Function
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			st, ok := instr.(*ssa.Store)
+			if !ok {
+				continue
+			}
+			fa, ok := st.Addr.(*ssa.FieldAddr)
+			if !ok || fieldOf(fa) == nil || !cobraRunFields[fieldOf(fa).Name()] {
+				continue
+			}
+			if isNamedType(fa.X.Type(), "github.com/spf13/cobra", "Command") {
+				fns = append(fns, extractFunctionsFromValue(st.Val, nil)...)
+			}
+		}
+	}
+	return fns
+}
+
+// flagReads records what the reachable functions read: globals, struct fields
+// and flags looked up by name.
+type flagReads struct {
+	globals map[*ssa.Global]bool
+	fields  map[*types.Var]bool
+	names   map[string]bool
+}
+
+// collectFlagReads scans funcs for loads of globals and struct fields and for
+// lookups of flags by name.
+func collectFlagReads(funcs []*ssa.Function) *flagReads {
+	r := &flagReads{globals: map[*ssa.Global]bool{}, fields: map[*types.Var]bool{}, names: map[string]bool{}}
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch in := instr.(type) {
+				case *ssa.UnOp:
+					if in.Op != token.MUL {
+						continue
+					}
+					switch x := in.X.(type) {
+					case *ssa.Global:
+						r.globals[x] = true
+					case *ssa.FieldAddr:
+						if f := fieldOf(x); f != nil {
+							r.fields[f] = true
+						}
+					}
+				case *ssa.Call:
+					sc := in.Call.StaticCallee()
+					if sc == nil || sc.Pkg == nil || !flagPackages[sc.Pkg.Pkg.Path()] {
+						continue
+					}
+					if n := sc.Name(); n == "Lookup" || n == "Changed" || strings.HasPrefix(n, "Get") {
+						args := in.Call.Args
+						if sc.Signature.Recv() != nil && len(args) > 0 {
+							args = args[1:]
+						}
+						if len(args) > 0 {
+							if s, ok := constString(args[0]); ok {
+								r.names[s] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return r
+}
+
+// isRead reports whether the value of flag d is read by a reachable function:
+// dereferenced where it is declared, or loaded from the global or struct
+// field it is declared into or stored in.
+func (r *flagReads) isRead(d flagDecl, reachable map[*ssa.Function]bool) bool {
+	switch v := d.val.(type) {
+	case *ssa.Global:
+		return r.globals[v]
+	case *ssa.FieldAddr:
+		return r.fields[fieldOf(v)]
+	}
+	if d.val.Referrers() == nil {
+		return true
+	}
+	for _, ref := range *d.val.Referrers() {
+		if ref == d.call {
+			continue
+		}
+		if st, ok := ref.(*ssa.Store); ok && st.Val == d.val {
+			// the pointer is kept in a global or field: p = flag.String(...)
+			switch addr := st.Addr.(type) {
+			case *ssa.Global:
+				if r.globals[addr] {
+					return true
+				}
+				continue
+			case *ssa.FieldAddr:
+				if r.fields[fieldOf(addr)] {
+					return true
+				}
+				continue
+			}
+		}
+		if st, ok := ref.(*ssa.Store); ok && st.Addr == d.val {
+			continue // a default assigned before parsing
+		}
+		if reachable[ref.Parent()] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Generated code. Server stubs generated from OpenAPI (oapi-codegen) or
+// protobuf (protoc) decode requests and encode responses on behalf of the
+// handwritten handlers. Their movements are tagged, the processes they enter
+// marked, and the totals are also given without them, so that a measurement
+// can be compared with and without the generated plumbing.
+
+// TagGenerated marks movements of functions declared in generated files,
+// which -exclude-generated leaves out of the counts.
+const TagGenerated = "generated"
+
+// MovementTotals are movement counts by type.
+type MovementTotals struct {
+	Entries int `json:"entries"`
+	Exits   int `json:"exits"`
+	Reads   int `json:"reads"`
+	Writes  int `json:"writes"`
+}
+
+// generatedCode holds the names of the generated files of the code.
+type generatedCode map[string]bool
+
+// generatedFiles returns the files of pkgs carrying the
+// "// Code generated ... DO NOT EDIT." comment.
+func generatedFiles(fset *token.FileSet, pkgs []*packages.Package) generatedCode {
+	files := generatedCode{}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, f := range pkg.Syntax {
+			if ast.IsGenerated(f) {
+				files[fset.Position(f.Pos()).Filename] = true
+			}
+		}
+	})
+	return files
+}
+
+// declares reports whether fn is declared in a generated file. Wrappers and
+// other synthetic functions have no position and are not.
+func (files generatedCode) declares(fn *ssa.Function) bool {
+	return fn.Pos().IsValid() && files[fn.Prog.Fset.Position(fn.Pos()).Filename]
+}
+
+// handwritten returns the counts of pr without the movements of generated
+// code, counted as pr is, or nil if pr counts none of them.
+func (pr ProcessReport) handwritten(cfg analysisConfig) *MovementTotals {
+	uncounted := cfg.uncounted()
+	generated := false
+	for _, m := range pr.Movements {
+		if hasTag(m, TagGenerated) && !hasAnyTag(m, uncounted) {
+			generated = true
+			break
+		}
+	}
+	if !generated {
+		return nil
+	}
+	var c Counts
+	for _, m := range pr.Movements {
+		c.addMovement(m)
+	}
+	uncounted = append(uncounted, TagGenerated)
+	c.uncount(uncounted...)
+	own := ProcessReport{Entries: c.Entries, Exits: c.Exits, Reads: c.Reads, Writes: c.Writes, Movements: c.Movements}
+	own.dedupe(uncounted, cfg.dedupe)
+	return &MovementTotals{Entries: own.Entries, Exits: own.Exits, Reads: own.Reads, Writes: own.Writes}
+}
+
+// addHandwritten adds the counts of pr without generated code to t.
+func (t *MovementTotals) addHandwritten(pr ProcessReport) {
+	own := pr.Handwritten
+	if own == nil {
+		own = &MovementTotals{Entries: pr.Entries, Exits: pr.Exits, Reads: pr.Reads, Writes: pr.Writes}
+	}
+	t.Entries += own.Entries
+	t.Exits += own.Exits
+	t.Reads += own.Reads
+	t.Writes += own.Writes
+}
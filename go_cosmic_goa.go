@@ -0,0 +1,97 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// goa support. goa.design generates, per service, NewEndpoints(Service)
+// wrapping each method of the service implementation in an endpoint, and an
+// HTTP server package whose MountXxxHandler functions register the routes of
+// method Xxx on a goa Muxer. The implementation methods are the processes,
+// with the routes mounted for them.
+
+const (
+	// goaPkgPath is the import path of the goa endpoint package.
+	goaPkgPath = "goa.design/goa/v3/pkg"
+	// goaHTTPPkgPath is the import path of the goa HTTP transport.
+	goaHTTPPkgPath = "goa.design/goa/v3/http"
+)
+
+// isGoaEndpointsConstructor reports whether fn is a goa generated
+// NewEndpoints(Service) *Endpoints, Endpoints holding goa endpoints.
+func isGoaEndpointsConstructor(fn *ssa.Function) bool {
+	if fn.Name() != "NewEndpoints" || fn.Signature.Recv() != nil ||
+		fn.Signature.Params().Len() != 1 || fn.Signature.Results().Len() != 1 {
+		return false
+	}
+	if !types.IsInterface(fn.Signature.Params().At(0).Type()) {
+		return false
+	}
+	res := fn.Signature.Results().At(0).Type()
+	if !isNamedType(res, fn.Pkg.Pkg.Path(), "Endpoints") {
+		return false
+	}
+	st, ok := res.(*types.Pointer).Elem().Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if isNamedType(st.Field(i).Type(), goaPkgPath, "Endpoint") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectGoa records the route registered by fn if it is a generated
+// MountXxxHandler(goahttp.Muxer, http.Handler) of a goa HTTP server package,
+// for the NewEndpoints of the service.
+func (r mountedRoutes) collectGoa(fn *ssa.Function) {
+	method, ok := strings.CutPrefix(fn.Name(), "Mount")
+	method, ok2 := strings.CutSuffix(method, "Handler")
+	params := fn.Signature.Params()
+	if !ok || !ok2 || method == "" || params.Len() != 2 || !isNamedType(params.At(0).Type(), goaHTTPPkgPath, "Muxer") {
+		return
+	}
+	svc := goaServicePackage(fn.Pkg)
+	if svc == "" {
+		return
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok || !call.Common().IsInvoke() || call.Common().Method.Name() != "Handle" {
+				continue
+			}
+			if args := call.Common().Args; len(args) == 3 {
+				if pattern, ok := constString(args[1]); ok {
+					if verb, ok := constString(args[0]); ok && httpMethods[verb] {
+						pattern = verb + " " + pattern
+					}
+					r.add(svc+".NewEndpoints", method, pattern)
+				}
+			}
+		}
+	}
+}
+
+// goaServicePackage returns the path of the service package whose endpoints
+// the goa HTTP server package pkg serves, from its New(*Endpoints, ...).
+func goaServicePackage(pkg *ssa.Package) string {
+	newFn, ok := pkg.Members["New"].(*ssa.Function)
+	if !ok || newFn.Signature.Params().Len() == 0 {
+		return ""
+	}
+	ptr, ok := newFn.Signature.Params().At(0).Type().(*types.Pointer)
+	if !ok {
+		return ""
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Name() != "Endpoints" || named.Obj().Pkg() == nil {
+		return ""
+	}
+	return named.Obj().Pkg().Path()
+}
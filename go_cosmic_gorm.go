@@ -0,0 +1,111 @@
+package main
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// GORM support. Queries are built by chaining methods of *gorm.DB, as in
+// db.Model(&User{}).Where("age > ?", 18).Find(&users); only the finisher
+// moves data, so its data group is found on the chain: a Table name, a Raw
+// statement, the Model, or else the type of the destination or value passed
+// to the finisher itself.
+
+// gormPkgPath is the import path of GORM v2.
+const gormPkgPath = "gorm.io/gorm"
+
+// isGormMethod reports whether fn is a method of GORM.
+func isGormMethod(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Pkg.Pkg != nil && fn.Pkg.Pkg.Path() == gormPkgPath && fn.Signature.Recv() != nil
+}
+
+// gormDataGroup names the table a GORM finisher call operates on.
+func gormDataGroup(call *ssa.CallCommon) string {
+	if len(call.Args) == 0 {
+		return ""
+	}
+	if name := gormChainTable(call.Args[0], 0); name != "" {
+		return name
+	}
+	// the finisher's own statement (Exec) or destination / value (Find, Create)
+	for _, arg := range call.Args[1:] {
+		if s, ok := constString(arg); ok {
+			if m := sqlTableRe.FindStringSubmatch(s); m != nil {
+				return m[1]
+			}
+			continue
+		}
+		if name := modelName(arg); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// gormChainTable returns the table named by the builder calls v results from.
+func gormChainTable(v ssa.Value, depth int) string {
+	call, ok := v.(*ssa.Call)
+	if !ok || depth > 16 {
+		return ""
+	}
+	sc := call.Call.StaticCallee()
+	args := call.Call.Args
+	if sc == nil || !isGormMethod(sc) || len(args) == 0 {
+		return ""
+	}
+	switch sc.Name() {
+	case "Table":
+		if len(args) > 1 {
+			if s, ok := constString(args[1]); ok {
+				return s
+			}
+		}
+	case "Raw":
+		if len(args) > 1 {
+			if s, ok := constString(args[1]); ok {
+				if m := sqlTableRe.FindStringSubmatch(s); m != nil {
+					return m[1]
+				}
+			}
+		}
+	case "Model":
+		if len(args) > 1 {
+			if name := modelName(args[1]); name != "" {
+				return name
+			}
+		}
+	}
+	return gormChainTable(args[0], depth+1)
+}
+
+// modelName returns the name of the struct type of a model argument such as
+// &User{} or &users ([]User), "" for other values.
+func modelName(v ssa.Value) string {
+	if mi, ok := v.(*ssa.MakeInterface); ok {
+		v = mi.X
+	}
+	t := v.Type()
+	for {
+		switch tt := t.(type) {
+		case *types.Pointer:
+			t = tt.Elem()
+			continue
+		case *types.Slice:
+			t = tt.Elem()
+			continue
+		case *types.Array:
+			t = tt.Elem()
+			continue
+		}
+		break
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return ""
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return ""
+	}
+	return named.Obj().Name()
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/ssa"
+)
+
+// callGraph is the part of the call graph reachable from the entry functions,
+// with functions numbered densely in discovery order. Its shape depends on the
+// traversal policy (static callees, or the pointer-analysis callgraph), so a
+// graph and the summaries computed over it are only valid for one policy.
+type callGraph struct {
+	funcs []*ssa.Function
+	index map[*ssa.Function]int32
+	succs [][]int32
+}
+
+// newCallGraph explores the functions reachable from entries through succ.
+func newCallGraph(entries []*ssa.Function, succ func(*ssa.Function) []*ssa.Function) *callGraph {
+	g := &callGraph{index: map[*ssa.Function]int32{}}
+	var queue []int32
+	id := func(fn *ssa.Function) int32 {
+		if i, ok := g.index[fn]; ok {
+			return i
+		}
+		i := int32(len(g.funcs))
+		g.index[fn] = i
+		g.funcs = append(g.funcs, fn)
+		g.succs = append(g.succs, nil)
+		queue = append(queue, i)
+		return i
+	}
+	for _, fn := range entries {
+		id(fn)
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, callee := range succ(g.funcs[i]) {
+			if callee != nil {
+				g.succs[i] = append(g.succs[i], id(callee))
+			}
+		}
+	}
+	return g
+}
+
+// staticCallees returns the static callees of fn's call, defer and go instructions.
+func staticCallees(fn *ssa.Function) []*ssa.Function {
+	var callees []*ssa.Function
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok {
+				if sc := call.Common().StaticCallee(); sc != nil {
+					callees = append(callees, sc)
+				}
+			}
+		}
+	}
+	return callees
+}
+
+// pointerCallees returns the callees of fn in the pointer-analysis callgraph,
+// falling back to its static callees when fn is not part of the callgraph.
+func pointerCallees(funcToNode map[*ssa.Function]*callgraph.Node) func(*ssa.Function) []*ssa.Function {
+	return func(fn *ssa.Function) []*ssa.Function {
+		node := funcToNode[fn]
+		if node == nil {
+			return staticCallees(fn)
+		}
+		var callees []*ssa.Function
+		for _, e := range node.Out {
+			if e != nil && e.Callee != nil && e.Callee.Func != nil {
+				callees = append(callees, e.Callee.Func)
+			}
+		}
+		return callees
+	}
+}
+
+// interfaceCallees returns, per function, every implementation of the
+// methods it calls through interfaces, by class hierarchy analysis: an over-
+// approximation of the dynamic callees static mode does not traverse. Port
+// calls are left out, as they are counted at the call.
+func (s settings) interfaceCallees(prog *ssa.Program) map[*ssa.Function][]*ssa.Function {
+	callees := map[*ssa.Function][]*ssa.Function{}
+	for fn, n := range cha.CallGraph(prog).Nodes {
+		if fn == nil {
+			continue
+		}
+		for _, e := range n.Out {
+			if e.Site == nil || !e.Site.Common().IsInvoke() || s.port(e.Site.Common().Method) != nil {
+				continue
+			}
+			callees[fn] = append(callees[fn], e.Callee.Func)
+		}
+	}
+	return callees
+}
+
+// withCallees adds extra callees to the callees of succ, for calls the
+// callgraph does not show: callbacks invoked by dependencies on behalf of the
+// caller (dataloader batches, directory walks).
+func withCallees(succ func(*ssa.Function) []*ssa.Function, extra map[*ssa.Function][]*ssa.Function) func(*ssa.Function) []*ssa.Function {
+	return func(fn *ssa.Function) []*ssa.Function {
+		callees := succ(fn)
+		if more := extra[fn]; len(more) > 0 {
+			callees = append(callees[:len(callees):len(callees)], more...)
+		}
+		return callees
+	}
+}
+
+// bitset is a fixed-size set of function indices.
+type bitset []uint64
+
+func newBitset(n int) bitset      { return make(bitset, (n+63)/64) }
+func (b bitset) set(i int32)      { b[i/64] |= 1 << (uint(i) % 64) }
+func (b bitset) has(i int32) bool { return b[i/64]&(1<<(uint(i)%64)) != 0 }
+
+func (b bitset) union(o bitset) {
+	for i, w := range o {
+		b[i] |= w
+	}
+}
+
+func (b bitset) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// posSet is a set of positions among n. It is a sorted slice while that is
+// smaller than a bitset of n, below n/32 positions, so that the many small
+// sets of a large graph (helpers, leaf functions) take memory in proportion
+// to their size rather than to the graph's.
+type posSet struct {
+	n      int
+	sparse []int32 // sorted, if dense is nil
+	dense  bitset
+}
+
+// newPosSet returns the set of the given positions among n.
+func newPosSet(n int, positions []int32) posSet {
+	s := posSet{n: n, sparse: slices.Clone(positions)}
+	slices.Sort(s.sparse)
+	s.sparse = slices.Compact(s.sparse)
+	s.densify()
+	return s
+}
+
+// densify turns s into a bitset once it is the smaller representation.
+func (s *posSet) densify() {
+	if s.dense != nil || len(s.sparse) <= s.n/32 {
+		return
+	}
+	s.dense = newBitset(s.n)
+	for _, p := range s.sparse {
+		s.dense.set(p)
+	}
+	s.sparse = nil
+}
+
+// union adds the positions of o to s.
+func (s *posSet) union(o posSet) {
+	switch {
+	case s.dense != nil && o.dense != nil:
+		s.dense.union(o.dense)
+	case s.dense != nil:
+		for _, p := range o.sparse {
+			s.dense.set(p)
+		}
+	case o.dense != nil:
+		s.dense = slices.Clone(o.dense)
+		for _, p := range s.sparse {
+			s.dense.set(p)
+		}
+		s.sparse = nil
+	default:
+		s.sparse = mergeSorted(s.sparse, o.sparse)
+		s.densify()
+	}
+}
+
+func (s posSet) has(p int32) bool {
+	if s.dense != nil {
+		return s.dense.has(p)
+	}
+	_, ok := slices.BinarySearch(s.sparse, p)
+	return ok
+}
+
+func (s posSet) count() int {
+	if s.dense != nil {
+		return s.dense.count()
+	}
+	return len(s.sparse)
+}
+
+// mergeSorted returns the sorted union of the sorted slices a and b.
+func mergeSorted(a, b []int32) []int32 {
+	merged := make([]int32, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case b[0] < a[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// movementSummaries memoizes, per strongly connected component of the call
+// graph, the set of functions reachable from it. Processes share most of their
+// subgraphs (middleware, helpers), so each subgraph is explored once instead of
+// once per process, and a process report only visits the functions that carry
+// movements.
+//
+// With pruning, components that transitively contain no classifiable movement
+// are left out of the sets entirely: they get no set of their own and other
+// sets are sized for the remaining functions only.
+type movementSummaries struct {
+	g       *callGraph
+	comp    []int32  // function -> component
+	reach   []posSet // component -> reachable positions (entry components only, after build)
+	pos     []int32  // function -> position in the sets, -1 if pruned
+	bearing []bearingFunc
+}
+
+// bearingFunc is a function with local movements, by graph index and counts ID.
+type bearingFunc struct {
+	v, id int32
+}
+
+// summarize computes the reachable sets of the entry components. Components are
+// processed callees first; a component's set is released once all components
+// calling it are done, unless it belongs to an entry.
+func summarize(g *callGraph, entries []*ssa.Function, localCounts *countsTable, prune bool) *movementSummaries {
+	s := &movementSummaries{g: g}
+	comps := s.components()
+
+	pinned := make([]bool, len(comps))
+	for _, fn := range entries {
+		pinned[s.comp[g.index[fn]]] = true
+	}
+	hasMovements := func(v int32) bool {
+		_, ok := localCounts.id(g.funcs[v])
+		return ok
+	}
+	// refs counts the distinct calling components of each component.
+	refs := make([]int32, len(comps))
+	children := make([][]int32, len(comps))
+	last := make([]int32, len(comps))
+	for i := range last {
+		last[i] = -1
+	}
+	for c, members := range comps {
+		for _, v := range members {
+			for _, w := range g.succs[v] {
+				cw := s.comp[w]
+				if cw != int32(c) && last[cw] != int32(c) {
+					last[cw] = int32(c)
+					children[c] = append(children[c], cw)
+					refs[cw]++
+				}
+			}
+		}
+	}
+
+	// live components reach a movement (or are entries); without pruning all are live.
+	live := make([]bool, len(comps))
+	for c, members := range comps {
+		live[c] = !prune || pinned[c]
+		for _, v := range members {
+			live[c] = live[c] || hasMovements(v)
+		}
+		for _, cw := range children[c] {
+			live[c] = live[c] || live[cw]
+		}
+	}
+	s.pos = make([]int32, len(g.funcs))
+	n := 0
+	for v := range g.funcs {
+		s.pos[v] = -1
+		if live[s.comp[v]] {
+			s.pos[v] = int32(n)
+			n++
+		}
+	}
+
+	s.reach = make([]posSet, len(comps))
+	var positions []int32
+	for c, members := range comps {
+		if !live[c] {
+			continue
+		}
+		positions = positions[:0]
+		for _, v := range members {
+			positions = append(positions, s.pos[v])
+		}
+		set := newPosSet(n, positions)
+		for _, cw := range children[c] {
+			if !live[cw] {
+				continue
+			}
+			set.union(s.reach[cw])
+			if refs[cw]--; refs[cw] == 0 && !pinned[cw] {
+				s.reach[cw] = posSet{}
+			}
+		}
+		s.reach[c] = set
+	}
+
+	for v, fn := range g.funcs {
+		if id, ok := localCounts.id(fn); ok {
+			s.bearing = append(s.bearing, bearingFunc{v: int32(v), id: id})
+		}
+	}
+	return s
+}
+
+// components returns the strongly connected components of the graph in reverse
+// topological order (callees before callers), using an iterative Tarjan search.
+func (s *movementSummaries) components() [][]int32 {
+	g := s.g
+	n := len(g.funcs)
+	s.comp = make([]int32, n)
+	index := make([]int32, n) // 0 means unvisited
+	low := make([]int32, n)
+	onStack := make([]bool, n)
+	var stack []int32
+	var comps [][]int32
+	type frame struct {
+		v    int32
+		edge int
+	}
+	var calls []frame
+	next := int32(1)
+	visit := func(v int32) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		calls = append(calls, frame{v: v})
+	}
+	for root := int32(0); root < int32(n); root++ {
+		if index[root] != 0 {
+			continue
+		}
+		visit(root)
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			v := f.v
+			if f.edge < len(g.succs[v]) {
+				w := g.succs[v][f.edge]
+				f.edge++
+				if index[w] == 0 {
+					visit(w)
+				} else if onStack[w] && index[w] < low[v] {
+					low[v] = index[w]
+				}
+				continue
+			}
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				if p := calls[len(calls)-1].v; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] == index[v] {
+				id := int32(len(comps))
+				var members []int32
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					s.comp[w] = id
+					members = append(members, w)
+					if w == v {
+						break
+					}
+				}
+				comps = append(comps, members)
+			}
+		}
+	}
+	return comps
+}
+
+// functions returns the functions included in the process of entry function
+// fn, sorted by name.
+func (s *movementSummaries) functions(fn *ssa.Function) []FunctionRef {
+	reach := s.reach[s.comp[s.g.index[fn]]]
+	var refs []FunctionRef
+	for v, f := range s.g.funcs {
+		if p := s.pos[v]; p < 0 || !reach.has(p) {
+			continue
+		}
+		ref := FunctionRef{Name: f.String()}
+		if f.Pos().IsValid() {
+			ref.Pos = f.Prog.Fset.Position(f.Pos()).String()
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// report builds the process report of entry function fn from its memoized summary.
+func (s *movementSummaries) report(fn *ssa.Function, localCounts *countsTable) ProcessReport {
+	pr := ProcessReport{
+		Name:   fmt.Sprintf("%s.%s", fn.Pkg.Pkg.Path(), fn.Name()),
+		Source: fn.String(),
+	}
+	reach := s.reach[s.comp[s.g.index[fn]]]
+	pr.Funcs = reach.count()
+	for _, b := range s.bearing {
+		if reach.has(s.pos[b.v]) {
+			pr.add(localCounts.get(b.id))
+		}
+	}
+	return pr
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestPosSet checks the unions of sparse and dense sets against maps, on both
+// sides of the size at which a set turns into a bitset.
+func TestPosSet(t *testing.T) {
+	const n = 1000
+	rng := rand.New(rand.NewSource(1))
+	random := func(k int) ([]int32, map[int32]bool) {
+		var positions []int32
+		want := map[int32]bool{}
+		for i := 0; i < k; i++ {
+			p := int32(rng.Intn(n))
+			positions = append(positions, p)
+			want[p] = true
+		}
+		return positions, want
+	}
+	for _, sizes := range [][2]int{{3, 5}, {3, 200}, {200, 3}, {200, 300}, {20, 20}} {
+		a, want := random(sizes[0])
+		b, more := random(sizes[1])
+		for p := range more {
+			want[p] = true
+		}
+		s := newPosSet(n, a)
+		s.union(newPosSet(n, b))
+		if s.count() != len(want) {
+			t.Errorf("%v: count %d, want %d", sizes, s.count(), len(want))
+		}
+		for p := int32(0); p < n; p++ {
+			if s.has(p) != want[p] {
+				t.Errorf("%v: has(%d) = %v", sizes, p, s.has(p))
+			}
+		}
+		if dense := s.dense != nil; dense != (len(want) > n/32) {
+			t.Errorf("%v: %d positions, dense = %v", sizes, len(want), dense)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// GraphQL support. gqlgen resolvers are reached from generated code through
+// the ResolverRoot interface, so they are found from the resolver root given
+// to NewExecutableSchema. Dataloaders call their batch functions from the
+// loader's own goroutine; the batch functions are processes of their own
+// unless -attribute-loaders adds them to the functions calling Load, so the
+// resolvers carry the Reads they cause.
+
+var (
+	// loaderConstructors are dataloader constructors taking the batch function first.
+	loaderConstructors = map[string]map[string]bool{
+		"github.com/graph-gophers/dataloader":    {"NewBatchedLoader": true},
+		"github.com/graph-gophers/dataloader/v6": {"NewBatchedLoader": true},
+		"github.com/graph-gophers/dataloader/v7": {"NewBatchedLoader": true},
+		"github.com/vikstrous/dataloadgen":       {"NewLoader": true, "NewMappedLoader": true},
+	}
+
+	// loaderMethods are the loader methods that enqueue keys for the batch function.
+	loaderMethods = map[string]bool{
+		"Load":         true,
+		"LoadMany":     true,
+		"LoadAll":      true,
+		"LoadThunk":    true,
+		"LoadAllThunk": true,
+	}
+)
+
+// originOf returns the generic function fn was instantiated from, or fn.
+func originOf(fn *ssa.Function) *ssa.Function {
+	if o := fn.Origin(); o != nil {
+		return o
+	}
+	return fn
+}
+
+// isLoaderConstructor reports whether fn creates a dataloader from a batch function.
+func isLoaderConstructor(fn *ssa.Function) bool {
+	fn = originOf(fn)
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil || fn.Signature.Recv() != nil {
+		return false
+	}
+	return loaderConstructors[fn.Pkg.Pkg.Path()][fn.Name()]
+}
+
+// isLoaderLoad reports whether fn is a Load method of a dataloader.
+func isLoaderLoad(fn *ssa.Function) bool {
+	fn = originOf(fn)
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil || fn.Signature.Recv() == nil {
+		return false
+	}
+	_, ok := loaderConstructors[fn.Pkg.Pkg.Path()]
+	return ok && loaderMethods[fn.Name()]
+}
+
+// gqlgenResolverRoot returns the resolver root passed to a generated gqlgen
+// NewExecutableSchema(Config{Resolvers: root}) call, with the ResolverRoot
+// interface, or nil if call is not one.
+func gqlgenResolverRoot(fn *ssa.Function, call *ssa.CallCommon) (ssa.Value, *types.Interface) {
+	if fn.Name() != "NewExecutableSchema" || len(call.Args) != 1 {
+		return nil, nil
+	}
+	cfg, ok := call.Args[0].(*ssa.UnOp)
+	if !ok {
+		return nil, nil
+	}
+	alloc, ok := cfg.X.(*ssa.Alloc)
+	if !ok {
+		return nil, nil
+	}
+	for _, ref := range *alloc.Referrers() {
+		fa, ok := ref.(*ssa.FieldAddr)
+		if !ok || fieldOf(fa) == nil || fieldOf(fa).Name() != "Resolvers" {
+			continue
+		}
+		api, ok := fieldOf(fa).Type().Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		for _, r := range *fa.Referrers() {
+			if st, ok := r.(*ssa.Store); ok && st.Addr == fa {
+				if mi, ok := st.Val.(*ssa.MakeInterface); ok {
+					return mi.X, api
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// gqlgenResolvers returns the resolver methods reachable from root: every
+// method of the ResolverRoot (Query, Mutation, one per object type) returns
+// the implementation of a resolver interface whose methods are the resolvers.
+func gqlgenResolvers(prog *ssa.Program, root ssa.Value, api *types.Interface) []*ssa.Function {
+	var resolvers []*ssa.Function
+	mset := prog.MethodSets.MethodSet(root.Type())
+	for i := 0; i < api.NumMethods(); i++ {
+		m := api.Method(i)
+		sel := mset.Lookup(m.Pkg(), m.Name())
+		if sel == nil {
+			continue
+		}
+		results := m.Type().(*types.Signature).Results()
+		if results.Len() != 1 {
+			continue
+		}
+		iface, ok := results.At(0).Type().Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		for _, b := range prog.MethodValue(sel).Blocks {
+			ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			if mi, ok := ret.Results[0].(*ssa.MakeInterface); ok {
+				resolvers = append(resolvers, exportedMethods(prog, mi.X.Type(), iface)...)
+			}
+		}
+	}
+	return resolvers
+}
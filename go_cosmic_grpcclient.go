@@ -0,0 +1,81 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// gRPC client stubs. A call of an RPC on a client generated by protoc
+// (GreeterClient.SayHello) sends the request to another service and receives
+// its response: an Exit and an Entry with the service as data group, like an
+// outgoing HTTP call. On streams (Greeter_ChatClient, grpc.ServerStreamingClient)
+// each Send is an Exit and each Recv an Entry; opening one is neither.
+
+// grpcStubs holds the import paths of the packages with generated .pb.go files.
+type grpcStubs map[string]bool
+
+// grpcStubPackages returns the packages of pkgs and their dependencies that
+// contain .pb.go files.
+func grpcStubPackages(pkgs []*packages.Package) grpcStubs {
+	stubs := grpcStubs{}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, f := range pkg.GoFiles {
+			if strings.HasSuffix(f, ".pb.go") {
+				stubs[pkg.PkgPath] = true
+				break
+			}
+		}
+	})
+	return stubs
+}
+
+// grpcStreamSends and grpcStreamReceives are the methods of streams sending
+// and receiving a message.
+var (
+	grpcStreamSends    = map[string]bool{"Send": true, "SendMsg": true}
+	grpcStreamReceives = map[string]bool{"Recv": true, "RecvMsg": true, "CloseAndRecv": true}
+)
+
+// clientCall returns the movement types of a call of the method name with
+// signature sig on a gRPC client, and the data group, or no types if it is
+// no RPC or stream message.
+func (s grpcStubs) clientCall(name string, sig *types.Signature) (typs []string, group string) {
+	if sig.Recv() == nil {
+		return nil, ""
+	}
+	recv := sig.Recv().Type()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || !strings.HasSuffix(named.Obj().Name(), "Client") {
+		return nil, ""
+	}
+	typeName, pkg := named.Obj().Name(), named.Obj().Pkg().Path()
+	service, stream := strings.TrimSuffix(typeName, "Client"), false
+	if i := strings.Index(typeName, "_"); i > 0 {
+		service, stream = typeName[:i], true
+	}
+	switch {
+	case pkg == grpcPkgPath && strings.HasSuffix(typeName, "StreamingClient"):
+		service, stream = "", true
+	case !s[pkg] || service == "":
+		return nil, ""
+	}
+	if stream {
+		switch {
+		case grpcStreamSends[name]:
+			return []string{MovementExit}, service
+		case grpcStreamReceives[name]:
+			return []string{MovementEntry}, service
+		}
+		return nil, ""
+	}
+	// Methods returning a stream open it.
+	if res := sig.Results(); res.Len() == 0 || strings.HasSuffix(namedTypeName(res.At(0).Type()), "Client") {
+		return nil, ""
+	}
+	return []string{MovementExit, MovementEntry}, strings.ToUpper(service[:1]) + service[1:]
+}
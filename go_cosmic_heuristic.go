@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Heuristic-grade measurement. Code that does not type-check, such as a
+// partial source delivery missing its dependencies, has no SSA form. It is
+// then measured from its syntax alone: package-qualified calls are classified
+// by the built-in tables and rules, calls of the code's own functions are
+// followed by name, and the method calls whose receivers cannot be resolved
+// widen the bounds of the size instead of counting. The output is labeled
+// with the grade, so it is never mistaken for a type-checked measurement.
+
+// GradeHeuristic labels a measurement made without type information.
+const GradeHeuristic = "heuristic"
+
+// CFPBounds are the bounds of a heuristic-grade size. Low counts the
+// movements classified as in a type-checked measurement; High adds the calls
+// whose names suggest a movement but whose callee is unknown.
+type CFPBounds struct {
+	Low  int `json:"low"`
+	High int `json:"high"`
+}
+
+// typeCheckFailure returns why the loaded packages cannot be measured from
+// their SSA form, or "" if they all type-check.
+func typeCheckFailure(pkgs []*packages.Package) string {
+	if len(pkgs) == 0 {
+		return "no packages found"
+	}
+	var ill int
+	var first string
+	for _, pkg := range pkgs {
+		if !pkg.IllTyped && len(pkg.Errors) == 0 {
+			continue
+		}
+		ill++
+		if first == "" && len(pkg.Errors) > 0 {
+			first = pkg.Errors[0].Msg
+		}
+	}
+	if ill == 0 {
+		return ""
+	}
+	reason := fmt.Sprintf("%d of %d packages do not type-check", ill, len(pkgs))
+	if first != "" {
+		reason += ": " + first
+	}
+	return reason
+}
+
+// heuristicFunc is a function declared in the code, with the movements of
+// its body and the calls of other functions of the code.
+type heuristicFunc struct {
+	// file is the file declaring the function.
+	file   string
+	counts Counts
+	// possible counts the calls that may be movements.
+	possible int
+	// writers are the parameters declared as an http.ResponseWriter,
+	// requests those declared as a request or request context and contexts
+	// those declared as a context.Context.
+	writers, requests, contexts map[string]bool
+	calls                       []string
+}
+
+// heuristicScan holds the declarations of the code measured from syntax.
+type heuristicScan struct {
+	fset  *token.FileSet
+	conf  settings
+	funcs map[string]*heuristicFunc // by funcKey
+	// methods maps a method name to the keys of the methods declared with it.
+	methods map[string][]string
+	entries map[string]entryPoint
+}
+
+// heuristicMeasure measures the Go files under dir from their syntax with the
+// settings conf, the processes owned by owners. It also returns the import
+// paths of the packages measured.
+func heuristicMeasure(dir, reason string, cfg analysisConfig, conf settings, owners codeOwners) (Output, []string, error) {
+	s := &heuristicScan{
+		fset:    token.NewFileSet(),
+		conf:    conf,
+		funcs:   map[string]*heuristicFunc{},
+		methods: map[string][]string{},
+		entries: map[string]entryPoint{},
+	}
+	files, skipped, err := s.parse(dir, modulePath(dir), cfg.tests)
+	if err != nil {
+		return Output{}, nil, err
+	}
+	var code []string
+	for _, f := range files {
+		s.declare(f)
+		if len(code) == 0 || code[len(code)-1] != f.pkg {
+			code = append(code, f.pkg)
+		}
+	}
+	for _, f := range files {
+		s.scan(f)
+	}
+
+	out := Output{Summary: Summary{Grade: GradeHeuristic, GradeReason: reason, Bounds: &CFPBounds{}}}
+	if skipped > 0 {
+		out.GradeReason += fmt.Sprintf("; %d files could not be parsed", skipped)
+	}
+	var keys []string
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ep := s.entries[key]
+		pr, possible := s.report(key)
+		pr.dedupe(cfg.uncounted(), cfg.dedupe)
+		low := pr.Entries + pr.Exits + pr.Reads + pr.Writes
+		pr.Bounds = &CFPBounds{Low: low, High: low + possible}
+		pr.Owners = owners.of(s.funcs[key].file)
+		for _, v := range ep.variants() {
+			pr := pr
+			pr.Trigger = v.trigger
+			pr.Method = v.method()
+			pr.ID = processID(pr.Source, pr.Method)
+			pr.Name = key
+			if route := strings.TrimSpace(strings.Join(v.methods, ",") + " " + v.route); route != "" {
+				pr.Name = route + " -> " + key
+			}
+			out.Processes = append(out.Processes, pr)
+			out.TotalEntries += pr.Entries
+			out.TotalExits += pr.Exits
+			out.TotalReads += pr.Reads
+			out.TotalWrites += pr.Writes
+			out.Bounds.Low += pr.Bounds.Low
+			out.Bounds.High += pr.Bounds.High
+		}
+	}
+	out.Teams = teamTotals(out.Processes)
+	return out, code, nil
+}
+
+// heuristicFile is a parsed file with the import path of its package and the
+// import paths of its imports by local name.
+type heuristicFile struct {
+	file    *ast.File
+	pkg     string
+	imports map[string]string
+}
+
+// parse parses the Go files under dir, skipping vendor and testdata
+// directories and, unless tests is set, test files. It returns the number of
+// files that could not be parsed, and an error if dir cannot be read.
+func (s *heuristicScan) parse(dir, module string, tests bool) ([]heuristicFile, int, error) {
+	var files []heuristicFile
+	skipped := 0
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil && p == dir {
+			return err
+		}
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || !tests && strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(s.fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			skipped++
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, filepath.Dir(p))
+		pkg := path.Join(module, filepath.ToSlash(rel))
+		if pkg == "." {
+			pkg = f.Name.Name // no go.mod
+		}
+		hf := heuristicFile{file: f, pkg: pkg, imports: map[string]string{}}
+		for _, imp := range f.Imports {
+			ip, _ := strconv.Unquote(imp.Path.Value)
+			local := importName(ip)
+			if imp.Name != nil {
+				local = imp.Name.Name
+			}
+			if local != "_" && local != "." {
+				hf.imports[local] = ip
+			}
+		}
+		files = append(files, hf)
+		return nil
+	})
+	return files, skipped, err
+}
+
+// declare records the functions and methods declared in f, and main.main.
+func (s *heuristicScan) declare(f heuristicFile) {
+	for _, decl := range f.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		key := funcKey(f.pkg, fd)
+		if fd.Recv != nil {
+			s.methods[fd.Name.Name] = append(s.methods[fd.Name.Name], key)
+		} else if f.file.Name.Name == "main" && fd.Name.Name == "main" {
+			s.entries[key] = entryPoint{trigger: "program start (main.main)"}
+		}
+		s.funcs[key] = &heuristicFunc{file: s.fset.Position(fd.Pos()).Filename}
+	}
+}
+
+// scan classifies the calls in the function bodies of f.
+func (s *heuristicScan) scan(f heuristicFile) {
+	for _, decl := range f.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		fn := s.funcs[funcKey(f.pkg, fd)]
+		fn.writers = responseWriterParams(f, fd)
+		fn.requests = requestParams(f, fd)
+		fn.contexts = contextParams(f, fd)
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				s.call(f, fn, call)
+			}
+			return true
+		})
+	}
+}
+
+// call classifies one call in the body of fn.
+func (s *heuristicScan) call(f heuristicFile, fn *heuristicFunc, call *ast.CallExpr) {
+	pos := s.fset.Position(call.Lparen)
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if key := f.pkg + "." + fun.Name; s.funcs[key] != nil {
+			fn.calls = append(fn.calls, key)
+		}
+	case *ast.SelectorExpr:
+		name := fun.Sel.Name
+		if x, ok := fun.X.(*ast.Ident); ok && f.imports[x.Name] != "" {
+			p := f.imports[x.Name]
+			if key := p + "." + name; s.funcs[key] != nil {
+				fn.calls = append(fn.calls, key)
+				return
+			}
+			callee := p + "." + name
+			if i, ok := responseHelpers[p][name]; ok && i < len(call.Args) && fn.writes(call.Args[i]) {
+				fn.counts.record(MovementExit, callee, "", pos)
+				return
+			}
+			if (requestFuncs[p][name] || bodyReaders[p][name]) && fn.readsRequestArg(call.Args) {
+				fn.counts.record(MovementEntry, callee, requestGroup, pos, TagRequest)
+				return
+			}
+			if entryRegistrations[p][name] {
+				s.register(f, call, callee)
+				return
+			}
+			typ := s.conf.rule(p, name, "", f.pkg)
+			switch {
+			case typ == movementNone:
+				return
+			case typ != "":
+			case s.conf.isMetricsPath(p):
+				// instrumentation, not a possible movement
+			case exitFuncs[p][name] || sendFuncs[p][name]:
+				typ = MovementExit
+			case receiveFuncs[p][name]:
+				typ = MovementEntry
+			case readFuncs[p][name]:
+				typ = MovementRead
+			case writeFuncs[p][name]:
+				typ = MovementWrite
+			case dependencyKind(p) != "" && guessMovement(name) != "":
+				fn.possible++
+			}
+			if typ != "" {
+				fn.counts.record(typ, callee, literalGroup(call.Args), pos)
+			}
+			return
+		}
+		// the response of a handler, written or encoded into
+		if responseMethods[name] && fn.writes(fun.X) {
+			fn.counts.record(MovementExit, "net/http.ResponseWriter."+name, "", pos)
+			return
+		}
+		if inner, ok := fun.X.(*ast.CallExpr); ok && name == "Encode" && len(inner.Args) > 0 && fn.writes(inner.Args[0]) {
+			fn.counts.record(MovementExit, name, "", pos)
+			return
+		}
+		// the request of a handler, one of its fields or a decoder of its body
+		if fn.readsRequest(fun.X) && requestMethod(fun.X, name) {
+			fn.counts.record(MovementEntry, name, requestGroup, pos, TagRequest)
+			return
+		}
+		if group, ok := fn.contextValueEntry(f, s.conf, call); ok {
+			fn.counts.record(MovementEntry, "context.Context.Value", group, pos)
+			return
+		}
+		// a method call on a receiver of unknown type
+		if keys := s.methods[name]; len(keys) == 1 {
+			fn.calls = append(fn.calls, keys[0])
+			return
+		}
+		switch {
+		case isReadName(name):
+			fn.counts.record(MovementRead, name, literalGroup(call.Args), pos)
+		case isWriteName(name):
+			fn.counts.record(MovementWrite, name, literalGroup(call.Args), pos)
+		case guessMovement(name) != "":
+			fn.possible++
+		}
+	}
+}
+
+// register records the functions of the code registered as handlers by a
+// call of an entry registration, on the route of its leading constant.
+func (s *heuristicScan) register(f heuristicFile, call *ast.CallExpr, callee string) {
+	var route string
+	for _, arg := range call.Args {
+		if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING && route == "" {
+			route, _ = strconv.Unquote(lit.Value)
+		}
+		var key string
+		switch a := arg.(type) {
+		case *ast.Ident:
+			key = f.pkg + "." + a.Name
+		case *ast.SelectorExpr:
+			if x, ok := a.X.(*ast.Ident); ok && f.imports[x.Name] != "" {
+				key = f.imports[x.Name] + "." + a.Sel.Name
+			}
+		}
+		if s.funcs[key] != nil {
+			s.entries[key] = entryPoint{trigger: "registered via " + callee, route: route}
+		}
+	}
+}
+
+// report sums the movements of the functions reachable from key by name,
+// with the number of possible movements among them.
+func (s *heuristicScan) report(key string) (ProcessReport, int) {
+	pr := ProcessReport{Source: key}
+	possible := 0
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	for len(queue) > 0 {
+		fn := s.funcs[queue[0]]
+		queue = queue[1:]
+		pr.Funcs++
+		pr.add(fn.counts)
+		possible += fn.possible
+		for _, callee := range fn.calls {
+			if !seen[callee] {
+				seen[callee] = true
+				queue = append(queue, callee)
+			}
+		}
+	}
+	return pr, possible
+}
+
+// funcKey returns the key of the function or method fd of package pkg,
+// "pkg.Func" or "pkg.Type.Method".
+func funcKey(pkg string, fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return pkg + "." + fd.Name.Name
+	}
+	t := fd.Recv.List[0].Type
+	for {
+		switch tt := t.(type) {
+		case *ast.StarExpr:
+			t = tt.X
+		case *ast.IndexExpr:
+			t = tt.X
+		case *ast.IndexListExpr:
+			t = tt.X
+		case *ast.Ident:
+			return pkg + "." + tt.Name + "." + fd.Name.Name
+		default:
+			return pkg + "." + fd.Name.Name
+		}
+	}
+}
+
+// literalGroup returns the data group named by the first string literal among
+// args, the table of an SQL statement or the literal itself, or "".
+func literalGroup(args []ast.Expr) string {
+	for _, arg := range args {
+		lit, ok := arg.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			continue
+		}
+		s, _ := strconv.Unquote(lit.Value)
+		if m := sqlTableRe.FindStringSubmatch(s); m != nil {
+			return m[1]
+		}
+		return s
+	}
+	return ""
+}
+
+// importName returns the package name an import path is conventionally
+// imported as: its last element without a major version suffix
+// (github.com/go-redis/redis/v8 is redis, gopkg.in/yaml.v3 is yaml).
+func importName(p string) string {
+	elems := strings.Split(p, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(name, "go-"), "go.")
+}
+
+// modulePath returns the module path declared by the go.mod of dir, or "".
+func modulePath(dir string) string {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
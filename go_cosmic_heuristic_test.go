@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestHeuristicSessionSettings measures code that does not type-check with the
+// settings the Analyzer was created with, even once the -config file is gone:
+// the heuristic grade must neither read the file again nor end the process.
+func TestHeuristicSessionSettings(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "cosmic.json")
+	rules := `{"rules": [{"package": "strings", "function": "ToUpper", "movement": "R"}]}`
+	if err := os.WriteFile(config, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	an, err := NewAnalyzer([]string{"-config", config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(config); err != nil {
+		t.Fatal(err)
+	}
+	out, err := an.Measure("testdata/untyped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Grade != GradeHeuristic {
+		t.Fatalf("grade %q, want %q", out.Grade, GradeHeuristic)
+	}
+	pr := processBySource(t, out, "example.com/untyped.listOrders")
+	if pr.Reads != 2 {
+		t.Errorf("reads = %d, want the os.ReadFile and the strings.ToUpper of the session's rule", pr.Reads)
+	}
+}
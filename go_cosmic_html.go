@@ -0,0 +1,206 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// HTML reports. -format html writes the measurement as a single HTML file,
+// with its style and script inline, to publish as a CI artifact: a table of
+// the processes that sorts by any column when its header is clicked, and for
+// each process a drill-down to its movements and the functions it includes,
+// each linked to its source location. Locations under the measured root link
+// to the local file, or with -source-url to a code browser: the template's
+// {path} is replaced by the path relative to the root and {line} by the line,
+// as in https://github.com/org/repo/blob/main/{path}#L{line}. Locations
+// outside the root, in the standard library or the module cache, are shown
+// without a link.
+
+// htmlSink renders the measurement as an HTML report on Close.
+type htmlSink struct {
+	w         io.Writer
+	root      string
+	sourceURL string
+	h         Header
+	prs       []ProcessReport
+}
+
+// NewHTMLSink returns a Sink writing the measurement as an HTML report to w,
+// linking the source locations under root through the sourceURL template, or
+// to the local files if it is empty.
+func NewHTMLSink(w io.Writer, root, sourceURL string) Sink {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &htmlSink{w: w, root: root, sourceURL: sourceURL}
+}
+
+func (s *htmlSink) WriteHeader(h Header) error {
+	s.h = h
+	return nil
+}
+
+func (s *htmlSink) WriteProcess(pr ProcessReport) error {
+	pr.Movements = nil
+	s.prs = append(s.prs, pr)
+	return nil
+}
+
+func (s *htmlSink) WriteMovement(_ ProcessReport, m Movement) error {
+	last := &s.prs[len(s.prs)-1]
+	last.Movements = append(last.Movements, m)
+	return nil
+}
+
+func (s *htmlSink) Close() error {
+	t, err := htmlReport.Clone()
+	if err != nil {
+		return err
+	}
+	return t.Funcs(template.FuncMap{"link": s.sourceLink}).Execute(s.w, struct {
+		Header
+		CFP       int
+		Processes []ProcessReport
+	}{s.h, s.h.TotalEntries + s.h.TotalExits + s.h.TotalReads + s.h.TotalWrites, s.prs})
+}
+
+// sourceLink returns the URL of source location pos ("file:line:col"), or ""
+// if it is not under the root. The URL is trusted, so that html/template
+// keeps the file:// links.
+func (s *htmlSink) sourceLink(pos string) template.URL {
+	file, line := pos, ""
+	if i := strings.LastIndex(file, ":"); i >= 0 {
+		file = file[:i]
+		if j := strings.LastIndex(file, ":"); j >= 0 {
+			file, line = file[:j], file[j+1:]
+		} else {
+			line = pos[i+1:]
+		}
+	}
+	if !filepath.IsAbs(file) {
+		return ""
+	}
+	rel, err := filepath.Rel(s.root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	if s.sourceURL == "" {
+		return template.URL("file://" + filepath.ToSlash(file))
+	}
+	return template.URL(strings.NewReplacer("{path}", filepath.ToSlash(rel), "{line}", line).Replace(s.sourceURL))
+}
+
+// htmlReport is the template of the HTML report; each sink binds link to its
+// sourceLink on a clone.
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cfp":    func(pr ProcessReport) int { return pr.cfp() },
+	"tags":   func(m Movement) string { return strings.Join(m.Tags, ", ") },
+	"link":   func(string) template.URL { return "" },
+	"plural": plural,
+}).Parse(htmlTemplate))
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Functional size: {{.CFP}} CFP</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { padding: 0.3em 0.7em; border-bottom: 1px solid #ddd; text-align: left; vertical-align: top; }
+td.n, th.n { text-align: right; }
+table.processes > thead th { cursor: pointer; user-select: none; background: #f4f4f4; }
+table.processes > thead th[aria-sort=ascending]::after { content: " \25B2"; }
+table.processes > thead th[aria-sort=descending]::after { content: " \25BC"; }
+tr.process { cursor: pointer; }
+tr.process:hover { background: #f8f8ff; }
+tr.detail > td { background: #fafafa; padding: 0.5em 2em; }
+tr.detail table { margin: 0.5em 0; font-size: 0.9em; }
+code { font-size: 0.95em; }
+.note { color: #666; font-size: 0.9em; }
+.heuristic { background: #fff4d6; padding: 0.5em 1em; border-left: 4px solid #e0a800; }
+</style>
+</head>
+<body>
+<h1>Functional size</h1>
+<p><strong>{{.CFP}} CFP</strong> in {{plural (len .Processes) "functional process" "functional processes"}}: {{plural .TotalEntries "Entry" "Entries"}}, {{plural .TotalExits "Exit" "Exits"}}, {{plural .TotalReads "Read" "Reads"}} and {{plural .TotalWrites "Write" "Writes"}}.</p>
+{{- if eq .Grade "heuristic"}}
+<p class="heuristic"><strong>Heuristic grade</strong>: measured from the syntax alone, because {{.GradeReason}}.{{with .Bounds}} The size is between {{.Low}} and {{.High}} CFP.{{end}}</p>
+{{- end}}
+<p class="note">Click a column header to sort, and a process to show its movements and functions.</p>
+<table class="processes">
+<thead><tr><th>Process</th><th>Trigger</th><th class="n">E</th><th class="n">X</th><th class="n">R</th><th class="n">W</th><th class="n">CFP</th><th class="n">Functions</th></tr></thead>
+{{- range $i, $pr := .Processes}}
+<tbody>
+<tr class="process" data-detail="p{{$i}}"><td><code>{{$pr.Name}}</code></td><td>{{$pr.Trigger}}</td><td class="n">{{$pr.Entries}}</td><td class="n">{{$pr.Exits}}</td><td class="n">{{$pr.Reads}}</td><td class="n">{{$pr.Writes}}</td><td class="n">{{cfp $pr}}</td><td class="n">{{$pr.Funcs}}</td></tr>
+<tr class="detail" id="p{{$i}}" hidden><td colspan="8">
+{{- with $pr.Source}}<p>Source: <code>{{.}}</code>{{with $pr.Owners}} &middot; owned by {{range $j, $o := .}}{{if $j}}, {{end}}{{$o}}{{end}}{{end}}</p>{{end}}
+{{- if $pr.Movements}}
+<table>
+<thead><tr><th>Type</th><th>Data group</th><th>Callee</th><th>Position</th></tr></thead>
+<tbody>
+{{- range $pr.Movements}}
+<tr><td>{{.Type}}{{with tags .}} ({{.}}){{end}}</td><td>{{.DataGroup}}</td><td><code>{{.Callee}}</code></td><td>{{with link .Pos}}<a href="{{.}}">{{end}}{{.Pos}}{{if link .Pos}}</a>{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p class="note">No data movements.</p>
+{{- end}}
+{{- if $pr.Functions}}
+<table>
+<thead><tr><th>Function</th><th>Position</th></tr></thead>
+<tbody>
+{{- range $pr.Functions}}
+<tr><td><code>{{.Name}}</code></td><td>{{with link .Pos}}<a href="{{.}}">{{end}}{{.Pos}}{{if link .Pos}}</a>{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</td></tr>
+</tbody>
+{{- end}}
+<tfoot><tr><th>Total</th><th></th><th class="n">{{.TotalEntries}}</th><th class="n">{{.TotalExits}}</th><th class="n">{{.TotalReads}}</th><th class="n">{{.TotalWrites}}</th><th class="n">{{.CFP}}</th><th></th></tr></tfoot>
+</table>
+{{- if .Teams}}
+<h2>By team</h2>
+<table>
+<thead><tr><th>Team</th><th class="n">Processes</th><th class="n">E</th><th class="n">X</th><th class="n">R</th><th class="n">W</th><th class="n">CFP</th></tr></thead>
+<tbody>
+{{- range .Teams}}
+<tr><td>{{.Team}}</td><td class="n">{{.Processes}}</td><td class="n">{{.Entries}}</td><td class="n">{{.Exits}}</td><td class="n">{{.Reads}}</td><td class="n">{{.Writes}}</td><td class="n">{{.CFP}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+<hr>
+<p class="note">Measured with COSMIC (ISO/IEC 19761) from the static call graph of the code: every entry point (a handler, a consumer, main) starts a functional process, which counts 1 CFP per data movement (Entry, Exit, Read, Write) of the calls reachable from it{{if .RulesVersion}}; rules version {{.RulesVersion}}{{end}}{{with .Tool}}, {{.Module}} {{.Version}}{{end}}.</p>
+<script>
+document.querySelectorAll("tr.process").forEach(function (tr) {
+  tr.addEventListener("click", function () {
+    var d = document.getElementById(tr.dataset.detail);
+    d.hidden = !d.hidden;
+  });
+});
+document.querySelectorAll("table.processes > thead th").forEach(function (th, col) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table");
+    var asc = th.getAttribute("aria-sort") !== "ascending";
+    table.querySelectorAll("thead th").forEach(function (h) { h.removeAttribute("aria-sort"); });
+    th.setAttribute("aria-sort", asc ? "ascending" : "descending");
+    var numeric = th.classList.contains("n");
+    var bodies = Array.prototype.slice.call(table.tBodies);
+    bodies.sort(function (a, b) {
+      var x = a.rows[0].cells[col].textContent, y = b.rows[0].cells[col].textContent;
+      var c = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+      return asc ? c : -c;
+    });
+    bodies.forEach(function (b) { table.insertBefore(b, table.tFoot); });
+  });
+});
+</script>
+</body>
+</html>
+`
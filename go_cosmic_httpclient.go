@@ -0,0 +1,133 @@
+package main
+
+import (
+	"go/types"
+	"net/url"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Outgoing HTTP calls. A request sent to another service is an Exit to that
+// functional user, and its response an Entry from it; both are recorded at
+// the call of http.Get or (*http.Client).Do, with the host of the URL as data
+// group when it is constant (or the constant prefix of a fmt.Sprintf).
+
+var (
+	// httpClientFuncs are the functions and client methods sending a request,
+	// by package path.
+	httpClientFuncs = map[string]map[string]bool{
+		"net/http": {"Get": true, "Head": true, "Post": true, "PostForm": true, "Do": true},
+		"github.com/go-resty/resty/v2": {
+			"Get": true, "Head": true, "Post": true, "Put": true, "Patch": true, "Delete": true,
+			"Options": true, "Execute": true, "Send": true,
+		},
+		"github.com/hashicorp/go-retryablehttp": {"Get": true, "Head": true, "Post": true, "PostForm": true, "Do": true},
+	}
+	// httpClientTypes are the receiver types of the client methods sending a
+	// request; http.Header.Get is no request.
+	httpClientTypes = map[string]bool{"Client": true, "Request": true}
+	// httpRequestConstructors build the request later sent by Do.
+	httpRequestConstructors = map[string]bool{"NewRequest": true, "NewRequestWithContext": true}
+)
+
+// isHTTPClientCall reports whether fn sends an HTTP request.
+func isHTTPClientCall(fn *ssa.Function) bool {
+	if !matchesTable(fn, httpClientFuncs) {
+		return false
+	}
+	recv := fn.Signature.Recv()
+	return recv == nil || httpClientTypes[namedTypeName(recv.Type())]
+}
+
+// isHTTPDoer reports whether interface method m sends a request the way
+// (*http.Client).Do does, as the Doer interfaces injected in its place do:
+// Do(*http.Request) (*http.Response, error).
+func isHTTPDoer(m *types.Func) bool {
+	sig := m.Type().(*types.Signature)
+	return m.Name() == "Do" && sig.Params().Len() == 1 && sig.Results().Len() == 2 &&
+		isNamedType(sig.Params().At(0).Type(), "net/http", "Request") &&
+		isNamedType(sig.Results().At(0).Type(), "net/http", "Response")
+}
+
+// httpHost returns the host a request is sent to: that of the URL argument
+// of args, or of the URL given to the constructor of the request argument.
+func httpHost(args []ssa.Value) string {
+	for _, arg := range args {
+		if host := urlHost(arg); host != "" {
+			return host
+		}
+		// client.Do(req), req from http.NewRequest(method, url, body)
+		if ext, ok := arg.(*ssa.Extract); ok {
+			arg = ext.Tuple
+		}
+		if call, ok := arg.(*ssa.Call); ok {
+			if sc := call.Call.StaticCallee(); sc != nil && httpRequestConstructors[sc.Name()] {
+				for _, a := range call.Call.Args {
+					if host := urlHost(a); host != "" {
+						return host
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// urlHost returns the host of the constant absolute URL v, or of the
+// constant prefix of a fmt.Sprintf building one, or "".
+func urlHost(v ssa.Value) string {
+	s, ok := constString(v)
+	if !ok {
+		s = sprintfFormat(v)
+	}
+	if !strings.Contains(s, "://") {
+		return ""
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// readsResponseBody reports whether a call reads the body of an HTTP
+// response, as io.ReadAll(resp.Body) or json.NewDecoder(resp.Body).Decode(&v)
+// do; that is the Entry of the response, not a Read.
+func readsResponseBody(args []ssa.Value) bool {
+	for _, arg := range args {
+		if responseBody(arg, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseBody reports whether v is the Body of an *http.Response or a reader
+// or decoder built on one.
+func responseBody(v ssa.Value, depth int) bool {
+	if depth > 4 {
+		return false
+	}
+	switch vv := v.(type) {
+	case *ssa.MakeInterface:
+		return responseBody(vv.X, depth+1)
+	case *ssa.UnOp:
+		fa, ok := vv.X.(*ssa.FieldAddr)
+		if !ok {
+			return false
+		}
+		st := fa.X.Type().Underlying().(*types.Pointer).Elem()
+		return isNamedType(st, "net/http", "Response") && st.Underlying().(*types.Struct).Field(fa.Field).Name() == "Body"
+	case *ssa.Call:
+		// bufio.NewReader(resp.Body), json.NewDecoder(resp.Body)
+		if sc := vv.Call.StaticCallee(); sc != nil && strings.HasPrefix(sc.Name(), "New") {
+			for _, arg := range vv.Call.Args {
+				if responseBody(arg, depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Measurement jobs. Measuring a large repository takes longer than an HTTP
+// client waits, so serve also takes jobs: POST /jobs?repo=<dir> queues the
+// measurement and answers at once with the job, which a pool of -job-workers
+// runs. GET /jobs/{id} returns its status and GET /jobs/{id}/result the JSON
+// output once done; with &webhook=<url> the job is POSTed to url when it
+// completes. The url must be under one of the -webhook-allow prefixes of the
+// operator, so that a client cannot make the server post to the hosts of its
+// network; without any, webhooks are refused. The finished jobs are kept for
+// -job-ttl, and at most the -max-jobs last ones, with their output.
+
+// Job statuses.
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// job is a measurement job, as reported by the job endpoints.
+type job struct {
+	ID      string `json:"id"`
+	Repo    string `json:"repo"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Webhook string `json:"webhook,omitempty"`
+	// Queued, Started and Finished are the times of the job's transitions.
+	Queued   time.Time  `json:"queued"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	dir string
+	out Output
+}
+
+// jobQueue holds the jobs and feeds the queued ones to the workers.
+type jobQueue struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	pending chan *job
+	// ttl and keep bound the finished jobs kept, if not zero.
+	ttl  time.Duration
+	keep int
+	// webhooks are the URL prefixes the webhooks must be under.
+	webhooks allowList
+}
+
+// newJobQueue returns a queue holding at most size waiting jobs, and of the
+// finished ones those of the last ttl, at most keep, accepting the webhooks
+// under the prefixes of webhooks.
+func newJobQueue(size, keep int, ttl time.Duration, webhooks allowList) *jobQueue {
+	return &jobQueue{jobs: map[string]*job{}, pending: make(chan *job, size), ttl: ttl, keep: keep, webhooks: webhooks}
+}
+
+// handle registers the job endpoints on mux, for the repositories under base.
+func (q *jobQueue) handle(mux *http.ServeMux, base string) {
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		repo := r.URL.Query().Get("repo")
+		dir, status, err := repoDir(base, repo)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		webhook := r.URL.Query().Get("webhook")
+		if webhook != "" && !q.webhooks.allows(webhook) {
+			http.Error(w, "webhook not under a -webhook-allow prefix of the server", http.StatusBadRequest)
+			return
+		}
+		j := &job{ID: jobID(), Repo: repo, Status: jobQueued, Webhook: webhook, Queued: time.Now(), dir: dir}
+		q.mu.Lock()
+		q.evict(j.Queued)
+		q.jobs[j.ID] = j
+		q.mu.Unlock()
+		select {
+		case q.pending <- j:
+		default:
+			q.mu.Lock()
+			delete(q.jobs, j.ID)
+			q.mu.Unlock()
+			http.Error(w, "too many jobs queued", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+j.ID)
+		q.writeJob(w, j, http.StatusAccepted)
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		j := q.job(r.PathValue("id"))
+		if j == nil {
+			http.NotFound(w, r)
+			return
+		}
+		q.writeJob(w, j, http.StatusOK)
+	})
+	mux.HandleFunc("GET /jobs/{id}/result", func(w http.ResponseWriter, r *http.Request) {
+		j := q.job(r.PathValue("id"))
+		if j == nil {
+			http.NotFound(w, r)
+			return
+		}
+		q.mu.Lock()
+		status, msg, out := j.Status, j.Error, j.out
+		q.mu.Unlock()
+		switch status {
+		case jobDone:
+			writeOutput(w, r, out)
+		case jobFailed:
+			http.Error(w, msg, http.StatusInternalServerError)
+		default:
+			http.Error(w, "job "+status, http.StatusConflict)
+		}
+	})
+}
+
+// job returns the job id, or nil.
+func (q *jobQueue) job(id string) *job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.evict(time.Now())
+	return q.jobs[id]
+}
+
+// evict removes the jobs finished for longer than the ttl, then the oldest
+// finished jobs beyond keep. q.mu must be held.
+func (q *jobQueue) evict(now time.Time) {
+	var finished []*job
+	for id, j := range q.jobs {
+		switch {
+		case j.Finished == nil:
+		case q.ttl > 0 && now.Sub(*j.Finished) > q.ttl:
+			delete(q.jobs, id)
+		default:
+			finished = append(finished, j)
+		}
+	}
+	if q.keep <= 0 || len(finished) <= q.keep {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *job) int { return a.Finished.Compare(*b.Finished) })
+	for _, j := range finished[:len(finished)-q.keep] {
+		delete(q.jobs, j.ID)
+	}
+}
+
+// writeJob writes the status of j as the JSON response.
+func (q *jobQueue) writeJob(w http.ResponseWriter, j *job, status int) {
+	q.mu.Lock()
+	data, err := json.MarshalIndent(j, "", "  ")
+	q.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// work runs the queued jobs with an, one at a time.
+func (q *jobQueue) work(an *Analyzer, cache *warmCache) {
+	for j := range q.pending {
+		q.mu.Lock()
+		started := time.Now()
+		j.Status, j.Started = jobRunning, &started
+		q.mu.Unlock()
+
+		out, _, err := cache.measure(an, j.dir)
+
+		q.mu.Lock()
+		finished := time.Now()
+		j.Finished = &finished
+		if err != nil {
+			j.Status, j.Error = jobFailed, err.Error()
+		} else {
+			j.Status, j.out = jobDone, out
+		}
+		data, _ := json.Marshal(j)
+		q.evict(finished)
+		q.mu.Unlock()
+		log.Printf("serve: job %s for %s %s in %v", j.ID, j.Repo, j.Status, finished.Sub(started).Round(time.Millisecond))
+		if j.Webhook != "" {
+			notify(j.Webhook, data)
+		}
+	}
+}
+
+// webhookClient posts the completed jobs to their webhooks. It does not
+// follow redirects, which could lead outside the -webhook-allow prefixes.
+var webhookClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// notify POSTs the completed job data to the webhook url.
+func notify(url string, data []byte) {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		log.Printf("serve: webhook %s: %v", url, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("serve: webhook %s: %s", url, resp.Status)
+	}
+}
+
+// allowList collects the URL prefixes of -webhook-allow.
+type allowList []*url.URL
+
+// String implements flag.Value.
+func (l *allowList) String() string {
+	var s []string
+	for _, u := range *l {
+		s = append(s, u.String())
+	}
+	return strings.Join(s, ",")
+}
+
+// Set implements flag.Value for an http or https URL prefix.
+func (l *allowList) Set(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("not an http or https URL")
+	}
+	*l = append(*l, u)
+	return nil
+}
+
+// allows reports whether the URL raw is under one of the prefixes: of the
+// same scheme and host, and with the path of the prefix as a path prefix.
+// A prefix such as https://hooks.example.com then does not allow the host
+// https://hooks.example.com.evil.net.
+func (l allowList) allows(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil {
+		return false
+	}
+	for _, p := range l {
+		if u.Scheme != p.Scheme || !strings.EqualFold(u.Host, p.Host) {
+			continue
+		}
+		path, dir := u.EscapedPath(), strings.TrimSuffix(p.EscapedPath(), "/")
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// jobID returns a new random job id.
+func jobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
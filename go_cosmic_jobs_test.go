@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWebhookAllow(t *testing.T) {
+	var l allowList
+	for _, p := range []string{"https://hooks.example.com/cosmic/", "http://ci.internal:8080"} {
+		if err := l.Set(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for raw, want := range map[string]bool{
+		"https://hooks.example.com/cosmic/done":      true,
+		"https://HOOKS.example.com/cosmic":           true,
+		"http://ci.internal:8080/any/path":           true,
+		"https://hooks.example.com/cosmicx":          false,
+		"https://hooks.example.com/other":            false,
+		"http://hooks.example.com/cosmic/done":       false,
+		"https://hooks.example.com.evil.net/cosmic/": false,
+		"https://x@hooks.example.com/cosmic/":        false,
+		"http://ci.internal/any":                     false,
+		"http://169.254.169.254/latest/meta-data":    false,
+	} {
+		if got := l.allows(raw); got != want {
+			t.Errorf("allows(%s) = %v, want %v", raw, got, want)
+		}
+	}
+	if err := l.Set("file:///etc/passwd"); err == nil {
+		t.Errorf("Set accepted a file URL")
+	}
+}
+
+// TestJobsWebhookRefused checks that without -webhook-allow a job with a
+// webhook is refused before it is queued.
+func TestJobsWebhookRefused(t *testing.T) {
+	q := newJobQueue(1, 0, 0, nil)
+	mux := http.NewServeMux()
+	q.handle(mux, "testdata")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/jobs?repo=changed&webhook="+url.QueryEscape("http://localhost:6379/"), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if len(q.jobs) != 0 || len(q.pending) != 0 {
+		t.Errorf("the refused job was queued")
+	}
+}
+
+func TestJobsEvict(t *testing.T) {
+	now := time.Now()
+	q := newJobQueue(1, 2, time.Hour, nil)
+	for id, age := range map[string]time.Duration{"expired": 2 * time.Hour, "old": 30 * time.Minute, "recent": 20 * time.Minute, "last": time.Minute} {
+		finished := now.Add(-age)
+		q.jobs[id] = &job{ID: id, Status: jobDone, Finished: &finished}
+	}
+	q.jobs["running"] = &job{ID: "running", Status: jobRunning}
+	q.evict(now)
+	for _, id := range []string{"expired", "old"} {
+		if q.jobs[id] != nil {
+			t.Errorf("job %s was kept", id)
+		}
+	}
+	for _, id := range []string{"recent", "last", "running"} {
+		if q.jobs[id] == nil {
+			t.Errorf("job %s was evicted", id)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Embedded key-value store support: bbolt (and boltdb), Badger and goleveldb.
+// Their databases are files of the process, persistent storage read and
+// written key by key. The data group is the bbolt bucket, as in
+// tx.Bucket([]byte("users")).Get(id), or else the key prefix up to the first
+// ':', as for Redis ("session:42" is a session).
+
+var (
+	bboltPkgPaths  = []string{"go.etcd.io/bbolt", "github.com/boltdb/bolt"}
+	badgerPkgPaths = []string{
+		"github.com/dgraph-io/badger",
+		"github.com/dgraph-io/badger/v2",
+		"github.com/dgraph-io/badger/v3",
+		"github.com/dgraph-io/badger/v4",
+	}
+	levelDBPkgPaths = []string{"github.com/syndtr/goleveldb/leveldb"}
+
+	// bboltReads and bboltWrites are the methods of Bucket moving keys.
+	bboltReads  = map[string]bool{"Get": true, "Cursor": true, "ForEach": true}
+	bboltWrites = map[string]bool{"Put": true, "Delete": true}
+	// badgerReads and badgerWrites are the methods of Txn moving keys.
+	badgerReads  = map[string]bool{"Get": true, "NewIterator": true, "NewKeyIterator": true}
+	badgerWrites = map[string]bool{"Set": true, "SetEntry": true, "Delete": true}
+	// levelDBReads and levelDBWrites are the methods of DB moving keys;
+	// DB.Write of a batch is a Write by name.
+	levelDBReads  = map[string]bool{"Get": true, "Has": true, "NewIterator": true}
+	levelDBWrites = map[string]bool{"Put": true, "Delete": true}
+
+	// bboltBuckets are the methods of Tx and Bucket opening a bucket by name.
+	bboltBuckets = map[string]bool{"Bucket": true, "CreateBucket": true, "CreateBucketIfNotExists": true}
+)
+
+// transactionFuncs are the functions running a transaction callback on
+// behalf of the caller. Map of package path -> function name -> index of the
+// callback argument, counting the receiver.
+var transactionFuncs = map[string]map[string]int{
+	"go.etcd.io/bbolt":               {"View": 1, "Update": 1, "Batch": 1},
+	"github.com/boltdb/bolt":         {"View": 1, "Update": 1, "Batch": 1},
+	"github.com/dgraph-io/badger":    {"View": 1, "Update": 1},
+	"github.com/dgraph-io/badger/v2": {"View": 1, "Update": 1},
+	"github.com/dgraph-io/badger/v3": {"View": 1, "Update": 1},
+	"github.com/dgraph-io/badger/v4": {"View": 1, "Update": 1},
+}
+
+// isEmbeddedKVCall reports whether call calls a function or method of an
+// embedded key-value store.
+func isEmbeddedKVCall(call *ssa.CallCommon) bool {
+	sc := call.StaticCallee()
+	if sc == nil || sc.Pkg == nil || sc.Pkg.Pkg == nil {
+		return false
+	}
+	p := sc.Pkg.Pkg.Path()
+	return inPaths(p, bboltPkgPaths) || inPaths(p, badgerPkgPaths) || inPaths(p, levelDBPkgPaths)
+}
+
+// embeddedKVGroup returns the data group of a key-value store call: the
+// bucket of a bbolt Bucket method, or the prefix of the constant key.
+func embeddedKVGroup(call *ssa.CallCommon) string {
+	if len(call.Args) == 0 {
+		return ""
+	}
+	if inPaths(call.StaticCallee().Pkg.Pkg.Path(), bboltPkgPaths) {
+		return bboltBucket(call.Args[0], 0)
+	}
+	for _, arg := range call.Args[1:] {
+		if key, ok := bytesString(arg); ok {
+			group, _, _ := strings.Cut(key, ":")
+			return group
+		}
+	}
+	return ""
+}
+
+// bboltBucket returns the name of the bbolt bucket v was opened as, following
+// nested buckets, or "".
+func bboltBucket(v ssa.Value, depth int) string {
+	if ext, ok := v.(*ssa.Extract); ok {
+		v = ext.Tuple // CreateBucketIfNotExists returns an error too
+	}
+	call, ok := v.(*ssa.Call)
+	if !ok || depth > 8 {
+		return ""
+	}
+	sc := call.Call.StaticCallee()
+	if sc == nil || !bboltBuckets[sc.Name()] || len(call.Call.Args) < 2 {
+		return ""
+	}
+	name, _ := bytesString(call.Call.Args[1])
+	if parent := bboltBucket(call.Call.Args[0], depth+1); parent != "" && name != "" {
+		return parent + "/" + name
+	}
+	return name
+}
+
+// bytesString returns the constant string of v or of []byte(v).
+func bytesString(v ssa.Value) (string, bool) {
+	if conv, ok := v.(*ssa.Convert); ok {
+		v = conv.X
+	}
+	return constString(v)
+}
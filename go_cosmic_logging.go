@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Logging. Print and Printf are classified as writes by name, so log.Printf
+// and the Printf of logrus or zerolog loggers count, and heavily logged code
+// is inflated. Movements of logger packages are tagged TagLogging; with
+// -no-log-exits they stay in the -detail output but do not count. The
+// logger_packages of the -config file add in-house logging packages.
+
+// loggerPackages are the logging packages, by path prefix.
+var loggerPackages = []string{
+	"log",
+	"log/slog",
+	"go.uber.org/zap",
+	"github.com/rs/zerolog",
+	"github.com/sirupsen/logrus",
+}
+
+// isLogger reports whether pkg is a logging package.
+func (s settings) isLogger(pkg *ssa.Package) bool {
+	if pkg == nil || pkg.Pkg == nil {
+		return false
+	}
+	p := pkg.Pkg.Path()
+	for _, prefix := range append(loggerPackages[:len(loggerPackages):len(loggerPackages)], s.LoggerPackages...) {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
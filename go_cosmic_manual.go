@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Manual measurements. A certifier's measurement is a spreadsheet of
+// functional processes and their sizes, named in the words of the
+// requirements ("Create order") rather than of the code
+// ("POST /orders -> example.com/shop/api.createOrder"). Given a CSV export of
+// it, "compare" aligns its rows with the processes of a JSON output by the
+// similarity of their names and reports the agreements and deviations.
+
+// manualProcess is a row of a manual measurement.
+type manualProcess struct {
+	name string
+	cfp  int
+	// counts are the counts by movement type, when the spreadsheet has them.
+	counts map[string]int
+}
+
+// manualColumns are the accepted headers of the columns of a manual
+// measurement, lowercase.
+var manualColumns = map[string][]string{
+	"name":        {"process", "functional process", "process name", "name"},
+	"cfp":         {"cfp", "size", "total"},
+	MovementEntry: {"e", "entry", "entries"},
+	MovementExit:  {"x", "exit", "exits"},
+	MovementRead:  {"r", "read", "reads"},
+	MovementWrite: {"w", "write", "writes"},
+}
+
+// readManual reads the processes of a manual measurement from a CSV file
+// with a header row, separated by commas or, as spreadsheets export them in
+// some locales, semicolons. A row named "total" is skipped.
+func readManual(path string) ([]manualProcess, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		r.Comma = ';'
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no header row", path)
+	}
+	cols := map[string]int{}
+	for i, h := range rows[0] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		for col, names := range manualColumns {
+			if _, ok := cols[col]; !ok && inPaths(h, names) {
+				cols[col] = i
+			}
+		}
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, fmt.Errorf("%s: no process column (one of %s)", path, strings.Join(manualColumns["name"], ", "))
+	}
+	types := []string{MovementEntry, MovementExit, MovementRead, MovementWrite}
+	hasTypes := false
+	for _, t := range types {
+		_, ok := cols[t]
+		hasTypes = hasTypes || ok
+	}
+	if _, ok := cols["cfp"]; !ok && !hasTypes {
+		return nil, fmt.Errorf("%s: no size column (one of %s) nor movement columns", path, strings.Join(manualColumns["cfp"], ", "))
+	}
+
+	var res []manualProcess
+	for n, row := range rows[1:] {
+		field := func(col string) (string, bool) {
+			i, ok := cols[col]
+			if !ok || i >= len(row) {
+				return "", false
+			}
+			return strings.TrimSpace(row[i]), true
+		}
+		name, _ := field("name")
+		if name == "" || strings.EqualFold(name, "total") {
+			continue
+		}
+		number := func(col string) (int, bool, error) {
+			s, ok := field(col)
+			if !ok || s == "" {
+				return 0, false, nil
+			}
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return 0, false, fmt.Errorf("%s:%d: %s of %s is not a number: %q", path, n+2, col, name, s)
+			}
+			return v, true, nil
+		}
+		p := manualProcess{name: name}
+		if hasTypes {
+			p.counts = map[string]int{}
+			for _, t := range types {
+				v, _, err := number(t)
+				if err != nil {
+					return nil, err
+				}
+				p.counts[t] = v
+				p.cfp += v
+			}
+		}
+		if v, ok, err := number("cfp"); err != nil {
+			return nil, err
+		} else if ok {
+			p.cfp = v
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// verbSynonyms map the words of operations to one verb, so that "Add
+// customer" matches "POST /customers".
+var verbSynonyms = map[string]string{
+	"add": "create", "new": "create", "post": "create", "insert": "create", "register": "create",
+	"edit": "update", "modify": "update", "change": "update", "put": "update", "patch": "update", "set": "update",
+	"remove": "delete", "del": "delete", "cancel": "delete",
+	"get": "read", "show": "read", "view": "read", "fetch": "read", "list": "read", "find": "read",
+	"display": "read", "retrieve": "read", "query": "read", "search": "read", "load": "read",
+}
+
+// nameWords returns the words of a process name as compared: lowercase,
+// split at mixed caps and punctuation, singular, with the verbs of
+// operations normalized. Of a Go function only the name counts, without its
+// package and receiver.
+func nameWords(name string) map[string]bool {
+	route, fn, ok := strings.Cut(name, " -> ")
+	if !ok {
+		route, fn = "", name
+	}
+	if i := strings.LastIndex(fn, "/"); i >= 0 && strings.Contains(fn[i:], ".") {
+		fn = fn[strings.LastIndex(fn, ".")+1:]
+	}
+	name = route + " " + fn
+	words := map[string]bool{}
+	var w []rune
+	flush := func() {
+		if len(w) == 0 {
+			return
+		}
+		s := string(w)
+		w = w[:0]
+		if len(s) > 3 && strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") {
+			s = strings.TrimSuffix(s, "s")
+		}
+		if v, ok := verbSynonyms[s]; ok {
+			s = v
+		}
+		words[s] = true
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])):
+			flush()
+		}
+		w = append(w, unicode.ToLower(r))
+	}
+	flush()
+	// main names the program, not what it does
+	delete(words, "main")
+	return words
+}
+
+// similarity returns the Dice coefficient of the words of two names, from 0
+// (no word in common) to 1 (the same words).
+func similarity(a, b map[string]bool) float64 {
+	if len(a)+len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// compareManual prints for every process of the manual measurement in
+// manualPath the process of the JSON output in outputPath it is aligned
+// with, whether their sizes agree within tol, and the rows and processes
+// left unaligned, then the totals. Names are aligned, best first, when their
+// similarity is at least minSimilarity. It reports whether all agree.
+func compareManual(manualPath, outputPath string, tol tolerance, minSimilarity float64) bool {
+	manual, err := readManual(manualPath)
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+	byKey, err := readCache(outputPath)
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+	var tool []ProcessReport
+	for _, pr := range byKey {
+		tool = append(tool, pr)
+	}
+	sort.Slice(tool, func(i, j int) bool { return tool[i].Name < tool[j].Name })
+
+	type pair struct {
+		m, t  int
+		score float64
+	}
+	var pairs []pair
+	for i, mp := range manual {
+		mw := nameWords(mp.name)
+		for j, pr := range tool {
+			if s := similarity(mw, nameWords(pr.Name)); s >= minSimilarity {
+				pairs = append(pairs, pair{i, j, s})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].score > pairs[j].score })
+	alignedTo := map[int]pair{}
+	toolAligned := map[int]bool{}
+	for _, p := range pairs {
+		if _, ok := alignedTo[p.m]; ok || toolAligned[p.t] {
+			continue
+		}
+		alignedTo[p.m], toolAligned[p.t] = p, true
+	}
+
+	agreeing, totalManual, totalTool := 0, 0, 0
+	for i, mp := range manual {
+		totalManual += mp.cfp
+		p, ok := alignedTo[i]
+		if !ok {
+			fmt.Printf("only in %s\t%s\t%d\n", manualPath, mp.name, mp.cfp)
+			continue
+		}
+		pr := tool[p.t]
+		verdict := "differ"
+		if tol.accepts(mp.cfp, pr.cfp()) {
+			verdict = "agree"
+			agreeing++
+		}
+		fmt.Printf("%s\t%s\t%s\t%d\t%d\t%+d\t%.2f%s\n", verdict, mp.name, pr.Name, mp.cfp, pr.cfp(), pr.cfp()-mp.cfp, p.score, typeDeviations(mp, pr))
+	}
+	for j, pr := range tool {
+		totalTool += pr.cfp()
+		if !toolAligned[j] {
+			fmt.Printf("only in %s\t%s\t%d\n", outputPath, pr.Name, pr.cfp())
+		}
+	}
+	overall := "differ"
+	if tol.accepts(totalManual, totalTool) {
+		overall = "agree"
+	}
+	fmt.Printf("%s\ttotal\t%d\t%d\t%+d\n", overall, totalManual, totalTool, totalTool-totalManual)
+	within := tol.String()
+	if !tol.relative {
+		within += " CFP"
+	}
+	log.Printf("%d of %d manually measured processes aligned, %d agree within %s; %d measured processes unaligned; the totals %s",
+		len(alignedTo), len(manual), agreeing, within, len(tool)-len(toolAligned), overall)
+	return agreeing == len(manual) && len(toolAligned) == len(tool) && overall == "agree"
+}
+
+// typeDeviations returns the differences by movement type of the process pr
+// from the manual process mp, as "\tE+1 R-1", or "" if the manual
+// measurement has no types or they agree.
+func typeDeviations(mp manualProcess, pr ProcessReport) string {
+	if mp.counts == nil {
+		return ""
+	}
+	var devs []string
+	for _, t := range []struct {
+		typ string
+		n   int
+	}{{MovementEntry, pr.Entries}, {MovementExit, pr.Exits}, {MovementRead, pr.Reads}, {MovementWrite, pr.Writes}} {
+		if d := t.n - mp.counts[t.typ]; d != 0 {
+			devs = append(devs, fmt.Sprintf("%s%+d", t.typ, d))
+		}
+	}
+	if len(devs) == 0 {
+		return ""
+	}
+	return "\t" + strings.Join(devs, " ")
+}
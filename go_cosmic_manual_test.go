@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTemp writes data to the file name of a temporary directory.
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadManual(t *testing.T) {
+	for _, c := range []struct {
+		desc, csv string
+		want      []manualProcess
+	}{
+		{"sizes", "Functional process,CFP\nCreate order,5\nShow order,3\nTotal,8\n",
+			[]manualProcess{{name: "Create order", cfp: 5}, {name: "Show order", cfp: 3}}},
+		{"semicolons and a byte order mark", "\ufeffProcess;Size\nCreate order;5\n",
+			[]manualProcess{{name: "Create order", cfp: 5}}},
+		{"movement types", "Name,E,X,R,W\nCreate order,1,1,0,2\n",
+			[]manualProcess{{name: "Create order", cfp: 4, counts: map[string]int{"E": 1, "X": 1, "R": 0, "W": 2}}}},
+		{"a size overriding the types", "Name,E,X,R,W,Total\nCreate order,1,1,,2,5\n",
+			[]manualProcess{{name: "Create order", cfp: 5, counts: map[string]int{"E": 1, "X": 1, "R": 0, "W": 2}}}},
+	} {
+		got, err := readManual(writeTemp(t, "manual.csv", []byte(c.csv)))
+		if err != nil {
+			t.Errorf("%s: %v", c.desc, err)
+		} else if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: read %+v, want %+v", c.desc, got, c.want)
+		}
+	}
+	for desc, csv := range map[string]string{
+		"no process column": "Requirement,CFP\nCreate order,5\n",
+		"no size column":    "Process,Owner\nCreate order,shop\n",
+		"not a number":      "Process,CFP\nCreate order,five\n",
+	} {
+		if _, err := readManual(writeTemp(t, "manual.csv", []byte(csv))); err == nil {
+			t.Errorf("%s: no error", desc)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	for _, c := range []struct {
+		manual, tool string
+		want         float64
+	}{
+		{"Create order", "POST /orders -> example.com/shop/api.createOrder", 1},
+		{"Add customer", "POST /customers -> example.com/shop/api.addCustomer", 1},
+		{"View order", "GET /orders/{id} -> example.com/shop/api.getOrder", 2 * 2 / 5.0},
+		{"Delete order", "POST /orders -> example.com/shop/api.createOrder", 2 * 1 / 4.0},
+		{"Nightly report", "example.com/shop.main", 0},
+	} {
+		if got := similarity(nameWords(c.manual), nameWords(c.tool)); got != c.want {
+			t.Errorf("similarity(%q, %q) = %.2f, want %.2f (%v, %v)", c.manual, c.tool, got, c.want, nameWords(c.manual), nameWords(c.tool))
+		}
+	}
+}
+
+func TestCompareManual(t *testing.T) {
+	out := Output{Processes: []ProcessReport{
+		{Name: "POST /orders -> example.com/shop/api.createOrder", Source: "example.com/shop/api.createOrder", Method: "POST", Entries: 1, Exits: 1, Writes: 2},
+		{Name: "GET /orders/{id} -> example.com/shop/api.getOrder", Source: "example.com/shop/api.getOrder", Method: "GET", Entries: 1, Exits: 1, Reads: 1},
+	}}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := writeTemp(t, "out.json", data)
+	for _, c := range []struct {
+		desc, csv string
+		tolerance string
+		want      bool
+	}{
+		{"the same sizes", "Process,CFP\nCreate order,4\nGet order,3\n", "0", true},
+		{"a deviation within the tolerance", "Process,CFP\nCreate order,5\nGet order,3\n", "1", true},
+		{"a deviation beyond the tolerance", "Process,CFP\nCreate order,6\nGet order,3\n", "1", false},
+		{"a process not measured manually", "Process,CFP\nCreate order,4\n", "10", false},
+		{"a process only measured manually", "Process,CFP\nCreate order,4\nGet order,3\nNightly report,2\n", "10", false},
+	} {
+		var tol tolerance
+		if err := tol.Set(c.tolerance); err != nil {
+			t.Fatal(err)
+		}
+		if got := compareManual(writeTemp(t, "manual.csv", []byte(c.csv)), output, tol, 0.5); got != c.want {
+			t.Errorf("%s: compared %v, want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestTypeDeviations(t *testing.T) {
+	pr := ProcessReport{Entries: 1, Exits: 1, Reads: 2}
+	mp := manualProcess{counts: map[string]int{"E": 1, "X": 1, "R": 1, "W": 1}}
+	if got := typeDeviations(mp, pr); got != "\tR+1 W-1" {
+		t.Errorf("deviations %q, want %q", got, "\tR+1 W-1")
+	}
+	if got := typeDeviations(manualProcess{}, pr); got != "" {
+		t.Errorf("deviations %q without types", got)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Markdown reports. -format markdown writes the measurement as a Markdown
+// document to paste into a PR description or a wiki page: a table of the
+// processes with their movements by type and the totals, the sizes by team
+// when processes are owned, the movements of each process with -detail and a
+// footer saying how the sizes were measured.
+
+// markdownSink renders the measurement as a Markdown document on Close.
+type markdownSink struct {
+	w      io.Writer
+	detail bool
+	h      Header
+	prs    []ProcessReport
+}
+
+// NewMarkdownSink returns a Sink writing the measurement as Markdown to w,
+// with the movements of each process when detail is set.
+func NewMarkdownSink(w io.Writer, detail bool) Sink {
+	return &markdownSink{w: w, detail: detail}
+}
+
+func (s *markdownSink) WriteHeader(h Header) error {
+	s.h = h
+	return nil
+}
+
+func (s *markdownSink) WriteProcess(pr ProcessReport) error {
+	pr.Movements = nil
+	s.prs = append(s.prs, pr)
+	return nil
+}
+
+func (s *markdownSink) WriteMovement(_ ProcessReport, m Movement) error {
+	if s.detail {
+		last := &s.prs[len(s.prs)-1]
+		last.Movements = append(last.Movements, m)
+	}
+	return nil
+}
+
+func (s *markdownSink) Close() error {
+	_, err := io.WriteString(s.w, renderMarkdown(s.h, s.prs))
+	return err
+}
+
+// renderMarkdown renders the report of the measurement of header h and
+// processes prs.
+func renderMarkdown(h Header, prs []ProcessReport) string {
+	var b strings.Builder
+	total := h.TotalEntries + h.TotalExits + h.TotalReads + h.TotalWrites
+	b.WriteString("## Functional size\n\n")
+	fmt.Fprintf(&b, "**%d CFP** in %s: %s, %s, %s and %s.\n", total, plural(len(prs), "functional process", "functional processes"),
+		plural(h.TotalEntries, "Entry", "Entries"), plural(h.TotalExits, "Exit", "Exits"), plural(h.TotalReads, "Read", "Reads"), plural(h.TotalWrites, "Write", "Writes"))
+	if h.Grade == GradeHeuristic {
+		fmt.Fprintf(&b, "\n> **Heuristic grade**: measured from the syntax alone, because %s.", markdownText(h.GradeReason))
+		if h.Bounds != nil {
+			fmt.Fprintf(&b, " The size is between %d and %d CFP.", h.Bounds.Low, h.Bounds.High)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n| Process | Trigger | E | X | R | W | CFP |\n")
+	b.WriteString("|---------|---------|--:|--:|--:|--:|----:|\n")
+	for _, pr := range prs {
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %d | %d |\n", markdownCode(pr.Name), markdownText(pr.Trigger), pr.Entries, pr.Exits, pr.Reads, pr.Writes, pr.cfp())
+	}
+	fmt.Fprintf(&b, "| **Total** | | %d | %d | %d | %d | **%d** |\n", h.TotalEntries, h.TotalExits, h.TotalReads, h.TotalWrites, total)
+
+	if len(h.Teams) > 0 {
+		b.WriteString("\n### By team\n\n")
+		b.WriteString("| Team | Processes | E | X | R | W | CFP |\n")
+		b.WriteString("|------|----------:|--:|--:|--:|--:|----:|\n")
+		for _, t := range h.Teams {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d |\n", markdownText(t.Team), t.Processes, t.Entries, t.Exits, t.Reads, t.Writes, t.CFP)
+		}
+	}
+
+	for _, pr := range prs {
+		if len(pr.Movements) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s: %d CFP</summary>\n\n", html.EscapeString(pr.Name), pr.cfp())
+		b.WriteString("| Type | Data group | Callee | Position |\n")
+		b.WriteString("|------|------------|--------|----------|\n")
+		for _, m := range pr.Movements {
+			typ := m.Type
+			if len(m.Tags) > 0 {
+				typ += " (" + strings.Join(m.Tags, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", typ, markdownText(m.DataGroup), markdownCode(m.Callee), markdownText(m.Pos))
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	b.WriteString("\n---\n\n")
+	b.WriteString("_Measured with COSMIC (ISO/IEC 19761) from the static call graph of the code: every entry point (a handler, a consumer, main) starts a functional process, ")
+	b.WriteString("which counts 1 CFP per data movement (Entry, Exit, Read, Write) of the calls reachable from it. ")
+	b.WriteString("Movements are classified by the called functions of known packages, so the size approximates a manual measurement")
+	if h.RulesVersion != 0 {
+		fmt.Fprintf(&b, "; rules version %d", h.RulesVersion)
+	}
+	if h.Tool != nil {
+		fmt.Fprintf(&b, ", %s %s", h.Tool.Module, h.Tool.Version)
+	}
+	b.WriteString("._\n")
+	return b.String()
+}
+
+// plural returns the count n of things, named one or many: "1 Read", "2 Reads".
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// markdownText escapes s for a cell of a Markdown table.
+func markdownText(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.NewReplacer(`|`, `\|`, `<`, `&lt;`, `>`, `&gt;`, `*`, `\*`, `_`, `\_`).Replace(s)
+}
+
+// markdownCode formats s as code in a cell of a Markdown table.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(s, "\n", " "), "|", `\|`) + "`"
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownPlurals(t *testing.T) {
+	pr := ProcessReport{Name: "main", Entries: 1, Exits: 1, Reads: 1, Writes: 1}
+	h := Header{Summary: Summary{TotalEntries: 1, TotalExits: 1, TotalReads: 1, TotalWrites: 1}, Processes: 1}
+	if got, want := renderMarkdown(h, []ProcessReport{pr}), "**4 CFP** in 1 functional process: 1 Entry, 1 Exit, 1 Read and 1 Write.\n"; !strings.Contains(got, want) {
+		t.Errorf("report lacks %q:\n%s", want, got)
+	}
+	h.TotalReads, h.TotalWrites = 2, 0
+	if got, want := renderMarkdown(h, []ProcessReport{pr, pr}), "in 2 functional processes: 1 Entry, 1 Exit, 2 Reads and 0 Writes.\n"; !strings.Contains(got, want) {
+		t.Errorf("report lacks %q:\n%s", want, got)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// Encoding. Encoding a value (json.Marshal, an Encoder's Encode) only
+// manipulates data: it moves the data when the encoded bytes cross the
+// boundary. An Encode is classified by the stream it writes into (see
+// streamMovement) and not counted when that stream is in memory or of unknown
+// origin. The bytes of a Marshal are followed to the calls writing them: a
+// file, socket or message producer counts itself, and an HTTP response makes
+// the Marshal the Exit of the response. Both Exits are named after the
+// encoded type.
+
+// marshalFuncs are the functions encoding a value into bytes, by package path.
+var marshalFuncs = map[string]map[string]bool{
+	"encoding/json":                                 {"Marshal": true, "MarshalIndent": true},
+	"encoding/xml":                                  {"Marshal": true, "MarshalIndent": true},
+	"gopkg.in/yaml.v2":                              {"Marshal": true},
+	"gopkg.in/yaml.v3":                              {"Marshal": true},
+	"sigs.k8s.io/yaml":                              {"Marshal": true},
+	"google.golang.org/protobuf/proto":              {"Marshal": true},
+	"github.com/golang/protobuf/proto":              {"Marshal": true},
+	"github.com/vmihailenco/msgpack/v5":             {"Marshal": true},
+	"github.com/fxamacker/cbor/v2":                  {"Marshal": true},
+	"google.golang.org/protobuf/encoding/protojson": {"Marshal": true},
+}
+
+// encoderPackages are the packages whose Encoders encode a value into a
+// stream.
+var encoderPackages = []string{
+	"encoding/json", "encoding/xml", "encoding/gob", "gopkg.in/yaml.v2", "gopkg.in/yaml.v3",
+	"github.com/BurntSushi/toml", "github.com/vmihailenco/msgpack/v5", "github.com/fxamacker/cbor/v2",
+}
+
+// isEncode reports whether fn is the Encode method of an encoder.
+func isEncode(fn *ssa.Function) bool {
+	return fn.Signature.Recv() != nil && fn.Pkg != nil && inPaths(fn.Pkg.Pkg.Path(), encoderPackages) &&
+		(fn.Name() == "Encode" || fn.Name() == "EncodeElement")
+}
+
+// encodedGroup returns the data group of the value encoded by the call of
+// fn, named after its type, or "".
+func encodedGroup(fn *ssa.Function, call *ssa.CallCommon) string {
+	args := call.Args
+	if fn.Signature.Recv() != nil && len(args) > 0 {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return entityName(unwrapInterface(args[0]).Type())
+}
+
+// marshalExit returns whether the call of fn marshals a value, whether the
+// bytes it returns as result are written into an HTTP response, and the data
+// group of the value.
+func marshalExit(fn *ssa.Function, call *ssa.CallCommon, result ssa.Value) (group string, marshals, exits bool) {
+	if fn.Pkg == nil || !matchesTable(fn, marshalFuncs) {
+		return "", false, false
+	}
+	if result == nil || !writtenToResponse(result, map[ssa.Value]bool{}) {
+		return "", true, false
+	}
+	return encodedGroup(fn, call), true, true
+}
+
+// writtenToResponse reports whether the bytes of v, or of a value derived
+// from them (a conversion, a reader or buffer of them), are written into an
+// HTTP response.
+func writtenToResponse(v ssa.Value, seen map[ssa.Value]bool) bool {
+	if seen[v] || len(seen) > 64 {
+		return false
+	}
+	seen[v] = true
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		switch r := ref.(type) {
+		case *ssa.Extract:
+			if r.Index == 0 && writtenToResponse(r, seen) {
+				return true
+			}
+		case *ssa.Convert:
+			if writtenToResponse(r, seen) {
+				return true
+			}
+		case *ssa.ChangeType:
+			if writtenToResponse(r, seen) {
+				return true
+			}
+		case *ssa.MakeInterface:
+			if writtenToResponse(r, seen) {
+				return true
+			}
+		case *ssa.Slice:
+			if writtenToResponse(r, seen) {
+				return true
+			}
+		case *ssa.Store:
+			// an element of the arguments of a variadic call
+			if ia, ok := r.Addr.(*ssa.IndexAddr); ok && r.Val == v && writtenToResponse(ia.X, seen) {
+				return true
+			}
+		case ssa.CallInstruction:
+			common := r.Common()
+			if common.IsInvoke() {
+				// w.Write(data)
+				if isResponseWriter(common.Value.Type()) {
+					return true
+				}
+				continue
+			}
+			for _, arg := range common.Args {
+				// w.Write(data), fmt.Fprint(w, string(data)), io.Copy(w, bytes.NewReader(data))
+				if arg != v && streamOrigin(arg, 0) == streamResponse {
+					return true
+				}
+			}
+			if sc := common.StaticCallee(); sc != nil && sc.Pkg != nil && streamOpeners[sc.Pkg.Pkg.Path()][sc.Name()] == streamMemory {
+				if call, ok := r.(*ssa.Call); ok && writtenToResponse(call, seen) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package main
+
+import "golang.org/x/tools/go/ssa"
+
+// Message producers. Publishing to a Kafka topic, a NATS subject or an AMQP
+// exchange is an Exit (see sendFuncs); the topic, subject or exchange names
+// the data group when it is constant.
+
+var (
+	saramaSends = map[string]bool{"SendMessage": true, "SendMessages": true}
+	natsSends   = map[string]bool{"Publish": true, "PublishMsg": true, "PublishRequest": true, "PublishAsync": true, "PublishMsgAsync": true}
+	amqpSends   = map[string]bool{"Publish": true, "PublishWithContext": true, "PublishWithDeferredConfirmWithContext": true}
+
+	// producerPkgPaths are the import paths of the message producers.
+	producerPkgPaths = []string{
+		"github.com/IBM/sarama",
+		"github.com/Shopify/sarama",
+		"github.com/segmentio/kafka-go",
+		"github.com/confluentinc/confluent-kafka-go/kafka",
+		"github.com/confluentinc/confluent-kafka-go/v2/kafka",
+		"github.com/nats-io/nats.go",
+		"github.com/nats-io/nats.go/jetstream",
+		"github.com/rabbitmq/amqp091-go",
+		"github.com/streadway/amqp",
+	}
+)
+
+// isProducerCall reports whether call calls a function or method of a
+// message producer package.
+func isProducerCall(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return call.Method.Pkg() != nil && inPaths(call.Method.Pkg().Path(), producerPkgPaths)
+	}
+	sc := call.StaticCallee()
+	return sc != nil && sc.Pkg != nil && sc.Pkg.Pkg != nil && inPaths(sc.Pkg.Pkg.Path(), producerPkgPaths)
+}
+
+// producerTopic returns the topic of a message sent with args: the Topic of
+// the message (sarama, kafka-go) or of the writer it is sent with, or the
+// first non-empty constant string, the NATS subject or the AMQP exchange (the
+// routing key on the default exchange ""), or "".
+func producerTopic(args []ssa.Value) string {
+	for _, arg := range args {
+		for _, v := range fieldStores(arg, "Topic") {
+			if topic, ok := constString(v); ok {
+				return topic
+			}
+		}
+	}
+	for _, arg := range args {
+		if s, ok := constString(arg); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// Message subscriptions. A handler subscribed to a topic is a functional
+// process triggered by the arrival of a message, which is its Entry; the
+// topic, when constant, names both the process and the data group.
+
+var (
+	// Subscription functions and methods taking a message handler. Map of
+	// package path -> name -> index of the topic argument (not counting a
+	// method receiver), or -1 when there is none.
+	subscriptionRegistrations = map[string]map[string]int{
+		"github.com/eclipse/paho.mqtt.golang": {
+			"Subscribe":                0,
+			"SubscribeMultiple":        -1,
+			"AddRoute":                 0,
+			"SetDefaultPublishHandler": -1,
+		},
+		// Pub/Sub callbacks are named after the subscription they receive from.
+		"cloud.google.com/go/pubsub": {
+			"Receive": -1,
+		},
+		"cloud.google.com/go/pubsub/v2": {
+			"Receive": -1,
+		},
+	}
+
+	// Queue polls by package path: receives from a queue the consumer pulls
+	// from. A function polling in a loop is a process of its own and every
+	// poll is an Entry.
+	pollFuncs = map[string]map[string]bool{
+		"github.com/aws/aws-sdk-go/service/sqs": {
+			"ReceiveMessage":            true,
+			"ReceiveMessageWithContext": true,
+		},
+		"github.com/aws/aws-sdk-go-v2/service/sqs": {
+			"ReceiveMessage": true,
+		},
+	}
+)
+
+// subscription is a message handler subscribed at a call site.
+type subscription struct {
+	via      string // subscribing function or method
+	topic    string
+	handlers []*ssa.Function
+}
+
+// subscriptionOf returns the subscription made by call, if it subscribes a handler.
+func subscriptionOf(call *ssa.CallCommon, routes *routeTable) (subscription, bool) {
+	var pkgPath, name, via string
+	recv, args := ssa.Value(nil), call.Args
+	if call.IsInvoke() {
+		if call.Method.Pkg() == nil {
+			return subscription{}, false
+		}
+		pkgPath, name, via = call.Method.Pkg().Path(), call.Method.Name(), call.Method.FullName()
+		recv = call.Value
+	} else if sc := call.StaticCallee(); sc != nil && sc.Pkg != nil && sc.Pkg.Pkg != nil {
+		pkgPath, name, via = sc.Pkg.Pkg.Path(), sc.Name(), sc.String()
+		if sc.Signature.Recv() != nil && len(args) > 0 {
+			recv, args = args[0], args[1:]
+		}
+	} else {
+		return subscription{}, false
+	}
+	idx, ok := subscriptionRegistrations[pkgPath][name]
+	if !ok {
+		return subscription{}, false
+	}
+	sub := subscription{via: via}
+	if idx < 0 && recv != nil {
+		sub.topic = receiverName(recv)
+	}
+	for i, arg := range args {
+		if i == idx {
+			sub.topic, _ = constString(arg)
+			continue
+		}
+		sub.handlers = append(sub.handlers, extractFunctionsFromValue(arg, routes)...)
+	}
+	return sub, len(sub.handlers) > 0
+}
+
+// collectSubscriptions finds the message subscriptions made by fn. Each
+// handler becomes an entry, and the message it receives is recorded in
+// received as an Entry movement of the handler.
+func collectSubscriptions(fn *ssa.Function, routes *routeTable, entries map[*ssa.Function]entryPoint, received map[*ssa.Function][]Movement) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			sub, ok := subscriptionOf(call.Common(), routes)
+			if !ok {
+				continue
+			}
+			m := Movement{Type: MovementEntry, DataGroup: sub.topic, Callee: sub.via}
+			if pos := fn.Prog.Fset.Position(call.Common().Pos()); pos.IsValid() {
+				m.Pos = pos.String()
+			}
+			for _, h := range sub.handlers {
+				entries[h] = entryPoint{trigger: "message subscription via " + sub.via, route: sub.topic}
+				received[h] = append(received[h], m)
+			}
+		}
+	}
+}
+
+// pollingLoops memoizes which functions poll a queue in a loop.
+type pollingLoops map[*ssa.Function]bool
+
+// of reports whether fn polls a queue in a loop.
+func (p pollingLoops) of(fn *ssa.Function) bool {
+	if polls, ok := p[fn]; ok {
+		return polls
+	}
+	polls := false
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok && matchesTable(call.Common().StaticCallee(), pollFuncs) {
+				polls = true
+			}
+		}
+	}
+	polls = polls && hasLoop(fn)
+	p[fn] = polls
+	return polls
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Metrics. Updating a counter, gauge or histogram (Prometheus Inc, Observe
+// and Set, a statsd Incr or Timing, an OpenTelemetry instrument's Add or
+// Record) is observability instrumentation, not a movement of functional
+// data; neither is the delivery of the metrics to their collector, through
+// the UDP socket of a statsd client or the exporter of a meter provider.
+// Movements of metrics packages are tagged TagMetrics and by default stay in
+// the -detail output without counting; -metrics counts them. The
+// metrics_packages of the -config file add in-house instrumentation packages.
+
+// TagMetrics marks movements of metrics instrumentation packages, which only
+// count with -metrics.
+const TagMetrics = "metrics"
+
+// metricsPackages are the metrics instrumentation packages, by path prefix.
+var metricsPackages = []string{
+	"expvar",
+	"github.com/prometheus/client_golang",
+	"go.opentelemetry.io/otel/metric",
+	"go.opentelemetry.io/otel/sdk/metric",
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric",
+	"go.opentelemetry.io/otel/exporters/prometheus",
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric",
+	"github.com/DataDog/datadog-go",
+	"github.com/cactus/go-statsd-client",
+	"github.com/smira/go-statsd",
+	"gopkg.in/alexcesaro/statsd.v2",
+	"github.com/quipo/statsd",
+	"github.com/armon/go-metrics",
+	"github.com/hashicorp/go-metrics",
+	"github.com/rcrowley/go-metrics",
+	"github.com/VictoriaMetrics/metrics",
+	"github.com/go-kit/kit/metrics",
+	"github.com/uber-go/tally",
+}
+
+// isMetrics reports whether pkg is a metrics instrumentation package.
+func (s settings) isMetrics(pkg *ssa.Package) bool {
+	return pkg != nil && pkg.Pkg != nil && s.isMetricsPath(pkg.Pkg.Path())
+}
+
+// isMetricsPath reports whether import path p is of a metrics
+// instrumentation package.
+func (s settings) isMetricsPath(p string) bool {
+	for _, prefix := range append(metricsPackages[:len(metricsPackages):len(metricsPackages)], s.MetricsPackages...) {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
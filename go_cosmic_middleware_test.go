@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// measureFixture measures the fixture module testdata/name with the flags
+// args.
+func measureFixture(t *testing.T, name string, args ...string) Output {
+	t.Helper()
+	an, err := NewAnalyzer(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := an.Measure("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// processBySource returns the process of out rooted at source, or fails.
+func processBySource(t *testing.T, out Output, source string) ProcessReport {
+	t.Helper()
+	for _, pr := range out.Processes {
+		if pr.Source == source {
+			return pr
+		}
+	}
+	t.Fatalf("no process of %s", source)
+	return ProcessReport{}
+}
+
+// TestMiddlewareClosures checks that the closures returned by middleware are
+// folded into the processes of the handlers they wrap instead of being
+// processes of their own, with their movements tagged as infrastructure.
+func TestMiddlewareClosures(t *testing.T) {
+	out := measureFixture(t, "middleware")
+	var sources []string
+	for _, pr := range out.Processes {
+		sources = append(sources, pr.Name)
+	}
+	sort.Strings(sources)
+	want := []string{
+		"GET /orders -> example.com/shop.getOrder",
+		"POST /orders -> example.com/shop.addOrder",
+		"example.com/shop.main",
+	}
+	if strings.Join(sources, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("processes:\n%s\nwant:\n%s", strings.Join(sources, "\n"), strings.Join(want, "\n"))
+	}
+
+	get := processBySource(t, out, "example.com/shop.getOrder")
+	infra := map[string]bool{}
+	for _, m := range get.Movements {
+		if hasTag(m, TagInfrastructure) {
+			infra[m.Callee] = true
+		} else if m.Callee == "log.Printf" || m.Callee == "(net/http.Header).Get" {
+			t.Errorf("movement of %s in middleware is not tagged infrastructure", m.Callee)
+		}
+	}
+	for _, callee := range []string{"log.Printf", "(net/http.Header).Get", "(net/http.ResponseWriter).WriteHeader"} {
+		if !infra[callee] {
+			t.Errorf("getOrder lacks the infrastructure movement of %s, folded from its middleware", callee)
+		}
+	}
+	if infra["os.ReadFile"] {
+		t.Errorf("the handler's own read is tagged infrastructure")
+	}
+
+	// The entry of auth reads the request, already entered by getOrder; its
+	// exit and the write of logging are the two movements it adds.
+	if get.Entries != 1 || get.Exits != 2 || get.Reads != 1 || get.Writes != 1 {
+		t.Errorf("E, X, R, W = %d, %d, %d, %d, want 1, 2, 1, 1", get.Entries, get.Exits, get.Reads, get.Writes)
+	}
+	excluded := processBySource(t, measureFixture(t, "middleware", "-exclude-infra"), "example.com/shop.getOrder")
+	if excluded.Entries != 1 || excluded.Exits != 1 || excluded.Reads != 1 || excluded.Writes != 0 {
+		t.Errorf("-exclude-infra: E, X, R, W = %d, %d, %d, %d, want the 1, 1, 1, 0 of getOrder alone", excluded.Entries, excluded.Exits, excluded.Reads, excluded.Writes)
+	}
+}
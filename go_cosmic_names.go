@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Process names. Baselines, dashboards and the -cache match processes by
+// name, source and ID, so these must not change when the code around a
+// process does. A process rooted at a declared function is named after it.
+// The compiler numbers anonymous functions in the order they appear in their
+// enclosing function (main$1, main$2), so that adding a goroutine renames
+// every handler literal after it; the processes rooted at function literals,
+// such as handlers, goroutines, pollers and signal handlers, are instead named
+// after their enclosing function and their role, seeded in the source with
+// their route when they have one: main$handler[/users], main$go; the name is
+// prefixed with the route already and leaves it out. A second literal of the
+// same role and route in the same function is main$go2, numbered in source
+// order among the literals of that role and route only. The startup process
+// of a package (-init) is named <package>.init. The ID of a process is a hash
+// of its source and HTTP method.
+
+// closureRoles are the roles naming the processes rooted at function
+// literals, by the prefix of their trigger.
+var closureRoles = []struct{ trigger, role string }{
+	{"registered via", "handler"},
+	{"GraphQL resolver", "resolver"},
+	{"scheduled via", "job"},
+	{"goroutine started by", "go"},
+	{"queue polling loop", "poll"},
+	{"OS signal", "signal"},
+	{"message subscription", "subscriber"},
+	{"WebSocket upgrade", "websocket"},
+	{"dataloader batch", "batch"},
+}
+
+// closureRole returns the role of function literal fn among entries, with
+// the route of its process if it has one, or "func" if it roots no process
+// or a process of another trigger.
+func closureRole(fn *ssa.Function, entries map[*ssa.Function]entryPoint) string {
+	ep, ok := entries[fn]
+	if !ok {
+		return "func"
+	}
+	for _, r := range closureRoles {
+		if !strings.HasPrefix(ep.trigger, r.trigger) {
+			continue
+		}
+		if ep.route != "" {
+			return r.role + "[" + ep.route + "]"
+		}
+		return r.role
+	}
+	return "func"
+}
+
+// stableFuncName returns the name of fn within its package, as fn.Name()
+// but naming function literals by role (see closureRole) instead of their
+// position among the literals of the enclosing function.
+func stableFuncName(fn *ssa.Function, entries map[*ssa.Function]entryPoint) string {
+	parent := fn.Parent()
+	if parent == nil {
+		return fn.Name()
+	}
+	suffix := closureSuffix(fn, parent, entries)
+	if i, j := strings.Index(suffix, "["), strings.LastIndex(suffix, "]"); i >= 0 && j > i {
+		suffix = suffix[:i] + suffix[j+1:]
+	}
+	return stableFuncName(parent, entries) + "$" + suffix
+}
+
+// stableSource returns the source of the process rooted at fn, as
+// fn.String() but naming function literals by role.
+func stableSource(fn *ssa.Function, entries map[*ssa.Function]entryPoint) string {
+	parent := fn.Parent()
+	if parent == nil {
+		return fn.String()
+	}
+	return stableSource(parent, entries) + "$" + closureSuffix(fn, parent, entries)
+}
+
+// closureSuffix returns the role of function literal fn of parent, numbered
+// from 2 when an earlier literal of parent has the same role.
+func closureSuffix(fn, parent *ssa.Function, entries map[*ssa.Function]entryPoint) string {
+	role := closureRole(fn, entries)
+	n := 1
+	for _, sibling := range parent.AnonFuncs {
+		if sibling == fn {
+			break
+		}
+		if closureRole(sibling, entries) == role {
+			n++
+		}
+	}
+	if n == 1 {
+		return role
+	}
+	return role + strconv.Itoa(n)
+}
+
+// processID returns the ID of the process of source and HTTP method, stable
+// across measurements as long as they are.
+func processID(source, method string) string {
+	h := sha256.Sum256([]byte(cacheKey(source, method)))
+	return hex.EncodeToString(h[:6])
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Overrides. A //cosmic: comment overrides the classification of the call it
+// is written after, or of the call on the next line when it is alone on its
+// line (the outermost call of the line, when calls are nested):
+//
+//	store.SaveOrder(o) //cosmic:W orders
+//
+// The movement is E, X, R, W or none, optionally followed by the data group;
+// the call is then classified by the override alone. "rules learn"
+// generalizes the overrides of a code base into -config rules, so the fixes
+// made by hand in one place apply everywhere.
+
+// overridePrefix starts an override comment.
+const overridePrefix = "//cosmic:"
+
+// TagOverride marks the movements given by an override.
+const TagOverride = "override"
+
+// override is a //cosmic: comment.
+type override struct {
+	movement, group string
+	pos             token.Position
+}
+
+// overrides holds the overrides by the position of the call they override
+// (its opening parenthesis).
+type overrides map[token.Pos]override
+
+// collectOverrides returns the overrides in the files of pkgs. Overrides that
+// cannot be parsed or follow no call are reported and ignored.
+func collectOverrides(fset *token.FileSet, pkgs []*packages.Package) overrides {
+	res := overrides{}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Syntax {
+			for _, cg := range f.Comments {
+				for _, c := range cg.List {
+					if !strings.HasPrefix(c.Text, overridePrefix) {
+						continue
+					}
+					pos := fset.Position(c.Pos())
+					o, err := parseOverride(strings.TrimPrefix(c.Text, overridePrefix))
+					if err != nil {
+						log.Printf("warning: %s: %v", pos, err)
+						continue
+					}
+					o.pos = pos
+					call := overriddenCall(fset, f, c)
+					if call == nil {
+						log.Printf("warning: %s: %s override follows no call", pos, overridePrefix)
+						continue
+					}
+					res[call.Lparen] = o
+				}
+			}
+		}
+	}
+	return res
+}
+
+// parseOverride parses the text of an override after its prefix.
+func parseOverride(text string) (override, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return override{}, fmt.Errorf("%s override without movement", overridePrefix)
+	}
+	o := override{movement: fields[0], group: strings.Join(fields[1:], " ")}
+	switch o.movement {
+	case MovementEntry, MovementExit, MovementRead, MovementWrite, movementNone:
+		return o, nil
+	}
+	return override{}, fmt.Errorf("%s override: movement must be E, X, R, W or none, not %q", overridePrefix, o.movement)
+}
+
+// overriddenCall returns the outermost call ending on the line of comment c
+// in f, or starting on the next line if no code precedes c on its line.
+func overriddenCall(fset *token.FileSet, f *ast.File, c *ast.Comment) *ast.CallExpr {
+	line := fset.Position(c.Pos()).Line
+	var same, next *ast.CallExpr
+	alone := true
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil || n == ast.Node(f) {
+			return true
+		}
+		if _, ok := n.(*ast.CommentGroup); ok {
+			return false
+		}
+		start, end := fset.Position(n.Pos()).Line, fset.Position(n.End()).Line
+		if end == line && n.End() <= c.Pos() {
+			alone = false
+		}
+		if call, ok := n.(*ast.CallExpr); ok {
+			switch {
+			case same == nil && end == line && call.End() <= c.Pos():
+				same = call
+			case next == nil && start == line+1:
+				next = call
+			}
+		}
+		return start <= line+1 && end >= line
+	})
+	if !alone {
+		return same
+	}
+	return next
+}
+
+// at returns the override of the call of common.
+func (o overrides) at(common *ssa.CallCommon) (override, bool) {
+	ov, ok := o[common.Pos()]
+	return ov, ok
+}
+
+// calleeName returns the name of the function or method called by common.
+func calleeName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return common.Method.FullName()
+	}
+	if sc := common.StaticCallee(); sc != nil {
+		return sc.String()
+	}
+	return common.Value.String()
+}
+
+// receiverOf returns the name of the receiver type of the method called by
+// common, as T or *T, or "" for a function.
+func receiverOf(common *ssa.CallCommon) string {
+	var t types.Type
+	switch {
+	case common.IsInvoke():
+		t = common.Value.Type()
+	case common.Signature().Recv() != nil:
+		t = common.Signature().Recv().Type()
+	default:
+		return ""
+	}
+	ptr := ""
+	if p, ok := t.(*types.Pointer); ok {
+		t, ptr = p.Elem(), "*"
+	}
+	if named, ok := t.(*types.Named); ok {
+		return ptr + named.Obj().Name()
+	}
+	return ""
+}
+
+// overriddenCallee is the function or method of an overridden call.
+type overriddenCallee struct {
+	pkg, receiver, name string
+}
+
+// runRulesLearn implements "rules learn [root]": it aggregates the overrides
+// of the analyzed code by the function or method they override and prints
+// -config rules for them, generalized to a name prefix where the overrides of
+// several methods of a receiver starting with the same word (SaveOrder,
+// SaveUser) agree, for review.
+func runRulesLearn(args []string) {
+	fs := flag.NewFlagSet("rules learn", flag.ExitOnError)
+	var cfg analysisConfig
+	cfg.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s rules learn [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	conf, err := readSettings(cfg.configFile)
+	if err != nil {
+		log.Fatalf("-config: %v", err)
+	}
+	a := analyze(root, cfg)
+	if a.untyped != "" {
+		log.Fatalf("rules learn needs code that type-checks")
+	}
+
+	// learned holds the overrides of each callee, by movement.
+	learned := map[overriddenCallee]map[string][]override{}
+	for _, pkg := range a.pkgs {
+		for _, fn := range packageFunctions(a.prog, pkg) {
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					call, ok := instr.(ssa.CallInstruction)
+					if !ok {
+						continue
+					}
+					common := call.Common()
+					o, ok := a.overrides.at(common)
+					if !ok {
+						continue
+					}
+					callee := overriddenCallee{receiver: receiverOf(common)}
+					if common.IsInvoke() && common.Method.Pkg() != nil {
+						callee.pkg, callee.name = common.Method.Pkg().Path(), common.Method.Name()
+					} else if sc := common.StaticCallee(); sc != nil && sc.Pkg != nil {
+						callee.pkg, callee.name = sc.Pkg.Pkg.Path(), sc.Name()
+					} else {
+						log.Printf("%s: the override of a dynamic call cannot be learned", o.pos)
+						continue
+					}
+					if conf.rule(callee.pkg, callee.name, callee.receiver, fn.Pkg.Pkg.Path()) == o.movement {
+						continue // a rule already says so
+					}
+					if learned[callee] == nil {
+						learned[callee] = map[string][]override{}
+					}
+					learned[callee][o.movement] = append(learned[callee][o.movement], o)
+				}
+			}
+		}
+	}
+	if len(learned) == 0 {
+		log.Printf("no overrides to learn from")
+	}
+
+	out := settings{Rules: learnRules(learned)}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		log.Fatalf("write rules: %v", err)
+	}
+}
+
+// learnRules returns the rules generalizing the overrides learned, sorted by
+// package, receiver and function. Callees overridden with different
+// movements get no rule, and neither is a prefix generalized over them.
+func learnRules(learned map[overriddenCallee]map[string][]override) []classificationRule {
+	// byWord holds the callees of each movement, by package, receiver and
+	// first word of their name.
+	type wordKey struct {
+		pkg, receiver, word string
+	}
+	byWord := map[wordKey]map[string][]overriddenCallee{}
+	conflicts := map[wordKey]bool{}
+	for callee, byMovement := range learned {
+		k := wordKey{callee.pkg, callee.receiver, firstWord(callee.name)}
+		if len(byMovement) > 1 {
+			log.Printf("%s: overridden as %s; no rule learned", qualifiedCallee(callee), strings.Join(movementsOf(byMovement), " and "))
+			conflicts[k] = true
+			continue
+		}
+		for m := range byMovement {
+			if byWord[k] == nil {
+				byWord[k] = map[string][]overriddenCallee{}
+			}
+			byWord[k][m] = append(byWord[k][m], callee)
+		}
+	}
+
+	var rules []classificationRule
+	for k, byMovement := range byWord {
+		generalize := len(byMovement) == 1 && !conflicts[k] && k.receiver != ""
+		for m, callees := range byMovement {
+			sort.Slice(callees, func(i, j int) bool { return callees[i].name < callees[j].name })
+			var sites []string
+			var names []string
+			for _, callee := range callees {
+				names = append(names, callee.name)
+				for _, o := range learned[callee][m] {
+					sites = append(sites, o.pos.String())
+				}
+			}
+			sort.Strings(sites)
+			if generalize && len(callees) > 1 {
+				rules = append(rules, classificationRule{
+					Package:  k.pkg,
+					Receiver: k.receiver,
+					Function: k.word + "*",
+					Movement: m,
+					Comment:  fmt.Sprintf("learned from the overrides of %s: %s", strings.Join(names, ", "), strings.Join(sites, ", ")),
+				})
+				continue
+			}
+			for _, callee := range callees {
+				var at []string
+				for _, o := range learned[callee][m] {
+					at = append(at, o.pos.String())
+				}
+				sort.Strings(at)
+				rules = append(rules, classificationRule{
+					Package:  callee.pkg,
+					Receiver: callee.receiver,
+					Function: callee.name,
+					Movement: m,
+					Comment:  "learned from the overrides at " + strings.Join(at, ", "),
+				})
+			}
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Receiver != b.Receiver {
+			return a.Receiver < b.Receiver
+		}
+		return a.Function < b.Function
+	})
+	return rules
+}
+
+// firstWord returns the first word of a mixed-caps name: Save of SaveOrder.
+func firstWord(name string) string {
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			return name[:i]
+		}
+	}
+	return name
+}
+
+// movementsOf returns the movements of overrides, sorted.
+func movementsOf(byMovement map[string][]override) []string {
+	var ms []string
+	for m := range byMovement {
+		ms = append(ms, m)
+	}
+	sort.Strings(ms)
+	return ms
+}
+
+// qualifiedCallee returns the name of callee as in the source.
+func qualifiedCallee(callee overriddenCallee) string {
+	if callee.receiver == "" {
+		return callee.pkg + "." + callee.name
+	}
+	if strings.HasPrefix(callee.receiver, "*") {
+		return "(*" + callee.pkg + "." + callee.receiver[1:] + ")." + callee.name
+	}
+	return "(" + callee.pkg + "." + callee.receiver + ")." + callee.name
+}
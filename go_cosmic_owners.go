@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Ownership. A process is owned by the teams owning the file of its entry
+// function in the CODEOWNERS file of the repository (in .github/, docs/ or at
+// the root, as GitHub and GitLab look for it), or in the "owners" mapping of
+// the -config file, which takes precedence. The output then rolls the sizes
+// up by team; a process with several owners counts for each of them.
+
+// ownerMapping assigns the files matching Path, a CODEOWNERS pattern relative
+// to the analyzed directory, to Owners; no owners leaves the files unowned.
+type ownerMapping struct {
+	Path   string   `json:"path"`
+	Owners []string `json:"owners"`
+}
+
+// TeamTotal is the size of the processes owned by a team.
+type TeamTotal struct {
+	Team      string `json:"team"`
+	Processes int    `json:"processes"`
+	Entries   int    `json:"entries"`
+	Exits     int    `json:"exits"`
+	Reads     int    `json:"reads"`
+	Writes    int    `json:"writes"`
+	CFP       int    `json:"cfp"`
+}
+
+// ownerRule is a compiled CODEOWNERS line.
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwners are the ownership rules of the analyzed code: those of the
+// CODEOWNERS file, relative to the directory holding it, and those of the
+// -config file, relative to the analyzed directory.
+type codeOwners struct {
+	base, root    string
+	rules, mapped []ownerRule
+}
+
+// codeOwnersFiles are the locations of a CODEOWNERS file in a repository, in
+// the order they are looked up.
+var codeOwnersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// compileOwnerMappings compiles the owners mapping of the -config file.
+func compileOwnerMappings(mappings []ownerMapping) ([]ownerRule, error) {
+	var rules []ownerRule
+	for _, m := range mappings {
+		re, err := ownerPattern(m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("owners: %v", err)
+		}
+		rules = append(rules, ownerRule{re, m.Owners})
+	}
+	return rules, nil
+}
+
+// readCodeOwners returns the ownership rules of the code in dir: the
+// CODEOWNERS file of dir or of the closest parent directory having one, up to
+// the root of the repository, and the mapped rules of the -config file.
+func readCodeOwners(dir string, mapped []ownerRule) (codeOwners, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return codeOwners{}, err
+	}
+	o := codeOwners{root: root, mapped: mapped}
+	for d := root; ; d = filepath.Dir(d) {
+		for _, name := range codeOwnersFiles {
+			path := filepath.Join(d, filepath.FromSlash(name))
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			o.base = d
+			o.rules, err = parseCodeOwners(path)
+			return o, err
+		}
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil || filepath.Dir(d) == d {
+			return o, nil
+		}
+	}
+}
+
+// parseCodeOwners parses a CODEOWNERS file: lines of a pattern followed by
+// the owners, with # comments. GitLab sections ([Section]) are ignored.
+func parseCodeOwners(path string) ([]ownerRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rules []ownerRule
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] != '\\') {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+			continue
+		}
+		re, err := ownerPattern(strings.ReplaceAll(fields[0], `\#`, "#"))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		rules = append(rules, ownerRule{re, fields[1:]})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// ownerPattern compiles a CODEOWNERS pattern, which has the syntax of a
+// .gitignore pattern: a pattern with a slash but at its end is relative to
+// the base directory, others match at any depth; * and ? match within a path
+// element and ** across them; a directory matches the files under it.
+func ownerPattern(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	if strings.HasPrefix(p, "!") {
+		return nil, fmt.Errorf("%s: negated patterns are not supported", pattern)
+	}
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", pattern, err)
+	}
+	return re, nil
+}
+
+// of returns the owners of file, by rules the last matching of which wins, or
+// nil.
+func (o codeOwners) of(file string) []string {
+	match := func(base string, rules []ownerRule) ([]string, bool) {
+		rel, err := filepath.Rel(base, file)
+		if base == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, false
+		}
+		rel = filepath.ToSlash(rel)
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].pattern.MatchString(rel) {
+				return rules[i].owners, true
+			}
+		}
+		return nil, false
+	}
+	if owners, ok := match(o.root, o.mapped); ok {
+		return owners
+	}
+	owners, _ := match(o.base, o.rules)
+	return owners
+}
+
+// owns returns the owners of the file declaring fn. Wrappers and other
+// synthetic functions have no position and no owners.
+func (o codeOwners) owns(fn *ssa.Function) []string {
+	if !fn.Pos().IsValid() {
+		return nil
+	}
+	return o.of(fn.Prog.Fset.Position(fn.Pos()).Filename)
+}
+
+// teamTotals rolls the sizes of processes up by owning team, sorted by team,
+// or returns nil if no process is owned.
+func teamTotals(processes []ProcessReport) []TeamTotal {
+	byTeam := map[string]*TeamTotal{}
+	for _, pr := range processes {
+		for _, team := range pr.Owners {
+			t := byTeam[team]
+			if t == nil {
+				t = &TeamTotal{Team: team}
+				byTeam[team] = t
+			}
+			t.Processes++
+			t.Entries += pr.Entries
+			t.Exits += pr.Exits
+			t.Reads += pr.Reads
+			t.Writes += pr.Writes
+			t.CFP += pr.cfp()
+		}
+	}
+	var res []TeamTotal
+	for _, t := range byTeam {
+		res = append(res, *t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Team < res[j].Team })
+	return res
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON Patch. A system keeping a copy of the last measurement applies the
+// changes of the next one rather than ingesting it whole: "compare -patch"
+// writes the RFC 6902 JSON Patch turning the first JSON output into the
+// second. The processes are matched by source and method, as by compare, so
+// that a changed process is patched in place, a new one added and a gone one
+// removed, instead of every process after the first difference replaced.
+// Other arrays (movements, data groups) are replaced whole when they differ.
+
+// patchOp is an operation of a JSON Patch.
+type patchOp struct {
+	Op   string `json:"op"`
+	From string `json:"from,omitempty"`
+	Path string `json:"path"`
+	// Value is nil for remove and move, and points to null for a null value.
+	Value *any `json:"value,omitempty"`
+}
+
+// valueOp returns the operation op of v at path.
+func valueOp(op, path string, v any) patchOp {
+	return patchOp{Op: op, Path: path, Value: &v}
+}
+
+// writePatch writes to path the JSON Patch from the JSON output at a to the
+// one at b.
+func writePatch(path, a, b string) (int, error) {
+	da, err := readJSONDocument(a)
+	if err != nil {
+		return 0, err
+	}
+	db, err := readJSONDocument(b)
+	if err != nil {
+		return 0, err
+	}
+	ops := diffJSON("", da, db, nil)
+	if ops == nil {
+		ops = []patchOp{}
+	}
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return len(ops), os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// readJSONDocument reads the JSON document at path, keeping its numbers as
+// written.
+func readJSONDocument(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return v, nil
+}
+
+// diffJSON appends to ops the operations turning a into b at path.
+func diffJSON(path string, a, b any, ops []patchOp) []patchOp {
+	if reflect.DeepEqual(a, b) {
+		return ops
+	}
+	oa, okA := a.(map[string]any)
+	ob, okB := b.(map[string]any)
+	if !okA || !okB {
+		return append(ops, valueOp("replace", path, b))
+	}
+	keys := make([]string, 0, len(oa)+len(ob))
+	for k := range oa {
+		keys = append(keys, k)
+	}
+	for k := range ob {
+		if _, ok := oa[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		va, inA := oa[k]
+		vb, inB := ob[k]
+		p := path + "/" + patchPointer(k)
+		switch {
+		case !inB:
+			ops = append(ops, patchOp{Op: "remove", Path: p})
+		case !inA:
+			ops = append(ops, valueOp("add", p, vb))
+		case k == "processes" && path == "":
+			ops = diffProcesses(p, va, vb, ops)
+		default:
+			ops = diffJSON(p, va, vb, ops)
+		}
+	}
+	return ops
+}
+
+// diffProcesses appends to ops the operations turning the processes a into
+// b at path: the processes of a missing from b are removed, those of b are
+// then moved or added into their index in turn and patched there.
+func diffProcesses(path string, a, b any, ops []patchOp) []patchOp {
+	pa, okA := a.([]any)
+	pb, okB := b.([]any)
+	if !okA || !okB {
+		return diffJSON(path, a, b, ops)
+	}
+	inB := map[string]bool{}
+	for _, pr := range pb {
+		inB[processKey(pr)] = true
+	}
+	var cur []any
+	var gone []int
+	for i, pr := range pa {
+		if inB[processKey(pr)] {
+			cur = append(cur, pr)
+		} else {
+			gone = append(gone, i)
+		}
+	}
+	for i := len(gone) - 1; i >= 0; i-- {
+		ops = append(ops, patchOp{Op: "remove", Path: path + "/" + strconv.Itoa(gone[i])})
+	}
+	for i, pr := range pb {
+		key := processKey(pr)
+		j := i
+		for j < len(cur) && processKey(cur[j]) != key {
+			j++
+		}
+		p := path + "/" + strconv.Itoa(i)
+		switch {
+		case j == len(cur):
+			ops = append(ops, valueOp("add", p, pr))
+			cur = append(cur[:i], append([]any{pr}, cur[i:]...)...)
+			continue
+		case j > i:
+			ops = append(ops, patchOp{Op: "move", From: path + "/" + strconv.Itoa(j), Path: p})
+			moved := cur[j]
+			cur = append(cur[:j], cur[j+1:]...)
+			cur = append(cur[:i], append([]any{moved}, cur[i:]...)...)
+		}
+		ops = diffJSON(p, cur[i], pr, ops)
+	}
+	return ops
+}
+
+// processKey identifies a process of a JSON output by source and method, as
+// cacheKey, or by name when it has no source.
+func processKey(pr any) string {
+	o, _ := pr.(map[string]any)
+	source, _ := o["source"].(string)
+	method, _ := o["method"].(string)
+	if source == "" {
+		name, _ := o["name"].(string)
+		return "name " + name
+	}
+	return cacheKey(source, method)
+}
+
+// patchPointer escapes a key of an object as a JSON Pointer token.
+func patchPointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
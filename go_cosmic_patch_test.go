@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// decodeJSON decodes s as readJSONDocument does.
+func decodeJSON(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("%v: %s", err, s)
+	}
+	return v
+}
+
+// applyPatch applies the RFC 6902 operations add, remove, replace and move
+// of ops to doc.
+func applyPatch(t *testing.T, doc any, ops []patchOp) any {
+	t.Helper()
+	for _, op := range ops {
+		switch op.Op {
+		case "add":
+			doc = patchAdd(t, doc, patchTokens(op.Path), *op.Value)
+		case "remove":
+			doc, _ = patchRemove(t, doc, patchTokens(op.Path))
+		case "replace":
+			doc, _ = patchRemove(t, doc, patchTokens(op.Path))
+			doc = patchAdd(t, doc, patchTokens(op.Path), *op.Value)
+		case "move":
+			var v any
+			doc, v = patchRemove(t, doc, patchTokens(op.From))
+			doc = patchAdd(t, doc, patchTokens(op.Path), v)
+		default:
+			t.Fatalf("operation %s", op.Op)
+		}
+	}
+	return doc
+}
+
+// patchTokens returns the unescaped tokens of a JSON Pointer.
+func patchTokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+	}
+	return tokens
+}
+
+// patchIndex returns the array index token tok of an array of n elements.
+func patchIndex(t *testing.T, tok string, n int) int {
+	t.Helper()
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || i > n {
+		t.Fatalf("index %s of an array of %d", tok, n)
+	}
+	return i
+}
+
+// patchAdd adds v at tokens of doc and returns the document.
+func patchAdd(t *testing.T, doc any, tokens []string, v any) any {
+	t.Helper()
+	if len(tokens) == 0 {
+		return v
+	}
+	switch c := doc.(type) {
+	case map[string]any:
+		if len(tokens) == 1 {
+			c[tokens[0]] = v
+		} else {
+			c[tokens[0]] = patchAdd(t, c[tokens[0]], tokens[1:], v)
+		}
+		return c
+	case []any:
+		i := patchIndex(t, tokens[0], len(c))
+		if len(tokens) == 1 {
+			return append(c[:i], append([]any{v}, c[i:]...)...)
+		}
+		c[i] = patchAdd(t, c[i], tokens[1:], v)
+		return c
+	}
+	t.Fatalf("add into %v", doc)
+	return nil
+}
+
+// patchRemove removes the value at tokens of doc and returns the document
+// and the value.
+func patchRemove(t *testing.T, doc any, tokens []string) (any, any) {
+	t.Helper()
+	switch c := doc.(type) {
+	case map[string]any:
+		v, ok := c[tokens[0]]
+		if !ok {
+			t.Fatalf("no member %s to remove", tokens[0])
+		}
+		if len(tokens) == 1 {
+			delete(c, tokens[0])
+		} else {
+			c[tokens[0]], v = patchRemove(t, v, tokens[1:])
+		}
+		return c, v
+	case []any:
+		i := patchIndex(t, tokens[0], len(c)-1)
+		v := c[i]
+		if len(tokens) == 1 {
+			return append(c[:i], c[i+1:]...), v
+		}
+		c[i], v = patchRemove(t, v, tokens[1:])
+		return c, v
+	}
+	t.Fatalf("remove from %v", doc)
+	return nil, nil
+}
+
+func TestPatch(t *testing.T) {
+	get := `{"source": "example.com/shop.getOrder", "method": "GET", "name": "GET /orders -> example.com/shop.getOrder", "reads": 1}`
+	add := `{"source": "example.com/shop.addOrder", "method": "POST", "name": "POST /orders -> example.com/shop.addOrder", "writes": 1}`
+	del := `{"source": "example.com/shop.deleteOrder", "method": "DELETE", "name": "DELETE /orders -> example.com/shop.deleteOrder", "writes": 1}`
+	cron := `{"name": "example.com/shop.main", "reads": 2, "data_groups": ["orders", "prices"]}`
+	addTwice := strings.Replace(add, `"writes": 1`, `"writes": 2, "data_groups": ["orders"]`, 1)
+	getV2 := strings.Replace(get, "GET /orders", "GET /v2/orders", 1)
+	cronLess := strings.Replace(cron, `, "prices"`, "", 1)
+	doc := func(total int, processes ...string) string {
+		return `{"total_reads": ` + strconv.Itoa(total) + `, "processes": [` + strings.Join(processes, ", ") + `]}`
+	}
+	for _, c := range []struct {
+		desc string
+		a, b string
+		// ops is the number of operations, at most
+		ops int
+	}{
+		{"the same measurement", doc(1, get, add), doc(1, get, add), 0},
+		{"a process changed in place", doc(1, get, add), doc(1, get, addTwice), 2},
+		{"a process renamed", doc(1, get, add), doc(1, getV2, add), 1},
+		{"a process added first", doc(1, get), doc(1, add, get), 1},
+		{"a process added last", doc(1, get), doc(1, get, add), 1},
+		{"a process removed", doc(1, get, del, add), doc(1, get, add), 1},
+		{"the processes reversed", doc(3, get, add, del, cron), doc(3, cron, del, add, get), 3},
+		{"moved and changed", doc(3, get, add, cron), doc(2, cronLess, get, addTwice), 5},
+		{"all replaced", doc(1, get), doc(2, cron, del), 4},
+		{"none left", doc(1, get, add), doc(0), 3},
+		{"keys escaped", `{"a/b": 1, "a~b": 2, "processes": []}`, `{"a/b": 2, "a~c": 2, "processes": [` + get + `]}`, 4},
+		{"a member added and removed", `{"grade": "heuristic", "processes": []}`, `{"total_reads": 0, "processes": []}`, 2},
+	} {
+		dir := t.TempDir()
+		a, b, patch := dir+"/a.json", dir+"/b.json", dir+"/patch.json"
+		if err := os.WriteFile(a, []byte(c.a), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(b, []byte(c.b), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		n, err := writePatch(patch, a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ops []patchOp
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&ops); err != nil {
+			t.Fatal(err)
+		}
+		if n != len(ops) || n > c.ops {
+			t.Errorf("%s: %d operations, reported as %d, want at most %d:\n%s", c.desc, len(ops), n, c.ops, data)
+		}
+		if got, want := applyPatch(t, decodeJSON(t, c.a), ops), decodeJSON(t, c.b); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: the patch turns a.json into\n%v\nnot into b.json\n%v\n%s", c.desc, got, want, data)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+)
+
+// Ports of hexagonal architectures. The domain of a ports-and-adapters code
+// base calls interfaces it declares, such as OrderRepository, implemented by
+// adapters doing the actual storage or messaging. A port declared in the
+// -config file classifies the calls of its methods at the domain boundary,
+// and the adapters are not traversed from these calls, so that the storage
+// calls behind a port call (resolved with -ptr) are not counted twice.
+
+// portInterface declares a port: an interface whose method calls are movements.
+type portInterface struct {
+	Package   string `json:"package"`
+	Interface string `json:"interface"`
+	// DataGroup is the data group of the port's movements; it defaults to the
+	// interface name without a Repository, Repo, Store, Gateway or Port suffix.
+	DataGroup string `json:"data_group,omitempty"`
+	// Methods give the movement types (E, X, R or W) of the port's methods;
+	// the others are classified from their names (Find reads, Save writes).
+	Methods map[string]string `json:"methods,omitempty"`
+
+	// entities is set for discovered repositories: the data group of a
+	// method is the entity it takes or returns.
+	entities bool
+}
+
+// portSuffixes are the name suffixes dropped from a port for its data group.
+var portSuffixes = []string{"Repository", "Repo", "Store", "Gateway", "Port"}
+
+// checkPorts validates the -config ports.
+func checkPorts(ports []portInterface) error {
+	for _, p := range ports {
+		if p.Package == "" || p.Interface == "" {
+			return fmt.Errorf("port %s.%s: package and interface are required", p.Package, p.Interface)
+		}
+		for name, m := range p.Methods {
+			switch m {
+			case MovementEntry, MovementExit, MovementRead, MovementWrite:
+			default:
+				return fmt.Errorf("port %s.%s: method %s: movement must be E, X, R or W, not %q", p.Package, p.Interface, name, m)
+			}
+		}
+	}
+	return nil
+}
+
+// port returns the port declared for the interface of method m, or nil.
+func (s settings) port(m *types.Func) *portInterface {
+	if m == nil || m.Pkg() == nil {
+		return nil
+	}
+	recv := m.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	name := namedTypeName(recv.Type())
+	for i := range s.Ports {
+		if p := &s.Ports[i]; p.Package == m.Pkg().Path() && p.Interface == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// portMovement returns the movement type and data group of a call of the
+// port method m, or "" if m is not a method of a port or not a movement.
+func (s settings) portMovement(m *types.Func) (typ, group string) {
+	p := s.port(m)
+	if p == nil {
+		return "", ""
+	}
+	typ = p.Methods[m.Name()]
+	if typ == "" {
+		typ = guessMovement(m.Name())
+	}
+	if typ == "" {
+		return "", ""
+	}
+	group = p.DataGroup
+	if group == "" && p.entities {
+		group = methodEntity(m)
+	}
+	if group == "" {
+		group = p.Interface
+		for _, suffix := range portSuffixes {
+			if g, ok := strings.CutSuffix(p.Interface, suffix); ok && g != "" {
+				group = g
+				break
+			}
+		}
+	}
+	return typ, group
+}
+
+// withoutPortCalls drops the edges of the port calls from the callgraph cg:
+// their movements are counted at the call, not again in the adapters
+// implementing the port. Direct calls of an adapter still reach it.
+func (s settings) withoutPortCalls(cg *callgraph.Graph) {
+	for _, n := range cg.Nodes {
+		kept := n.Out[:0]
+		for _, e := range n.Out {
+			if e.Site != nil && e.Site.Common().IsInvoke() && s.port(e.Site.Common().Method) != nil {
+				continue
+			}
+			kept = append(kept, e)
+		}
+		n.Out = kept
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Query subcommands answer questions about a measurement from the traversal
+// index instead of printing the measurement itself.
+
+// runAffected implements "affected <file[:line]> [root]": it lists the
+// functional processes whose call graph includes the function at that
+// position, or any function of the file when no line is given.
+func runAffected(args []string) {
+	fs := flag.NewFlagSet("affected", flag.ExitOnError)
+	var cfg analysisConfig
+	cfg.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s affected [flags] <file[:line]> [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	file, line, err := parsePosition(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("affected: %v", err)
+	}
+	root := "."
+	if fs.NArg() == 2 {
+		root = fs.Arg(1)
+	}
+
+	a := analyze(root, cfg)
+	g := newCallGraph(a.entries, a.succ)
+	affected := affectedEntries(g, a.entries, atPosition(file, line))
+	if len(affected) == 0 {
+		log.Printf("no functional process includes %s", fs.Arg(0))
+		return
+	}
+	for _, fn := range a.entries {
+		if affected[fn] {
+			fmt.Printf("%s\t%s\n", processName(fn, a.entryPoints[fn], a.entryPoints), fn.String())
+		}
+	}
+}
+
+// runCallers implements "callers <callee> [root]": for every functional process
+// it prints the shortest call chain from the entry function to each call site
+// classified with that callee. The callee is given as in the detailed output
+// (os.WriteFile, (*database/sql.DB).Exec) or by its trailing name (Exec).
+func runCallers(args []string) {
+	fs := flag.NewFlagSet("callers", flag.ExitOnError)
+	var cfg analysisConfig
+	cfg.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s callers [flags] <callee> [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	callee := fs.Arg(0)
+	root := "."
+	if fs.NArg() == 2 {
+		root = fs.Arg(1)
+	}
+
+	a := analyze(root, cfg)
+	g := newCallGraph(a.entries, a.succ)
+	// sites maps the graph index of each function to its matching movements.
+	sites := map[int32][]Movement{}
+	for v, fn := range g.funcs {
+		id, ok := a.localCounts.id(fn)
+		if !ok {
+			continue
+		}
+		for _, m := range a.localCounts.get(id).Movements {
+			if m.Callee == callee || strings.HasSuffix(m.Callee, "."+callee) {
+				sites[int32(v)] = append(sites[int32(v)], m)
+			}
+		}
+	}
+	found := false
+	for _, fn := range a.entries {
+		parent := shortestPaths(g, g.index[fn])
+		printed := false
+		for v := range g.funcs {
+			ms := sites[int32(v)]
+			if len(ms) == 0 || parent[v] == -2 {
+				continue
+			}
+			if !printed {
+				fmt.Println(processName(fn, a.entryPoints[fn], a.entryPoints))
+				printed, found = true, true
+			}
+			chain := callChain(g, parent, int32(v))
+			for _, m := range ms {
+				fmt.Printf("  %s [%s %s at %s]\n", chain, m.Type, m.Callee, m.Pos)
+			}
+		}
+	}
+	if !found {
+		log.Printf("no functional process calls %s", callee)
+	}
+}
+
+// shortestPaths runs a breadth-first search from root and returns the parent
+// of every function on a shortest path from root: -1 for root itself and -2
+// for functions that are not reachable.
+func shortestPaths(g *callGraph, root int32) []int32 {
+	parent := make([]int32, len(g.funcs))
+	for i := range parent {
+		parent[i] = -2
+	}
+	parent[root] = -1
+	queue := []int32{root}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range g.succs[v] {
+			if parent[w] == -2 {
+				parent[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return parent
+}
+
+// callChain renders the path from the search root to v.
+func callChain(g *callGraph, parent []int32, v int32) string {
+	var names []string
+	for ; v >= 0; v = parent[v] {
+		names = append(names, g.funcs[v].String())
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, " -> ")
+}
+
+// parsePosition parses "file[:line]" into an absolute file name and a line, 0 if absent.
+func parsePosition(pos string) (file string, line int, err error) {
+	if i := strings.LastIndex(pos, ":"); i > 0 {
+		if n, err := strconv.Atoi(pos[i+1:]); err == nil {
+			pos, line = pos[:i], n
+		}
+	}
+	file, err = filepath.Abs(pos)
+	return file, line, err
+}
+
+// atPosition returns a seed matching the functions whose body spans line of
+// file; every function of the file matches when line is 0.
+func atPosition(file string, line int) func(*ssa.Function) bool {
+	return func(fn *ssa.Function) bool {
+		syn := fn.Syntax()
+		if syn == nil {
+			return false
+		}
+		start, end := fn.Prog.Fset.Position(syn.Pos()), fn.Prog.Fset.Position(syn.End())
+		if filepath.Clean(start.Filename) != file {
+			return false
+		}
+		return line == 0 || start.Line <= line && line <= end.Line
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Redaction. Data groups are named after constant arguments, so a SQL
+// statement or a URL captured from the code may carry a password or a token.
+// Before any sink writes the output, the text matching a redaction pattern is
+// replaced: the built-in patterns cover credentials in URLs and key=value
+// pairs, and the redact patterns of the -config file add more. A pattern with
+// a capture group only redacts the group, keeping the key it matched.
+
+// redacted replaces the redacted text.
+const redacted = "[REDACTED]"
+
+// builtinRedactions are the patterns always redacted.
+var builtinRedactions = []*regexp.Regexp{
+	// the password of the user info of a URL: postgres://app:secret@db/app
+	regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]*:([^@\s/]+)@`),
+	// password=secret, api_key: "secret", token='secret'
+	regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["']?([^\s"'&;,]+)`),
+}
+
+// parseRedactions compiles the redact patterns of the -config file.
+func parseRedactions(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %v", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// redact replaces the secrets in the names and data groups of out.
+func (out *Output) redact(patterns []*regexp.Regexp) {
+	patterns = append(builtinRedactions[:len(builtinRedactions):len(builtinRedactions)], patterns...)
+	r := func(s string) string { return redactText(s, patterns) }
+	for i := range out.Processes {
+		pr := &out.Processes[i]
+		pr.Name = r(pr.Name)
+		pr.Trigger = r(pr.Trigger)
+		for j := range pr.Invokes {
+			pr.Invokes[j] = r(pr.Invokes[j])
+		}
+		for j := range pr.DataGroups {
+			pr.DataGroups[j] = r(pr.DataGroups[j])
+		}
+		for j := range pr.Movements {
+			pr.Movements[j].DataGroup = r(pr.Movements[j].DataGroup)
+		}
+	}
+	for i := range out.Chains {
+		for j := range out.Chains[i].Processes {
+			out.Chains[i].Processes[j] = r(out.Chains[i].Processes[j])
+		}
+	}
+}
+
+// redactText replaces the matches of patterns in s, or their first capture
+// group if they have one.
+func redactText(s string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllLiteralString(s, redacted)
+			continue
+		}
+		var b []byte
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+			if m[2] < 0 {
+				continue
+			}
+			b = append(b, s[last:m[2]]...)
+			b = append(b, redacted...)
+			last = m[3]
+		}
+		if b != nil {
+			s = string(append(b, s[last:]...))
+		}
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Redis support. go-redis has a method per command (Get, HSet), returning a
+// *XxxCmd; redigo sends commands by name, as in conn.Do("GET", key). Both are
+// classified from the command, and the data group is the key, or its prefix
+// up to the first ':' as keys are namespaced ("session:42" is a session).
+
+var (
+	// goRedisPkgPaths are the import paths of go-redis.
+	goRedisPkgPaths = []string{
+		"github.com/redis/go-redis/v9",
+		"github.com/go-redis/redis/v8",
+		"github.com/go-redis/redis/v7",
+		"github.com/go-redis/redis",
+	}
+	// redigoPkgPaths are the import paths of redigo.
+	redigoPkgPaths = []string{
+		"github.com/gomodule/redigo/redis",
+		"github.com/garyburd/redigo/redis",
+	}
+
+	// redisCommands are the commands moving data, by name: R for those
+	// returning stored data, W for those only storing or deleting it.
+	redisCommands = map[string]string{
+		"GET": MovementRead, "MGET": MovementRead, "GETRANGE": MovementRead, "STRLEN": MovementRead,
+		"GETDEL": MovementRead, "GETEX": MovementRead, "GETSET": MovementRead,
+		"HGET": MovementRead, "HMGET": MovementRead, "HGETALL": MovementRead, "HKEYS": MovementRead,
+		"HVALS": MovementRead, "HLEN": MovementRead, "HEXISTS": MovementRead, "HSCAN": MovementRead,
+		"LRANGE": MovementRead, "LINDEX": MovementRead, "LLEN": MovementRead, "LPOP": MovementRead,
+		"RPOP": MovementRead, "BLPOP": MovementRead, "BRPOP": MovementRead,
+		"SMEMBERS": MovementRead, "SISMEMBER": MovementRead, "SCARD": MovementRead, "SSCAN": MovementRead,
+		"SPOP": MovementRead, "SRANDMEMBER": MovementRead,
+		"ZRANGE": MovementRead, "ZRANGEBYSCORE": MovementRead, "ZREVRANGE": MovementRead,
+		"ZREVRANGEBYSCORE": MovementRead, "ZSCORE": MovementRead, "ZRANK": MovementRead,
+		"ZCARD": MovementRead, "ZCOUNT": MovementRead, "ZSCAN": MovementRead,
+		"EXISTS": MovementRead, "KEYS": MovementRead, "SCAN": MovementRead, "TTL": MovementRead,
+		"PTTL": MovementRead, "TYPE": MovementRead,
+
+		"SET": MovementWrite, "SETEX": MovementWrite, "SETNX": MovementWrite, "SETXX": MovementWrite,
+		"PSETEX": MovementWrite, "MSET": MovementWrite, "MSETNX": MovementWrite, "SETRANGE": MovementWrite,
+		"APPEND": MovementWrite, "INCR": MovementWrite, "INCRBY": MovementWrite, "INCRBYFLOAT": MovementWrite,
+		"DECR": MovementWrite, "DECRBY": MovementWrite,
+		"HSET": MovementWrite, "HSETNX": MovementWrite, "HMSET": MovementWrite, "HDEL": MovementWrite,
+		"HINCRBY": MovementWrite, "HINCRBYFLOAT": MovementWrite,
+		"LPUSH": MovementWrite, "RPUSH": MovementWrite, "LPUSHX": MovementWrite, "RPUSHX": MovementWrite,
+		"LSET": MovementWrite, "LREM": MovementWrite, "LTRIM": MovementWrite, "LINSERT": MovementWrite,
+		"SADD": MovementWrite, "SREM": MovementWrite, "ZADD": MovementWrite, "ZREM": MovementWrite,
+		"ZINCRBY": MovementWrite, "ZREMRANGEBYSCORE": MovementWrite, "ZREMRANGEBYRANK": MovementWrite,
+		"DEL": MovementWrite, "UNLINK": MovementWrite, "EXPIRE": MovementWrite, "EXPIREAT": MovementWrite,
+		"PEXPIRE": MovementWrite, "PEXPIREAT": MovementWrite, "PERSIST": MovementWrite,
+		"RENAME": MovementWrite,
+	}
+)
+
+// isRedisClient reports whether fn belongs to go-redis or redigo; only their
+// commands move data, not the methods of replies such as (*StringCmd).Scan.
+func isRedisClient(fn *ssa.Function) bool {
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return false
+	}
+	p := fn.Pkg.Pkg.Path()
+	return inPaths(p, goRedisPkgPaths) || inPaths(p, redigoPkgPaths)
+}
+
+// redisCommand returns the movement type and data group of a Redis command
+// sent by call, a go-redis command method or a redigo Do, DoContext or Send.
+func redisCommand(call *ssa.CallCommon) (typ, group string) {
+	var pkgPath, name string
+	var sig *types.Signature
+	if call.IsInvoke() {
+		if call.Method.Pkg() == nil {
+			return "", ""
+		}
+		pkgPath, name, sig = call.Method.Pkg().Path(), call.Method.Name(), call.Method.Type().(*types.Signature)
+	} else if sc := call.StaticCallee(); sc != nil && sc.Pkg != nil && sc.Pkg.Pkg != nil {
+		pkgPath, name, sig = sc.Pkg.Pkg.Path(), sc.Name(), sc.Signature
+	} else {
+		return "", ""
+	}
+	// the constant command names and keys, also among the ...any arguments
+	var names []string
+	for _, arg := range call.Args {
+		args := []ssa.Value{arg}
+		if _, ok := arg.(*ssa.Slice); ok {
+			args = variadicArgs(arg)
+		}
+		for _, a := range args {
+			if s, ok := constString(a); ok {
+				names = append(names, s)
+			} else if s := sprintfFormat(a); s != "" {
+				names = append(names, s)
+			}
+		}
+	}
+	var command string
+	switch {
+	case inPaths(pkgPath, redigoPkgPaths):
+		if name != "Do" && name != "DoContext" && name != "Send" {
+			return "", ""
+		}
+		if len(names) > 0 {
+			command, names = names[0], names[1:]
+		}
+	case inPaths(pkgPath, goRedisPkgPaths):
+		// command methods return a *XxxCmd; Do takes the command by name
+		if sig.Results().Len() != 1 {
+			return "", ""
+		}
+		ptr, ok := sig.Results().At(0).Type().(*types.Pointer)
+		if !ok {
+			return "", ""
+		}
+		if named, ok := ptr.Elem().(*types.Named); !ok || !strings.HasSuffix(named.Obj().Name(), "Cmd") {
+			return "", ""
+		}
+		command = name
+		if name == "Do" {
+			command = ""
+			if len(names) > 0 {
+				command, names = names[0], names[1:]
+			}
+		}
+	default:
+		return "", ""
+	}
+	typ = redisCommands[strings.ToUpper(command)]
+	if typ == "" {
+		return "", ""
+	}
+	if len(names) > 0 {
+		group, _, _ = strings.Cut(names[0], ":")
+	}
+	return typ, group
+}
+
+// sprintfFormat returns the constant text of the format of a fmt.Sprintf
+// call up to the first verb, as in fmt.Sprintf("user:%d", id), or "".
+func sprintfFormat(v ssa.Value) string {
+	call, ok := v.(*ssa.Call)
+	if !ok {
+		return ""
+	}
+	sc := call.Call.StaticCallee()
+	if sc == nil || sc.Pkg == nil || sc.Pkg.Pkg.Path() != "fmt" || sc.Name() != "Sprintf" || len(call.Call.Args) == 0 {
+		return ""
+	}
+	format, ok := constString(call.Call.Args[0])
+	if !ok {
+		return ""
+	}
+	format, _, _ = strings.Cut(format, "%")
+	return format
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Repository discovery. Clean-architecture code bases inject their storage
+// behind repository interfaces (UserStore.Get, OrderRepository.Save), whose
+// calls static mode cannot follow. An interface of the analyzed code is taken
+// for a repository when its methods are named like reads or writes, take or
+// return an entity type of the code, and an implementation of it in the code
+// performs storage calls. It is then classified like a declared port, at its
+// call sites, with the entity of each method as data group.
+
+// maxStorageDepth bounds the static calls followed from a repository method
+// to its storage calls.
+const maxStorageDepth = 3
+
+// discoverRepositories returns the repository interfaces of pkgs as ports.
+func discoverRepositories(prog *ssa.Program, pkgs []*ssa.Package) []portInterface {
+	var ifaces []*types.TypeName
+	var impls []types.Type
+	for _, pkg := range pkgs {
+		for _, mem := range pkg.Members {
+			t, ok := mem.(*ssa.Type)
+			if !ok {
+				continue
+			}
+			if iface, ok := t.Type().Underlying().(*types.Interface); ok {
+				if isRepositoryShaped(iface) {
+					ifaces = append(ifaces, t.Object().(*types.TypeName))
+				}
+			} else if named, ok := t.Type().(*types.Named); ok && named.TypeParams().Len() == 0 {
+				impls = append(impls, types.NewPointer(named))
+			}
+		}
+	}
+	var ports []portInterface
+	for _, obj := range ifaces {
+		iface := obj.Type().Underlying().(*types.Interface)
+		for _, impl := range impls {
+			if types.Implements(impl, iface) && performsStorage(prog, impl, iface) {
+				ports = append(ports, portInterface{Package: obj.Pkg().Path(), Interface: obj.Name(), entities: true})
+				break
+			}
+		}
+	}
+	return ports
+}
+
+// isRepositoryShaped reports whether a method of iface is named like a read
+// or write and takes or returns an entity: a struct type declared in the
+// interface's package or another package of the code.
+func isRepositoryShaped(iface *types.Interface) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if typ := guessMovement(m.Name()); typ == MovementRead || typ == MovementWrite {
+			if methodEntity(m) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// methodEntity returns the name of the first entity type among the
+// parameters and results of m, or "". Entities are named struct types, also
+// behind pointers and slices, declared outside the standard library.
+func methodEntity(m *types.Func) string {
+	sig := m.Type().(*types.Signature)
+	for _, tuple := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tuple.Len(); i++ {
+			if name := entityName(tuple.At(i).Type()); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// entityName returns the name of the entity type t, or "".
+func entityName(t types.Type) string {
+	for {
+		switch tt := t.(type) {
+		case *types.Pointer:
+			t = tt.Elem()
+			continue
+		case *types.Slice:
+			t = tt.Elem()
+			continue
+		case *types.Named:
+			if _, ok := tt.Underlying().(*types.Struct); !ok || tt.Obj().Pkg() == nil || isStdlib(tt.Obj().Pkg().Path()) {
+				return ""
+			}
+			return tt.Obj().Name()
+		}
+		return ""
+	}
+}
+
+// performsStorage reports whether a method of impl implementing iface
+// reaches a storage call within maxStorageDepth static calls.
+func performsStorage(prog *ssa.Program, impl types.Type, iface *types.Interface) bool {
+	mset := prog.MethodSets.MethodSet(impl)
+	seen := map[*ssa.Function]bool{}
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if sel := mset.Lookup(m.Pkg(), m.Name()); sel != nil {
+			if reachesStorage(prog.MethodValue(sel), maxStorageDepth, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reachesStorage reports whether fn calls storage within depth static calls.
+func reachesStorage(fn *ssa.Function, depth int, seen map[*ssa.Function]bool) bool {
+	if fn == nil || depth < 0 || seen[fn] {
+		return false
+	}
+	seen[fn] = true
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			common := call.Common()
+			if isStorageCall(common) {
+				return true
+			}
+			if sc := common.StaticCallee(); sc != nil && reachesStorage(sc, depth-1, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStorageCall reports whether call reads or writes persistent storage by
+// the built-in classification.
+func isStorageCall(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return matchesMethodTable(call.Method, readFuncs) || matchesMethodTable(call.Method, writeFuncs)
+	}
+	sc := call.StaticCallee()
+	if sc == nil {
+		return false
+	}
+	if typ, _ := entMovement(sc); typ != "" {
+		return true
+	}
+	if typ, _ := redisCommand(call); typ != "" {
+		return true
+	}
+	return matchesTable(sc, readFuncs) || matchesTable(sc, writeFuncs) || isCloudStorageClient(sc)
+}
+
+// isStdlib reports whether import path p is of the standard library.
+func isStdlib(p string) bool {
+	return !strings.Contains(strings.SplitN(p, "/", 2)[0], ".")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"filippo.io/age"
+)
+
+// ReqIF (OMG Requirements Interchange Format 1.2) export. Every functional
+// process becomes a "COSMIC Functional Process" spec object and every data
+// movement a "COSMIC Data Movement" spec object nested below its process in
+// the specification hierarchy. Identifiers are derived from the process ID
+// and, for a movement, from its type, callee and data group, so re-imports
+// update the existing ALM objects instead of duplicating them, even once the
+// process is renamed or movements are added before others.
+
+const reqifNamespace = "http://www.omg.org/spec/ReqIF/20110401/reqif.xsd"
+
+// reqifAttr is an attribute definition of one of the exported spec object types.
+type reqifAttr struct {
+	id, name string
+	integer  bool
+}
+
+var (
+	reqifProcessAttrs = []reqifAttr{
+		{"ad-process-name", "Name", false},
+		{"ad-process-source", "Source", false},
+		{"ad-process-trigger", "Triggering event", false},
+		{"ad-process-entries", "Entries", true},
+		{"ad-process-exits", "Exits", true},
+		{"ad-process-reads", "Reads", true},
+		{"ad-process-writes", "Writes", true},
+		{"ad-process-cfp", "CFP", true},
+	}
+	reqifMovementAttrs = []reqifAttr{
+		{"ad-movement-type", "Movement type", false},
+		{"ad-movement-datagroup", "Data group", false},
+		{"ad-movement-callee", "Callee", false},
+		{"ad-movement-location", "Location", false},
+	}
+)
+
+// reqifSink collects the measurement and writes it as ReqIF on Close, since
+// the document lists all spec objects before the specification hierarchy.
+type reqifSink struct {
+	path       string
+	recipients []age.Recipient
+	out        Output
+}
+
+// NewReqIFSink returns a Sink writing a ReqIF document to path, encrypted to
+// the age recipients if any are given.
+func NewReqIFSink(path string, recipients ...age.Recipient) Sink {
+	return &reqifSink{path: path, recipients: recipients}
+}
+
+func (s *reqifSink) WriteHeader(h Header) error {
+	s.out = h.output()
+	return nil
+}
+
+func (s *reqifSink) WriteProcess(pr ProcessReport) error {
+	pr.Movements = nil
+	s.out.Processes = append(s.out.Processes, pr)
+	return nil
+}
+
+func (s *reqifSink) WriteMovement(_ ProcessReport, m Movement) error {
+	last := &s.out.Processes[len(s.out.Processes)-1]
+	last.Movements = append(last.Movements, m)
+	return nil
+}
+
+func (s *reqifSink) Close() error {
+	return writeReqIF(s.path, s.out, s.recipients)
+}
+
+// writeReqIF writes the measurement as a ReqIF document to path.
+func writeReqIF(path string, out Output, recipients []age.Recipient) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, "<REQ-IF xmlns=%q>\n", reqifNamespace)
+	b.WriteString("  <THE-HEADER>\n    <REQ-IF-HEADER IDENTIFIER=\"header\">\n")
+	fmt.Fprintf(&b, "      <CREATION-TIME>%s</CREATION-TIME>\n", now)
+	b.WriteString("      <REQ-IF-TOOL-ID>go-cosmic-ssa</REQ-IF-TOOL-ID>\n")
+	b.WriteString("      <REQ-IF-VERSION>1.0</REQ-IF-VERSION>\n")
+	b.WriteString("      <SOURCE-TOOL-ID>go-cosmic-ssa</SOURCE-TOOL-ID>\n")
+	b.WriteString("      <TITLE>COSMIC functional size measurement</TITLE>\n")
+	b.WriteString("    </REQ-IF-HEADER>\n  </THE-HEADER>\n")
+	b.WriteString("  <CORE-CONTENT>\n    <REQ-IF-CONTENT>\n")
+
+	b.WriteString("      <DATATYPES>\n")
+	fmt.Fprintf(&b, "        <DATATYPE-DEFINITION-STRING IDENTIFIER=\"dt-string\" LONG-NAME=\"String\" LAST-CHANGE=%q MAX-LENGTH=\"32000\"/>\n", now)
+	fmt.Fprintf(&b, "        <DATATYPE-DEFINITION-INTEGER IDENTIFIER=\"dt-count\" LONG-NAME=\"Count\" LAST-CHANGE=%q MIN=\"0\" MAX=\"2147483647\"/>\n", now)
+	b.WriteString("      </DATATYPES>\n")
+
+	b.WriteString("      <SPEC-TYPES>\n")
+	writeReqIFObjectType(&b, "sot-process", "COSMIC Functional Process", reqifProcessAttrs, now)
+	writeReqIFObjectType(&b, "sot-movement", "COSMIC Data Movement", reqifMovementAttrs, now)
+	fmt.Fprintf(&b, "        <SPECIFICATION-TYPE IDENTIFIER=\"st-measurement\" LONG-NAME=\"COSMIC Measurement\" LAST-CHANGE=%q/>\n", now)
+	b.WriteString("      </SPEC-TYPES>\n")
+
+	b.WriteString("      <SPEC-OBJECTS>\n")
+	for _, pr := range out.Processes {
+		writeReqIFObject(&b, reqifID("p", pr.ID), "sot-process", reqifProcessAttrs, []string{
+			pr.Name, pr.Source, pr.Trigger,
+			fmt.Sprint(pr.Entries), fmt.Sprint(pr.Exits), fmt.Sprint(pr.Reads), fmt.Sprint(pr.Writes),
+			fmt.Sprint(pr.Entries + pr.Exits + pr.Reads + pr.Writes),
+		}, now)
+		ids := reqifMovementIDs(pr)
+		for i, m := range pr.Movements {
+			writeReqIFObject(&b, ids[i], "sot-movement", reqifMovementAttrs, []string{
+				m.Type, m.DataGroup, m.Callee, m.Pos,
+			}, now)
+		}
+	}
+	b.WriteString("      </SPEC-OBJECTS>\n")
+
+	b.WriteString("      <SPECIFICATIONS>\n")
+	fmt.Fprintf(&b, "        <SPECIFICATION IDENTIFIER=\"spec-measurement\" LONG-NAME=\"COSMIC Measurement\" LAST-CHANGE=%q>\n", now)
+	b.WriteString("          <TYPE><SPECIFICATION-TYPE-REF>st-measurement</SPECIFICATION-TYPE-REF></TYPE>\n")
+	b.WriteString("          <CHILDREN>\n")
+	for _, pr := range out.Processes {
+		pid := reqifID("p", pr.ID)
+		fmt.Fprintf(&b, "            <SPEC-HIERARCHY IDENTIFIER=\"h-%s\" LAST-CHANGE=%q>\n", pid, now)
+		fmt.Fprintf(&b, "              <OBJECT><SPEC-OBJECT-REF>%s</SPEC-OBJECT-REF></OBJECT>\n", pid)
+		if len(pr.Movements) > 0 {
+			b.WriteString("              <CHILDREN>\n")
+			for _, mid := range reqifMovementIDs(pr) {
+				fmt.Fprintf(&b, "                <SPEC-HIERARCHY IDENTIFIER=\"h-%s\" LAST-CHANGE=%q>\n", mid, now)
+				fmt.Fprintf(&b, "                  <OBJECT><SPEC-OBJECT-REF>%s</SPEC-OBJECT-REF></OBJECT>\n", mid)
+				b.WriteString("                </SPEC-HIERARCHY>\n")
+			}
+			b.WriteString("              </CHILDREN>\n")
+		}
+		b.WriteString("            </SPEC-HIERARCHY>\n")
+	}
+	b.WriteString("          </CHILDREN>\n        </SPECIFICATION>\n      </SPECIFICATIONS>\n")
+	b.WriteString("    </REQ-IF-CONTENT>\n  </CORE-CONTENT>\n</REQ-IF>\n")
+
+	return writeEncrypted(path, b.Bytes(), recipients)
+}
+
+// writeReqIFObjectType writes a SPEC-OBJECT-TYPE with the given attribute definitions.
+func writeReqIFObjectType(b *bytes.Buffer, id, name string, attrs []reqifAttr, now string) {
+	fmt.Fprintf(b, "        <SPEC-OBJECT-TYPE IDENTIFIER=%q LONG-NAME=%q LAST-CHANGE=%q>\n", id, name, now)
+	b.WriteString("          <SPEC-ATTRIBUTES>\n")
+	for _, a := range attrs {
+		kind, dt := "STRING", "dt-string"
+		if a.integer {
+			kind, dt = "INTEGER", "dt-count"
+		}
+		fmt.Fprintf(b, "            <ATTRIBUTE-DEFINITION-%s IDENTIFIER=%q LONG-NAME=%q LAST-CHANGE=%q>\n", kind, a.id, a.name, now)
+		fmt.Fprintf(b, "              <TYPE><DATATYPE-DEFINITION-%s-REF>%s</DATATYPE-DEFINITION-%s-REF></TYPE>\n", kind, dt, kind)
+		fmt.Fprintf(b, "            </ATTRIBUTE-DEFINITION-%s>\n", kind)
+	}
+	b.WriteString("          </SPEC-ATTRIBUTES>\n        </SPEC-OBJECT-TYPE>\n")
+}
+
+// writeReqIFObject writes a SPEC-OBJECT; values are given in attribute order.
+func writeReqIFObject(b *bytes.Buffer, id, typ string, attrs []reqifAttr, values []string, now string) {
+	fmt.Fprintf(b, "        <SPEC-OBJECT IDENTIFIER=%q LAST-CHANGE=%q>\n", id, now)
+	b.WriteString("          <VALUES>\n")
+	for i, a := range attrs {
+		kind := "STRING"
+		if a.integer {
+			kind = "INTEGER"
+		}
+		fmt.Fprintf(b, "            <ATTRIBUTE-VALUE-%s THE-VALUE=\"%s\">\n", kind, reqifEscape(values[i]))
+		fmt.Fprintf(b, "              <DEFINITION><ATTRIBUTE-DEFINITION-%s-REF>%s</ATTRIBUTE-DEFINITION-%s-REF></DEFINITION>\n", kind, a.id, kind)
+		fmt.Fprintf(b, "            </ATTRIBUTE-VALUE-%s>\n", kind)
+	}
+	b.WriteString("          </VALUES>\n")
+	fmt.Fprintf(b, "          <TYPE><SPEC-OBJECT-TYPE-REF>%s</SPEC-OBJECT-TYPE-REF></TYPE>\n", typ)
+	b.WriteString("        </SPEC-OBJECT>\n")
+}
+
+// reqifMovementIDs returns the identifiers of the movements of pr, in order.
+// A movement is identified by its process, type, callee and data group, and
+// by its occurrence among the movements of the process sharing them.
+func reqifMovementIDs(pr ProcessReport) []string {
+	seen := map[string]int{}
+	var ids []string
+	for _, m := range pr.Movements {
+		key := pr.ID + "|" + m.Type + "|" + m.Callee + "|" + m.DataGroup
+		ids = append(ids, reqifID("m", fmt.Sprintf("%s#%d", key, seen[key])))
+		seen[key]++
+	}
+	return ids
+}
+
+// reqifID derives a stable XML identifier from a key.
+func reqifID(prefix, key string) string {
+	sum := sha1.Sum([]byte(key))
+	return prefix + "-" + hex.EncodeToString(sum[:10])
+}
+
+// reqifEscape escapes s for use in an XML attribute value.
+func reqifEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
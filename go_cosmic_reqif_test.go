@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var reqifObject = regexp.MustCompile(`<SPEC-OBJECT IDENTIFIER="([^"]+)"`)
+
+// reqifObjects exports out as ReqIF and returns the identifiers of its spec
+// objects.
+func reqifObjects(t *testing.T, out Output) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.reqif")
+	if err := writeReqIF(path, out, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range reqifObject.FindAllStringSubmatch(string(data), -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+// TestReqIFStableIdentifiers checks that the identifiers of a process and of
+// its movements survive a rename of the process and a movement inserted
+// before them.
+func TestReqIFStableIdentifiers(t *testing.T) {
+	pr := ProcessReport{
+		Name: "GET /orders -> example.com/shop.getOrder", ID: "5e1f0c2a9b3d", Source: "example.com/shop.getOrder",
+		Movements: []Movement{
+			{Type: "E", Callee: "(*net/http.Request).FormValue"},
+			{Type: "R", DataGroup: "orders", Callee: "os.ReadFile"},
+			{Type: "R", DataGroup: "orders", Callee: "os.ReadFile"},
+			{Type: "X", Callee: "(net/http.ResponseWriter).Write"},
+		},
+	}
+	before := reqifObjects(t, Output{Processes: []ProcessReport{pr}})
+	if len(before) != 5 {
+		t.Fatalf("%d spec objects, want 5", len(before))
+	}
+
+	pr.Name = "GET /v2/orders -> example.com/shop.getOrder"
+	pr.Movements = append([]Movement{
+		{Type: "R", DataGroup: "orders", Callee: "os.ReadFile"},
+		{Type: "W", DataGroup: "audit", Callee: "os.WriteFile"},
+	}, pr.Movements...)
+	after := map[string]bool{}
+	for _, id := range reqifObjects(t, Output{Processes: []ProcessReport{pr}}) {
+		if after[id] {
+			t.Errorf("identifier %s is not unique", id)
+		}
+		after[id] = true
+	}
+	if len(after) != 7 {
+		t.Errorf("%d spec objects, want 7", len(after))
+	}
+	for _, id := range before {
+		if !after[id] {
+			t.Errorf("identifier %s changed", id)
+		}
+	}
+}
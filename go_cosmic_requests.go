@@ -0,0 +1,254 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// HTTP requests. The data a handler reads from its request (the body, form
+// and query values, headers, path parameters, a body bound to a struct) is
+// an Entry of its process, not a Read by name as (*url.URL).Query would be.
+// The request is one message however many attributes are read: its reads
+// are tagged TagRequest and count once per data group and process, named
+// after the type the body is decoded or bound into, or requestGroup.
+
+// TagRequest marks the reads of the request of a handler, which count once
+// per data group.
+const TagRequest = "request"
+
+// requestGroup is the data group of the request attributes other than a
+// decoded body.
+const requestGroup = "request"
+
+var (
+	// requestMethods are the methods reading the request, by package path:
+	// those of *http.Request and of the request contexts of web frameworks.
+	requestMethods = map[string]map[string]bool{
+		"net/http": {
+			"FormValue": true, "PostFormValue": true, "FormFile": true, "MultipartReader": true,
+			"ParseForm": true, "ParseMultipartForm": true, "Cookie": true, "Cookies": true,
+			"PathValue": true, "BasicAuth": true, "UserAgent": true, "Referer": true,
+		},
+		"github.com/gin-gonic/gin": {
+			"Param": true, "Query": true, "DefaultQuery": true, "GetQuery": true, "QueryArray": true, "QueryMap": true,
+			"PostForm": true, "DefaultPostForm": true, "GetPostForm": true, "PostFormArray": true, "PostFormMap": true,
+			"FormFile": true, "MultipartForm": true, "GetHeader": true, "Cookie": true, "GetRawData": true,
+			"Bind": true, "BindJSON": true, "BindXML": true, "BindQuery": true, "BindUri": true, "BindHeader": true,
+			"BindYAML": true, "BindWith": true, "ShouldBind": true, "ShouldBindJSON": true, "ShouldBindXML": true,
+			"ShouldBindQuery": true, "ShouldBindUri": true, "ShouldBindHeader": true, "ShouldBindYAML": true,
+			"ShouldBindWith": true, "ShouldBindBodyWith": true,
+		},
+		"github.com/labstack/echo/v4": {
+			"Param": true, "QueryParam": true, "QueryParams": true, "QueryString": true, "FormValue": true,
+			"FormParams": true, "FormFile": true, "MultipartForm": true, "Cookie": true, "Cookies": true, "Bind": true,
+		},
+		"github.com/gofiber/fiber/v2": {
+			"Params": true, "ParamsInt": true, "Query": true, "QueryInt": true, "QueryParser": true, "FormValue": true,
+			"FormFile": true, "MultipartForm": true, "Get": true, "Cookies": true, "Body": true, "BodyParser": true,
+		},
+	}
+	// requestFuncs are the functions reading the request they are given, by
+	// package path.
+	requestFuncs = map[string]map[string]bool{
+		"github.com/gorilla/mux":              {"Vars": true},
+		"github.com/go-chi/chi":               {"URLParam": true},
+		"github.com/go-chi/chi/v5":            {"URLParam": true},
+		"github.com/julienschmidt/httprouter": {"ParamsFromContext": true},
+	}
+	// bodyReaders are the functions reading the body they are given, by
+	// package path.
+	bodyReaders = map[string]map[string]bool{
+		"io":        {"ReadAll": true, "ReadFull": true, "ReadAtLeast": true, "Copy": true, "CopyN": true, "CopyBuffer": true},
+		"io/ioutil": {"ReadAll": true},
+	}
+	// requestFields are the fields of *http.Request whose methods read it
+	// (r.URL.Query(), r.Header.Get, r.Form.Get).
+	requestFields = map[string]bool{"URL": true, "Header": true, "Form": true, "PostForm": true, "MultipartForm": true}
+	// requestContexts are the request types of web frameworks, by package
+	// path, as their handlers declare them.
+	requestContexts = map[string]map[string]bool{
+		"net/http":                    {"Request": true},
+		"github.com/gin-gonic/gin":    {"Context": true},
+		"github.com/labstack/echo/v4": {"Context": true},
+		"github.com/gofiber/fiber/v2": {"Ctx": true},
+	}
+)
+
+// requestRead returns the data group of the request read by the call of
+// fn, or false if it reads no request.
+func requestRead(fn *ssa.Function, call *ssa.CallCommon) (string, bool) {
+	if fn.Pkg == nil {
+		return "", false
+	}
+	p, name := fn.Pkg.Pkg.Path(), fn.Name()
+	switch {
+	case fn.Signature.Recv() == nil:
+		if !requestFuncs[p][name] && !(bodyReaders[p][name] && readsRequestBody(call.Args)) {
+			return "", false
+		}
+	case requestMethods[p][name] && len(call.Args) > 0 && isRequestContext(call.Args[0].Type()):
+	case len(call.Args) > 0 && (requestField(call.Args[0]) || requestBody(call.Args[0], 0)):
+		// r.URL.Query(), r.Header.Get("X-Id"), json.NewDecoder(r.Body).Decode(&v)
+	default:
+		return "", false
+	}
+	if g := encodedGroup(fn, call); g != "" && (strings.Contains(name, "Bind") || strings.Contains(name, "Decode") || strings.HasSuffix(name, "Parser")) {
+		return g, true
+	}
+	return requestGroup, true
+}
+
+// requestInvoke returns the data group of the request read by the invoked
+// method of a request context interface (echo.Context), or false.
+func requestInvoke(call *ssa.CallCommon) (string, bool) {
+	m := call.Method
+	if m.Name() == "Read" && requestBody(call.Value, 0) {
+		// r.Body.Read(p)
+		return requestGroup, true
+	}
+	if m.Pkg() == nil || !requestMethods[m.Pkg().Path()][m.Name()] || !isRequestContext(call.Value.Type()) {
+		return "", false
+	}
+	if strings.Contains(m.Name(), "Bind") && len(call.Args) > 0 {
+		if g := entityName(unwrapInterface(call.Args[0]).Type()); g != "" {
+			return g, true
+		}
+	}
+	return requestGroup, true
+}
+
+// isRequestContext reports whether t is an *http.Request or the request
+// context of a web framework.
+func isRequestContext(t types.Type) bool {
+	named, ok := derefType(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && requestContexts[named.Obj().Pkg().Path()][named.Obj().Name()]
+}
+
+// requestField reports whether v is loaded from a field of an *http.Request
+// read through its methods.
+func requestField(v ssa.Value) bool {
+	switch vv := v.(type) {
+	case *ssa.UnOp:
+		if fa, ok := vv.X.(*ssa.FieldAddr); ok {
+			st := derefType(fa.X.Type())
+			return isNamedType(st, "net/http", "Request") && requestFields[st.Underlying().(*types.Struct).Field(fa.Field).Name()]
+		}
+	case *ssa.FieldAddr:
+		st := derefType(vv.X.Type())
+		return isNamedType(st, "net/http", "Request") && requestFields[st.Underlying().(*types.Struct).Field(vv.Field).Name()]
+	}
+	return false
+}
+
+// readsRequestBody reports whether a call reads the body of an HTTP request,
+// as io.ReadAll(r.Body) does.
+func readsRequestBody(args []ssa.Value) bool {
+	for _, arg := range args {
+		if requestBody(arg, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestBody reports whether v is the Body of an *http.Request or a reader
+// or decoder built on one.
+func requestBody(v ssa.Value, depth int) bool {
+	if depth > 4 {
+		return false
+	}
+	switch vv := v.(type) {
+	case *ssa.MakeInterface:
+		return requestBody(vv.X, depth+1)
+	case *ssa.ChangeInterface:
+		return requestBody(vv.X, depth+1)
+	case *ssa.UnOp:
+		fa, ok := vv.X.(*ssa.FieldAddr)
+		if !ok {
+			return false
+		}
+		st := derefType(fa.X.Type())
+		return isNamedType(st, "net/http", "Request") && st.Underlying().(*types.Struct).Field(fa.Field).Name() == "Body"
+	case *ssa.Call:
+		// bufio.NewReader(r.Body), json.NewDecoder(r.Body), http.MaxBytesReader(w, r.Body, n)
+		if sc := vv.Call.StaticCallee(); sc != nil && (strings.HasPrefix(sc.Name(), "New") || sc.Name() == "MaxBytesReader") {
+			for _, arg := range vv.Call.Args {
+				if requestBody(arg, depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// requestParams returns the names of the parameters of fd declared as a
+// request or request context, in file f measured from its syntax.
+func requestParams(f heuristicFile, fd *ast.FuncDecl) map[string]bool {
+	names := map[string]bool{}
+	for _, field := range fd.Type.Params.List {
+		t := field.Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		sel, ok := t.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || !requestContexts[f.imports[x.Name]][sel.Sel.Name] {
+			continue
+		}
+		for _, name := range field.Names {
+			names[name.Name] = true
+		}
+	}
+	return names
+}
+
+// readsRequestArg reports whether one of args, measured from syntax, is a
+// request parameter of fn, one of its fields or its body.
+func (fn *heuristicFunc) readsRequestArg(args []ast.Expr) bool {
+	for _, arg := range args {
+		if fn.readsRequest(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// readsRequest reports whether e, measured from syntax, is a request
+// parameter of fn, one of its fields or its body.
+func (fn *heuristicFunc) readsRequest(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return fn.requests[e.Name]
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		return ok && fn.requests[x.Name] && (requestFields[e.Sel.Name] || e.Sel.Name == "Body")
+	case *ast.CallExpr:
+		// json.NewDecoder(r.Body)
+		return len(e.Args) > 0 && fn.readsRequest(e.Args[0])
+	}
+	return false
+}
+
+// requestMethod reports whether the method name of x, a request measured
+// from syntax, reads it: a request method of the request itself, any
+// method of its fields and Decode of a decoder of its body.
+func requestMethod(x ast.Expr, name string) bool {
+	switch x.(type) {
+	case *ast.Ident:
+		for _, methods := range requestMethods {
+			if methods[name] {
+				return true
+			}
+		}
+		return false
+	case *ast.CallExpr:
+		return name == "Decode"
+	}
+	return true
+}
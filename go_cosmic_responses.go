@@ -0,0 +1,123 @@
+package main
+
+import (
+	"go/ast"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// HTTP responses. Writing the response of a handler is an Exit of its
+// process, whether through the http.ResponseWriter itself (Write,
+// WriteString, WriteHeader), through a helper writing into it (fmt.Fprintf,
+// io.Copy, http.Error) or through an encoder wrapping it (see streamMovement).
+// A response writer's Write is not a Write of storage, as the name heuristics
+// would have it. Bytes encoded by a Marshal are counted as the Exit of the
+// Marshal (see marshalExit) and not again where they are written. Measured
+// from syntax, the writers are the parameters declared as an
+// http.ResponseWriter.
+
+// responseMethods are the methods of a response writer writing the response.
+var responseMethods = map[string]bool{"Write": true, "WriteString": true, "WriteHeader": true}
+
+// responseHelpers are the functions writing into the response writer they
+// are given, by package path, with the index of the writer's argument.
+var responseHelpers = map[string]map[string]int{
+	"fmt": {"Fprint": 0, "Fprintf": 0, "Fprintln": 0},
+	"io":  {"WriteString": 0, "Copy": 0, "CopyN": 0, "CopyBuffer": 0},
+	"net/http": {
+		"Error": 0, "NotFound": 0, "Redirect": 0, "ServeContent": 0, "ServeFile": 0, "ServeFileFS": 0,
+	},
+}
+
+// writesResponse reports whether call writes an HTTP response: it invokes a
+// response method of a response writer or passes one to a helper.
+func writesResponse(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return responseMethods[call.Method.Name()] && isResponseWriter(call.Value.Type())
+	}
+	sc := call.StaticCallee()
+	if sc == nil || sc.Pkg == nil {
+		return false
+	}
+	if sc.Signature.Recv() != nil {
+		// the methods of a framework's own response writer
+		return responseMethods[sc.Name()] && len(call.Args) > 0 && isResponseWriter(call.Args[0].Type())
+	}
+	i, ok := responseHelpers[sc.Pkg.Pkg.Path()][sc.Name()]
+	return ok && i < len(call.Args) && streamOrigin(call.Args[i], 0) == streamResponse
+}
+
+// writesMarshalled reports whether call writes bytes returned by a Marshal,
+// directly or converted, as an argument or an element of its variadic
+// arguments.
+func writesMarshalled(call *ssa.CallCommon) bool {
+	for _, arg := range call.Args {
+		if marshalled(arg, 0) {
+			return true
+		}
+		if s, ok := arg.(*ssa.Slice); ok {
+			if alloc, ok := s.X.(*ssa.Alloc); ok && alloc.Referrers() != nil {
+				for _, ref := range *alloc.Referrers() {
+					ia, ok := ref.(*ssa.IndexAddr)
+					if !ok || ia.Referrers() == nil {
+						continue
+					}
+					for _, r := range *ia.Referrers() {
+						if st, ok := r.(*ssa.Store); ok && st.Addr == ia && marshalled(st.Val, 0) {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// marshalled reports whether v holds the bytes returned by a Marshal.
+func marshalled(v ssa.Value, depth int) bool {
+	if depth > 8 {
+		return false
+	}
+	switch vv := v.(type) {
+	case *ssa.Extract:
+		return marshalled(vv.Tuple, depth+1)
+	case *ssa.Convert:
+		return marshalled(vv.X, depth+1)
+	case *ssa.ChangeType:
+		return marshalled(vv.X, depth+1)
+	case *ssa.MakeInterface:
+		return marshalled(vv.X, depth+1)
+	case *ssa.Slice:
+		return marshalled(vv.X, depth+1)
+	case *ssa.Call:
+		return matchesTable(vv.Call.StaticCallee(), marshalFuncs)
+	}
+	return false
+}
+
+// writes reports whether e is a parameter of fn declared as an
+// http.ResponseWriter.
+func (fn *heuristicFunc) writes(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && fn.writers[id.Name]
+}
+
+// responseWriterParams returns the names of the parameters of fd declared as
+// an http.ResponseWriter, in file f measured from its syntax.
+func responseWriterParams(f heuristicFile, fd *ast.FuncDecl) map[string]bool {
+	names := map[string]bool{}
+	for _, field := range fd.Type.Params.List {
+		sel, ok := field.Type.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "ResponseWriter" {
+			continue
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || f.imports[x.Name] != "net/http" {
+			continue
+		}
+		for _, name := range field.Names {
+			names[name.Name] = true
+		}
+	}
+	return names
+}
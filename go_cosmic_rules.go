@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Classification rules extend the built-in tables from the -config file for
+// the packages a code base depends on that have no built-in support. The
+// "rules suggest" subcommand drafts them from the dependencies in use.
+
+// classificationRule classifies the calls of one function or method of a package.
+type classificationRule struct {
+	Package string `json:"package"`
+	// Function is the function or method name, or a name prefix followed by
+	// *, as Save*; a rule of the name takes precedence over the prefixes,
+	// and a longer prefix over a shorter one.
+	Function string `json:"function"`
+	// Receiver restricts the rule to the methods of a receiver type, named
+	// as Store or *Store (either matches both).
+	Receiver string `json:"receiver,omitempty"`
+	Movement string `json:"movement"` // E, X, R, W or none
+	// Scope restricts the rule to the calls made by the packages matching one
+	// of these patterns, where ... matches any string, as in
+	// example.com/app/cmd/cli/...; without it the rule applies everywhere.
+	// A scoped rule takes precedence over an unscoped one, so a rule can
+	// count fmt.Print as an Exit in a CLI and another make it none (no
+	// movement) in the web packages.
+	Scope []string `json:"scope,omitempty"`
+	// Comment is free text, e.g. why the rule was suggested.
+	Comment string `json:"comment,omitempty"`
+
+	// scope are the compiled Scope patterns.
+	scope []*regexp.Regexp
+}
+
+// movementNone is the movement of a rule classifying calls as no movement.
+const movementNone = "none"
+
+// appliesTo reports whether the rule applies to the calls of a method of
+// receiver, or a function if "", made by the package at import path caller.
+func (r classificationRule) appliesTo(receiver, caller string) bool {
+	if r.Receiver != "" && strings.TrimPrefix(r.Receiver, "*") != strings.TrimPrefix(receiver, "*") {
+		return false
+	}
+	if len(r.scope) == 0 {
+		return true
+	}
+	for _, re := range r.scope {
+		if re.MatchString(caller) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileScope compiles the package patterns of a rule's Scope. As with the go
+// command, a pattern ending in /... also matches the package it is under.
+func compileScope(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		if p == "" {
+			return nil, fmt.Errorf("empty scope pattern")
+		}
+		expr := regexp.QuoteMeta(p)
+		if strings.HasSuffix(expr, `/\.\.\.`) {
+			expr = strings.TrimSuffix(expr, `/\.\.\.`) + `(/\.\.\.)?`
+		}
+		res = append(res, regexp.MustCompile("^"+strings.ReplaceAll(expr, `\.\.\.`, `.*`)+"$"))
+	}
+	return res, nil
+}
+
+// ruleFor returns the movement type a -config rule gives to the function or
+// method name of pkg called by common in fn, movementNone, or "".
+func (s settings) ruleFor(pkg *types.Package, name string, common *ssa.CallCommon, fn *ssa.Function) string {
+	if pkg == nil {
+		return ""
+	}
+	var caller string
+	if fn != nil && fn.Pkg != nil {
+		caller = fn.Pkg.Pkg.Path()
+	}
+	return s.rule(pkg.Path(), name, receiverOf(common), caller)
+}
+
+// rule returns the movement type a -config rule gives to the function or
+// method name of the package at import path p, of receiver type receiver if
+// a method, when called from the package at import path caller,
+// movementNone, or "".
+func (s settings) rule(p, name, receiver, caller string) string {
+	if typ := matchRules(s.rules[p][name], receiver, caller); typ != "" {
+		return typ
+	}
+	var typ, longest string
+	for function, rules := range s.rules[p] {
+		prefix, ok := strings.CutSuffix(function, "*")
+		if !ok || !strings.HasPrefix(name, prefix) || typ != "" && len(prefix) <= len(longest) {
+			continue
+		}
+		if t := matchRules(rules, receiver, caller); t != "" {
+			typ, longest = t, prefix
+		}
+	}
+	return typ
+}
+
+// matchRules returns the movement type of the first scoped rule applying to
+// the call, of the first rule applying if none, or "".
+func matchRules(rules []classificationRule, receiver, caller string) string {
+	typ := ""
+	for _, r := range rules {
+		if !r.appliesTo(receiver, caller) {
+			continue
+		}
+		if len(r.scope) > 0 {
+			return r.Movement
+		}
+		if typ == "" {
+			typ = r.Movement
+		}
+	}
+	return typ
+}
+
+// dependencyKinds guess what a dependency does from the elements of its import
+// path, in order of precedence.
+var dependencyKinds = []struct {
+	kind     string
+	keywords []string
+}{
+	{"messaging", []string{"kafka", "sarama", "nats", "amqp", "rabbitmq", "mqtt", "pubsub", "sqs", "sns", "queue", "stream", "nsq", "pulsar"}},
+	{"database", []string{"sql", "db", "pg", "pgx", "mysql", "sqlite", "mongo", "redis", "redigo", "cassandra", "gocql", "elastic", "dynamodb", "bolt", "badger", "leveldb", "etcd", "cache", "orm", "bun", "ent", "kv", "store"}},
+	{"file storage", []string{"s3", "storage", "blob", "gcs", "azblob", "ftp", "sftp", "minio"}},
+	{"web or RPC framework", []string{"http", "web", "mux", "router", "gin", "echo", "fiber", "chi", "grpc", "rpc", "graphql", "websocket", "rest", "api"}},
+}
+
+// movementGuesses guess the movement type of a function from its name prefix.
+var movementGuesses = []struct {
+	movement string
+	prefixes []string
+}{
+	{MovementRead, []string{"Get", "MGet", "HGet", "Find", "Query", "Select", "Load", "Fetch", "List", "Read", "Scan", "Lookup", "Count", "Exists", "Download"}},
+	{MovementWrite, []string{"Set", "MSet", "HSet", "Put", "Insert", "Update", "Upsert", "Delete", "Del", "Remove", "Save", "Write", "Create", "Exec", "Incr", "Decr", "Expire", "Upload", "Store"}},
+	{MovementExit, []string{"Publish", "Send", "Produce", "Post", "Emit", "Notify", "Push"}},
+	{MovementEntry, []string{"Subscribe", "Consume", "Receive", "Poll", "Listen"}},
+}
+
+// runRules implements the "rules" subcommands.
+func runRules(args []string) {
+	switch {
+	case len(args) > 0 && args[0] == "suggest":
+		runRulesSuggest(args[1:])
+	case len(args) > 0 && args[0] == "learn":
+		runRulesLearn(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: %s rules suggest|learn [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		os.Exit(2)
+	}
+}
+
+// runRulesSuggest implements "rules suggest [root]": it lists the
+// dependencies of the analyzed code that look like I/O or web frameworks but
+// have neither built-in support nor rules, and prints -config rules for the
+// functions of theirs the code calls, classified from their names, for review.
+func runRulesSuggest(args []string) {
+	fs := flag.NewFlagSet("rules suggest", flag.ExitOnError)
+	var cfg analysisConfig
+	cfg.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s rules suggest [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	conf, err := readSettings(cfg.configFile)
+	if err != nil {
+		log.Fatalf("-config: %v", err)
+	}
+	required, err := requiredModules(root)
+	if err != nil {
+		log.Fatalf("go.mod: %v", err)
+	}
+
+	a := analyze(root, cfg)
+	known := knownPackages()
+	// kinds holds the uncovered dependency packages that look like I/O.
+	kinds := map[string]string{}
+	for _, pkg := range a.pkgs {
+		for _, imp := range pkg.Pkg.Imports() {
+			p := imp.Path()
+			if !strings.Contains(strings.SplitN(p, "/", 2)[0], ".") || isKnownPackage(p, known) || conf.rules[p] != nil {
+				continue
+			}
+			if required != nil && !isRequired(p, required) {
+				continue
+			}
+			if kind := dependencyKind(p); kind != "" {
+				kinds[p] = kind
+			}
+		}
+	}
+	if len(kinds) == 0 {
+		log.Printf("no unsupported I/O or web dependencies found")
+	}
+
+	// calls collects the functions and methods of those packages called by the code.
+	calls := map[string]map[string]bool{}
+	for _, pkg := range a.pkgs {
+		for _, fn := range packageFunctions(a.prog, pkg) {
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					call, ok := instr.(ssa.CallInstruction)
+					if !ok {
+						continue
+					}
+					var p, name string
+					if common := call.Common(); common.IsInvoke() {
+						if common.Method.Pkg() != nil {
+							p, name = common.Method.Pkg().Path(), common.Method.Name()
+						}
+					} else if sc := common.StaticCallee(); sc != nil && sc.Pkg != nil {
+						p, name = sc.Pkg.Pkg.Path(), sc.Name()
+					}
+					if _, ok := kinds[p]; ok && token.IsExported(name) {
+						if calls[p] == nil {
+							calls[p] = map[string]bool{}
+						}
+						calls[p][name] = true
+					}
+				}
+			}
+		}
+	}
+
+	var out settings
+	var paths []string
+	for p := range kinds {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		log.Printf("%s: no rules (looks like %s), %d functions called", p, kinds[p], len(calls[p]))
+		var names []string
+		for name := range calls[p] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if m := guessMovement(name); m != "" {
+				out.Rules = append(out.Rules, classificationRule{
+					Package:  p,
+					Function: name,
+					Movement: m,
+					Comment:  fmt.Sprintf("suggested: %s, guessed from the name", kinds[p]),
+				})
+			}
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		log.Fatalf("write rules: %v", err)
+	}
+}
+
+// knownPackages returns the package paths of the built-in tables.
+func knownPackages() map[string]bool {
+	known := map[string]bool{}
+	for _, t := range []map[string]map[string]bool{
+		entryRegistrations, readFuncs, writeFuncs, exitFuncs, receiveFuncs, sendFuncs,
+		pollFuncs, upgradeFuncs, systemEntryFuncs, loaderConstructors, cloudStorageReads, cloudStorageWrites,
+		httpClientFuncs, configurationFuncs, cacheReads, cacheWrites,
+	} {
+		for p := range t {
+			known[p] = true
+		}
+	}
+	for _, t := range []map[string]map[string]int{
+		scheduleRegistrations, routeGroups, controllerRegistrations, serviceRegistrations,
+		subscriptionRegistrations, callbackFuncs, transactionFuncs, execFuncs, configFileLoads,
+	} {
+		for p := range t {
+			known[p] = true
+		}
+	}
+	for _, p := range []string{revelPkgPath, gormPkgPath, goaPkgPath, goaHTTPPkgPath, grpcPkgPath, kratosHTTPPkgPath, cloudEventsEventPkg, entPkgPath} {
+		known[p] = true
+	}
+	return known
+}
+
+// isKnownPackage reports whether p has built-in support: it is in a table or
+// under one of the infrastructure, codec or crypto path prefixes.
+func isKnownPackage(p string, known map[string]bool) bool {
+	if known[p] {
+		return true
+	}
+	for _, prefixes := range [][]string{
+		infrastructurePackages, codecPackages, cryptoPackages, metricsPackages, goRedisPkgPaths, redigoPkgPaths,
+		elasticsearchAPIPkgPaths, elasticsearchTypedAPIPrefixes, elasticsearchBulkIndexerPkgPaths,
+	} {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(p, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dependencyKind guesses what the package at import path p does, or "".
+func dependencyKind(p string) string {
+	elems := strings.FieldsFunc(strings.ToLower(p), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	})
+	for _, k := range dependencyKinds {
+		for _, kw := range k.keywords {
+			for _, e := range elems {
+				if e == kw || strings.HasPrefix(e, "go"+kw) || strings.HasSuffix(e, kw) && len(kw) > 3 {
+					return k.kind
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// guessMovement guesses the movement type of a function from its name, or "".
+func guessMovement(name string) string {
+	for _, g := range movementGuesses {
+		for _, prefix := range g.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return g.movement
+			}
+		}
+	}
+	return ""
+}
+
+// requiredModules returns the module paths required by the go.mod of root or
+// of its closest parent directory, or nil if there is none.
+func requiredModules(root string) ([]string, error) {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir, _ = os.Getwd()
+	}
+	for {
+		f, err := os.Open(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			return parseRequires(bufio.NewScanner(f))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// parseRequires returns the module paths of the require directives of a go.mod.
+func parseRequires(sc *bufio.Scanner) ([]string, error) {
+	var mods []string
+	block := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		switch {
+		case block && line == ")":
+			block = false
+		case block && len(fields) >= 2:
+			mods = append(mods, fields[0])
+		case len(fields) >= 2 && fields[0] == "require" && fields[1] == "(":
+			block = true
+		case len(fields) >= 3 && fields[0] == "require":
+			mods = append(mods, fields[1])
+		}
+	}
+	return mods, sc.Err()
+}
+
+// isRequired reports whether package path p belongs to one of the modules.
+func isRequired(p string, modules []string) bool {
+	for _, m := range modules {
+		if p == m || strings.HasPrefix(p, m+"/") {
+			return true
+		}
+	}
+	return false
+}
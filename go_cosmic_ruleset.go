@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Rule-set versions. What a measurement counts depends on the built-in
+// classification tables, which change from one version of the tool to the
+// next, and on the options and -config file it was made with. A difference
+// between two measurements made with different rules is a change of the
+// rules, not of the functional size. Every output therefore records the
+// version of the built-in rules and a digest of the options and settings
+// affecting the counts; "compare" and "budgets" warn when those of their
+// measurements differ, a -cache measured with other rules is not reused, and
+// -rules-version pins the version a CI expects.
+
+// RulesVersion is the version of the built-in classification rules. It is
+// incremented, with an entry in ruleChanges, by every change of the tables
+// that changes what existing code counts.
+const RulesVersion = 1
+
+// ruleChanges summarizes what changed in each version of the built-in rules,
+// by version.
+var ruleChanges = map[int]string{
+	1: "first versioned rule set; sizes measured before it have no rules_version",
+}
+
+// ruleSet identifies the rules a measurement was made with.
+type ruleSet struct {
+	Version int    `json:"rules_version"`
+	Digest  string `json:"rules_digest"`
+}
+
+// rulesDigest returns a digest of the options of cfg and the settings of s
+// affecting the counts; the owners, budgets and redactions do not.
+func rulesDigest(cfg analysisConfig, s settings) string {
+	cfg.configFile, cfg.heuristicFallback = "", false
+	s.Owners, s.Budgets, s.Redact, s.NotEntries = nil, nil, nil, nil
+	conf, _ := json.Marshal(s)
+	h := sha256.Sum256([]byte(fmt.Sprintf("%+v\n%s", cfg, conf)))
+	return hex.EncodeToString(h[:6])
+}
+
+// readRuleSet reads the rules of the JSON measurement at path.
+func readRuleSet(path string) (ruleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ruleSet{}, err
+	}
+	var rs ruleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return ruleSet{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return rs, nil
+}
+
+// migrationWarnings returns why measurements made with rules a and b may
+// differ by their rules, with the changes of the built-in rules between
+// them, or nil if the rules are the same.
+func migrationWarnings(a, b ruleSet) []string {
+	var warnings []string
+	if a.Version != b.Version {
+		warnings = append(warnings, fmt.Sprintf("the built-in rules are of version %s and %s; the sizes may differ by these changes of the rules, not of the code:", versionName(a.Version), versionName(b.Version)))
+		for v := min(a.Version, b.Version) + 1; v <= max(a.Version, b.Version); v++ {
+			if change, ok := ruleChanges[v]; ok {
+				warnings = append(warnings, fmt.Sprintf("  version %d: %s", v, change))
+			}
+		}
+	}
+	if a.Digest != b.Digest && a.Digest != "" && b.Digest != "" {
+		warnings = append(warnings, fmt.Sprintf("the options or -config settings differ (rules digest %s and %s)", a.Digest, b.Digest))
+	}
+	return warnings
+}
+
+// versionName names rules version v; measurements before versioning have 0.
+func versionName(v int) string {
+	if v == 0 {
+		return "0 (unversioned)"
+	}
+	return fmt.Sprint(v)
+}
+
+// warnRules logs the migration warnings of the JSON measurements at paths a
+// and b.
+func warnRules(a, b string) {
+	ra, err := readRuleSet(a)
+	if err != nil {
+		return
+	}
+	rb, err := readRuleSet(b)
+	if err != nil {
+		return
+	}
+	for _, w := range migrationWarnings(ra, rb) {
+		log.Printf("warning: %s, %s: %s", a, b, w)
+	}
+	if ta, tb := readToolVersion(a), readToolVersion(b); ta != nil && tb != nil && *ta != *tb {
+		log.Printf("warning: %s, %s: measured by different builds of the tool, %s and %s", a, b, ta, tb)
+	}
+}
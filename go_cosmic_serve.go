@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Serve mode. Loading and type-checking a repository takes most of a
+// measurement, so a PR bot asking for one measurement after another waits
+// minutes each time. "serve" keeps the loaded programs of the repositories it
+// has measured warm and measures them again in seconds, until the repository
+// has a new commit: the program is then loaded again. A program holds the
+// whole SSA form of its repository, so only the -warm-repos most recently
+// measured are kept, each until it has been idle for -warm-ttl.
+
+// warmRepo is the loaded program of a repository at a commit.
+type warmRepo struct {
+	// mu serializes the loads and measurements of the repository.
+	mu     sync.Mutex
+	commit string
+	a      *analysis
+	// users counts the measurements holding the entry and used is when the
+	// last one ended; both are guarded by the mu of the cache.
+	users int
+	used  time.Time
+}
+
+// warmCache holds the loaded programs by repository directory: keep at most
+// of them, each for ttl after its last measurement.
+type warmCache struct {
+	mu    sync.Mutex
+	repos map[string]*warmRepo
+	keep  int
+	ttl   time.Duration
+}
+
+// newWarmCache returns a cache keeping keep loaded programs at most, each
+// for ttl after its last measurement; 0 is no limit.
+func newWarmCache(keep int, ttl time.Duration) *warmCache {
+	return &warmCache{repos: map[string]*warmRepo{}, keep: keep, ttl: ttl}
+}
+
+// repo returns the entry of the repository in dir, creating it, for a
+// measurement; it must be given back with release.
+func (c *warmCache) repo(dir string) *warmRepo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.repos[dir]
+	if r == nil {
+		r = &warmRepo{}
+		c.repos[dir] = r
+	}
+	r.users++
+	return r
+}
+
+// release gives back the entry r of a measurement, then evicts the programs
+// no longer kept.
+func (c *warmCache) release(r *warmRepo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r.users--
+	r.used = time.Now()
+	c.evict(r.used)
+}
+
+// evict drops the programs unused for longer than the ttl, then the least
+// recently used beyond keep; programs being measured are kept. c.mu must be
+// held.
+func (c *warmCache) evict(now time.Time) {
+	var idle []string
+	for dir, r := range c.repos {
+		switch {
+		case r.users > 0:
+		case c.ttl > 0 && now.Sub(r.used) > c.ttl:
+			c.drop(dir)
+		default:
+			idle = append(idle, dir)
+		}
+	}
+	if c.keep <= 0 || len(c.repos) <= c.keep {
+		return
+	}
+	slices.SortFunc(idle, func(a, b string) int { return c.repos[a].used.Compare(c.repos[b].used) })
+	for _, dir := range idle[:min(len(idle), len(c.repos)-c.keep)] {
+		c.drop(dir)
+	}
+}
+
+// drop removes the entry of dir, releasing its program. c.mu must be held.
+func (c *warmCache) drop(dir string) {
+	c.repos[dir].a = nil
+	delete(c.repos, dir)
+}
+
+// headCommit returns the commit checked out in dir, or "" if dir is not in a
+// git repository; the program of such a directory is loaded every time.
+func headCommit(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// runServe implements "serve": an HTTP server measuring the repositories under
+// -root. GET /measure?repo=<dir>[&detail=1] returns the JSON output for the
+// repository at dir, relative to -root; POST /jobs measures it in the
+// background (see jobQueue).
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	an := &Analyzer{}
+	an.register(fs)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	root := fs.String("root", ".", "directory containing the repositories to measure")
+	jobWorkers := fs.Int("job-workers", 2, "number of measurement jobs run in parallel")
+	queueSize := fs.Int("queue", 100, "number of measurement jobs waiting at most; further jobs are refused")
+	jobTTL := fs.Duration("job-ttl", time.Hour, "time the finished measurement jobs and their results are kept (0 for no limit)")
+	maxJobs := fs.Int("max-jobs", 1000, "number of finished measurement jobs kept at most, the oldest are dropped first (0 for no limit)")
+	warmRepos := fs.Int("warm-repos", 10, "number of repositories whose programs are kept loaded at most, the least recently measured are dropped first (0 for no limit)")
+	warmTTL := fs.Duration("warm-ttl", time.Hour, "time the program of a repository is kept loaded after its last measurement (0 for no limit)")
+	var webhooks allowList
+	fs.Var(&webhooks, "webhook-allow", "URL prefix the job webhooks may post to (https://hooks.example.com/cosmic/); may be repeated, webhooks are refused without any")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve [-addr host:port] [-root dir] [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if an.changedFiles != "" || an.cacheFile != "" {
+		log.Fatalf("serve: -changed-files and -cache are not supported; the programs are kept loaded instead")
+	}
+	if err := an.init(); err != nil {
+		log.Fatal(err)
+	}
+	base, err := filepath.Abs(*root)
+	if err != nil {
+		log.Fatalf("-root: %v", err)
+	}
+	cache := newWarmCache(*warmRepos, *warmTTL)
+
+	http.HandleFunc("/measure", func(w http.ResponseWriter, r *http.Request) {
+		dir, status, err := repoDir(base, r.URL.Query().Get("repo"))
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		out, warm, err := cache.measure(an, dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if warm {
+			w.Header().Set("X-Cosmic-Cache", "warm")
+		} else {
+			w.Header().Set("X-Cosmic-Cache", "cold")
+		}
+		writeOutput(w, r, out)
+	})
+	jobs := newJobQueue(*queueSize, *maxJobs, *jobTTL, webhooks)
+	jobs.handle(http.DefaultServeMux, base)
+	for i := 0; i < *jobWorkers; i++ {
+		go jobs.work(an, cache)
+	}
+	log.Printf("serving measurements of the repositories under %s on %s", base, *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// repoDir returns the directory of the repository repo under base, or the
+// HTTP status and error if there is none.
+func repoDir(base, repo string) (string, int, error) {
+	dir := filepath.Join(base, filepath.FromSlash(repo))
+	if rel, err := filepath.Rel(base, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", http.StatusBadRequest, errors.New("repo must be inside -root")
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", http.StatusNotFound, fmt.Errorf("no repository %s", repo)
+	}
+	return dir, http.StatusOK, nil
+}
+
+// writeOutput writes out as the JSON response to r, with the movements if
+// the detail parameter is set.
+func writeOutput(w http.ResponseWriter, r *http.Request, out Output) {
+	w.Header().Set("Content-Type", "application/json")
+	detail := r.URL.Query().Get("detail")
+	if err := WriteSinks(out, NewJSONSink(w, detail != "" && detail != "0" && detail != "false")); err != nil {
+		log.Printf("serve: %s: write output: %v", r.URL, err)
+	}
+}
+
+// measure measures the repository in dir with an, loading its program unless
+// it is warm, and reports whether it was.
+func (c *warmCache) measure(an *Analyzer, dir string) (Output, bool, error) {
+	r := c.repo(dir)
+	defer c.release(r)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commit := headCommit(dir)
+	warm := r.a != nil && commit != "" && commit == r.commit
+	if !warm {
+		start := time.Now()
+		a, err := an.load(dir)
+		if err != nil {
+			r.a = nil
+			return Output{}, false, err
+		}
+		r.a, r.commit = a, commit
+		log.Printf("serve: loaded %s at %s in %v", dir, commit, time.Since(start).Round(time.Millisecond))
+	}
+	out, err := an.measure(r.a)
+	return out, warm, err
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarmCacheEvict(t *testing.T) {
+	now := time.Now()
+	c := newWarmCache(3, time.Hour)
+	programs := map[string]*warmRepo{}
+	for dir, idle := range map[string]time.Duration{"expired": 2 * time.Hour, "old": 30 * time.Minute, "recent": 20 * time.Minute, "last": time.Minute} {
+		programs[dir] = &warmRepo{a: &analysis{}, used: now.Add(-idle)}
+		c.repos[dir] = programs[dir]
+	}
+	// a program being measured is kept whatever its age
+	programs["measured"] = &warmRepo{a: &analysis{}, users: 1, used: now.Add(-3 * time.Hour)}
+	c.repos["measured"] = programs["measured"]
+	c.evict(now)
+	for _, dir := range []string{"expired", "old"} {
+		if c.repos[dir] != nil || programs[dir].a != nil {
+			t.Errorf("the program of %s was kept", dir)
+		}
+	}
+	for _, dir := range []string{"recent", "last", "measured"} {
+		if c.repos[dir] == nil || programs[dir].a == nil {
+			t.Errorf("the program of %s was dropped", dir)
+		}
+	}
+
+	// a new program makes room by dropping the least recently used
+	r := c.repo("new")
+	if r.users != 1 {
+		t.Fatalf("%d users of a new entry, want 1", r.users)
+	}
+	r.a = &analysis{}
+	c.release(r)
+	if c.repos["recent"] != nil || programs["recent"].a != nil {
+		t.Errorf("the least recently used program was kept")
+	}
+	if len(c.repos) != 3 || c.repos["last"] == nil || c.repos["measured"] == nil || c.repos["new"] != r {
+		t.Errorf("kept %d programs, want last, measured and new", len(c.repos))
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Entry signatures. In-house RPC frameworks dispatch to handlers through
+// reflection or generated tables that registration detection cannot follow,
+// but their handlers share a signature. Every function or method of the code
+// matching one of the entry_signatures of the -config file is an entry point.
+//
+// A pattern is a Go function type: func(context.Context, *XRequest)
+// (*XResponse, error). Types are matched structurally; a named type by its
+// name, qualified by its package name when the pattern qualifies it. A type
+// name starting with X and an upper-case letter matches any name with the
+// rest as suffix (XRequest matches GetUserRequest), and _ matches any type.
+
+// entrySignature is a parsed entry signature pattern.
+type entrySignature struct {
+	pattern string
+	typ     *ast.FuncType
+}
+
+// parseEntrySignatures parses the entry_signatures of the -config file.
+func parseEntrySignatures(patterns []string) ([]entrySignature, error) {
+	var sigs []entrySignature
+	for _, p := range patterns {
+		expr, err := parser.ParseExpr(p)
+		if err != nil {
+			return nil, fmt.Errorf("entry signature %q: %v", p, err)
+		}
+		ft, ok := expr.(*ast.FuncType)
+		if !ok {
+			return nil, fmt.Errorf("entry signature %q: not a function type", p)
+		}
+		sigs = append(sigs, entrySignature{p, ft})
+	}
+	return sigs, nil
+}
+
+// entrySignatureOf returns the entry signature pattern fn matches, or "".
+func (s settings) entrySignatureOf(fn *ssa.Function) string {
+	for _, sig := range s.signatures {
+		if matchesFields(sig.typ.Params, fn.Signature.Params(), fn.Signature.Variadic()) &&
+			matchesFields(sig.typ.Results, fn.Signature.Results(), false) {
+			return sig.pattern
+		}
+	}
+	return ""
+}
+
+// matchesFields reports whether the parameters or results of a signature
+// match the fields of a pattern.
+func matchesFields(fields *ast.FieldList, tuple *types.Tuple, variadic bool) bool {
+	var exprs []ast.Expr
+	if fields != nil {
+		for _, f := range fields.List {
+			for n := max(len(f.Names), 1); n > 0; n-- {
+				exprs = append(exprs, f.Type)
+			}
+		}
+	}
+	if len(exprs) != tuple.Len() {
+		return false
+	}
+	for i, expr := range exprs {
+		t := tuple.At(i).Type()
+		if ell, ok := expr.(*ast.Ellipsis); ok {
+			if !variadic || i != len(exprs)-1 {
+				return false
+			}
+			expr, t = ell.Elt, t.(*types.Slice).Elem()
+		}
+		if !matchesType(expr, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesType reports whether type t matches the type expression of a pattern.
+func matchesType(expr ast.Expr, t types.Type) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return matchesType(e.X, t)
+	case *ast.StarExpr:
+		p, ok := t.(*types.Pointer)
+		return ok && matchesType(e.X, p.Elem())
+	case *ast.ArrayType:
+		s, ok := t.(*types.Slice)
+		return ok && e.Len == nil && matchesType(e.Elt, s.Elem())
+	case *ast.MapType:
+		m, ok := t.(*types.Map)
+		return ok && matchesType(e.Key, m.Key()) && matchesType(e.Value, m.Elem())
+	case *ast.InterfaceType:
+		iface, ok := t.Underlying().(*types.Interface)
+		return ok && iface.Empty() && (e.Methods == nil || len(e.Methods.List) == 0)
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		named := namedType(t)
+		return ok && named != nil && named.Obj().Pkg() != nil && named.Obj().Pkg().Name() == pkg.Name &&
+			matchesTypeName(e.Sel.Name, named.Obj().Name())
+	case *ast.Ident:
+		if e.Name == "_" {
+			return true
+		}
+		if obj := types.Universe.Lookup(e.Name); obj != nil {
+			return types.Identical(obj.Type(), t)
+		}
+		named := namedType(t)
+		return named != nil && matchesTypeName(e.Name, named.Obj().Name())
+	}
+	return false
+}
+
+// namedType returns t as a named type, or nil.
+func namedType(t types.Type) *types.Named {
+	named, _ := t.(*types.Named)
+	return named
+}
+
+// matchesTypeName reports whether a type name matches the name of a pattern,
+// where a leading X before an upper-case letter stands for any prefix.
+func matchesTypeName(pattern, name string) bool {
+	if len(pattern) > 1 && pattern[0] == 'X' && unicode.IsUpper(rune(pattern[1])) {
+		return strings.HasSuffix(name, pattern[1:])
+	}
+	return pattern == name
+}
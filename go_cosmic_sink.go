@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Sink receives a measurement piece by piece and exports it in some format.
+// WriteHeader is called first, then WriteProcess for every process followed by
+// WriteMovement for each of its movements, and finally Close. Embedders
+// implement Sink to add exporters without touching the built-in formats.
+type Sink interface {
+	WriteHeader(h Header) error
+	WriteProcess(pr ProcessReport) error
+	WriteMovement(pr ProcessReport, m Movement) error
+	Close() error
+}
+
+// Header carries the measurement-wide part of the Output, handed to a Sink
+// before any process, with the number of processes.
+type Header struct {
+	Summary
+	Processes int
+}
+
+// output returns an Output with the header totals and no processes.
+func (h Header) output() Output {
+	return Output{Summary: h.Summary}
+}
+
+// WriteSinks streams out into every sink and closes them. All sinks are
+// closed even if one fails; the first error is returned.
+func WriteSinks(out Output, sinks ...Sink) error {
+	var first error
+	for _, s := range sinks {
+		if err := writeSink(out, s); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func writeSink(out Output, s Sink) error {
+	h := Header{Summary: out.Summary, Processes: len(out.Processes)}
+	err := s.WriteHeader(h)
+	for _, pr := range out.Processes {
+		if err != nil {
+			break
+		}
+		if err = s.WriteProcess(pr); err != nil {
+			break
+		}
+		for _, m := range pr.Movements {
+			if err = s.WriteMovement(pr, m); err != nil {
+				break
+			}
+		}
+	}
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// jsonSink encodes the measurement as the indented JSON Output document.
+type jsonSink struct {
+	w      io.Writer
+	detail bool
+	out    Output
+}
+
+// NewJSONSink returns a Sink encoding the measurement as JSON to w. Individual
+// movements are only included when detail is set.
+func NewJSONSink(w io.Writer, detail bool) Sink {
+	return &jsonSink{w: w, detail: detail}
+}
+
+func (s *jsonSink) WriteHeader(h Header) error {
+	s.out = h.output()
+	return nil
+}
+
+func (s *jsonSink) WriteProcess(pr ProcessReport) error {
+	pr.Movements = nil
+	s.out.Processes = append(s.out.Processes, pr)
+	return nil
+}
+
+func (s *jsonSink) WriteMovement(_ ProcessReport, m Movement) error {
+	if s.detail {
+		last := &s.out.Processes[len(s.out.Processes)-1]
+		last.Movements = append(last.Movements, m)
+	}
+	return nil
+}
+
+func (s *jsonSink) Close() error {
+	enc := json.NewEncoder(s.w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // process names contain "->"
+	return enc.Encode(s.out)
+}
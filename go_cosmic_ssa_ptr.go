@@ -1,39 +1,289 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
+	"go/constant"
 	"go/token"
+	"go/types"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/pointer"
 	"golang.org/x/tools/go/ssa"
-	"golang.org/x/tools/go/callgraph"
 )
 
 // ProcessReport is the per-functional-process COSMIC-like counts.
 type ProcessReport struct {
-	Name   string `json:"name"`
-	Source string `json:"source,omitempty"` // package/path:func
-	Entries int   `json:"entries"`
-	Exits   int   `json:"exits"`
-	Reads   int   `json:"reads"`
-	Writes  int   `json:"writes"`
-	Funcs   int   `json:"functions_included"`
+	Name string `json:"name"`
+	// ID identifies the process across measurements (see processID).
+	ID      string `json:"id,omitempty"`
+	Source  string `json:"source,omitempty"` // package/path:func
+	Entries int    `json:"entries"`
+	Exits   int    `json:"exits"`
+	Reads   int    `json:"reads"`
+	Writes  int    `json:"writes"`
+	// Funcs counts the functions traversed from the entry: those of the
+	// analyzed packages, and with -deps those of their dependencies too.
+	Funcs int `json:"functions_included"`
+	// Trigger describes the triggering event of the process (program start, handler registration).
+	Trigger string `json:"trigger,omitempty"`
+	// Schedule is the cron spec or interval of a timer-triggered process.
+	Schedule string `json:"schedule,omitempty"`
+	// Invokes names the main processes of the binaries of the analyzed code
+	// the process runs as subprocesses (only with -compose).
+	Invokes []string `json:"invokes,omitempty"`
+	// SystemEntries counts the Entries from the clock and the random source,
+	// included in Entries only with -system-entries.
+	SystemEntries int `json:"system_entries,omitempty"`
+	// Method is the HTTP method of a route restricted to one; a handler
+	// registered for several methods is one process per method.
+	Method string `json:"method,omitempty"`
+	// DataGroups lists the data groups moved by the process, when they can be named.
+	DataGroups []string `json:"data_groups,omitempty"`
+	// Movements lists the individual data movements (only emitted with -detail).
+	Movements []Movement `json:"movements,omitempty"`
+	// Cached is set when the report was taken unchanged from the -cache file.
+	Cached bool `json:"cached,omitempty"`
+	// Bounds are the bounds of the size of a heuristic-grade measurement.
+	Bounds *CFPBounds `json:"cfp_bounds,omitempty"`
+	// Generated is set when the entry function is generated code, such as an
+	// oapi-codegen or protoc server stub.
+	Generated bool `json:"generated,omitempty"`
+	// Handwritten are the counts without the movements of generated code,
+	// given when the process counts some.
+	Handwritten *MovementTotals `json:"handwritten,omitempty"`
+	// CFPUpperBound is the size when every implementation of the interfaces
+	// called is traversed (-overapproximate).
+	CFPUpperBound int `json:"cfp_upper_bound,omitempty"`
+	// Owners are the teams owning the file of the entry function, from
+	// CODEOWNERS or the owners of the -config file.
+	Owners []string `json:"owners,omitempty"`
+	// Functions are the functions included in the process (only with
+	// -functions).
+	Functions []FunctionRef `json:"functions,omitempty"`
+}
+
+// FunctionRef is a function included in a process, with its position.
+type FunctionRef struct {
+	Name string `json:"name"`
+	Pos  string `json:"pos,omitempty"`
+}
+
+// Data movement types.
+const (
+	MovementEntry = "E"
+	MovementExit  = "X"
+	MovementRead  = "R"
+	MovementWrite = "W"
+)
+
+// Movement is a single classified data movement found at a call site.
+type Movement struct {
+	Type      string `json:"type"` // E, X, R or W
+	DataGroup string `json:"data_group,omitempty"`
+	Callee    string `json:"callee"`
+	// Pos is the file:line:column of the call, telling apart nested calls on
+	// one line, as in w.Write(mustJSON(x)).
+	Pos string `json:"pos,omitempty"`
+	// Tags classify the movement beyond its type, e.g. as infrastructure.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Movement tags.
+const (
+	// TagInfrastructure marks movements of middleware (authn, CORS, rate limiting, tracing).
+	TagInfrastructure = "infrastructure"
+	// TagSystem marks Entries from the clock or the OS random source, which
+	// only count as functional users with -system-entries.
+	TagSystem = "system"
+	// TagConfiguration marks reads of environment variables and configuration,
+	// which only count with -configuration.
+	TagConfiguration = "configuration"
+	// TagCommandLine marks the parsing and declaration of command-line flags,
+	// which only count with -cli.
+	TagCommandLine = "command-line"
+	// TagLogging marks movements of logging packages, which -no-log-exits
+	// leaves out of the counts.
+	TagLogging = "logging"
+)
+
+// entryPoint describes how an entry function is triggered.
+type entryPoint struct {
+	trigger string
+	route   string // full route path, when registered on a router with a constant path
+	// methods are the HTTP methods the route is registered for, if restricted.
+	methods []string
+	// schedule is the cron spec or interval of a timer-triggered process.
+	schedule string
+}
+
+// variants returns one entry point per HTTP method of ep, or ep itself.
+func (ep entryPoint) variants() []entryPoint {
+	if len(ep.methods) < 2 {
+		return []entryPoint{ep}
+	}
+	vs := make([]entryPoint, len(ep.methods))
+	for i, m := range ep.methods {
+		vs[i] = ep
+		vs[i].methods = []string{m}
+	}
+	return vs
+}
+
+// method returns the HTTP method of a route restricted to a single method.
+func (ep entryPoint) method() string {
+	if len(ep.methods) == 1 {
+		return ep.methods[0]
+	}
+	return ""
+}
+
+// addEntry records ep as the entry point of fn. A registration of fn on the
+// same route for other HTTP methods adds its methods instead: the function
+// then handles one process per method.
+func addEntry(set map[*ssa.Function]entryPoint, fn *ssa.Function, ep entryPoint) {
+	if prev, ok := set[fn]; ok && prev.route == ep.route && len(prev.methods) > 0 && len(ep.methods) > 0 {
+		methods := append([]string(nil), prev.methods...)
+		for _, m := range ep.methods {
+			methods = appendUnique(methods, m)
+		}
+		prev.methods = methods
+		set[fn] = prev
+		return
+	}
+	set[fn] = ep
+}
+
+// Counts is the per-function tally of data movements found by scanning its instructions.
+type Counts struct {
+	Entries, Exits, Reads, Writes int
+	Movements                     []Movement
+}
+
+// countsTable stores the local counts of the scanned functions compactly: only
+// functions with movements are kept, their counts live in one slice indexed by
+// function ID and their movements in one shared arena. This replaces a map
+// entry plus a movement slice per function, which dominated GC time on large
+// builds.
+type countsTable struct {
+	ids       map[*ssa.Function]int32
+	counts    []compactCounts
+	movements []Movement
+}
+
+// compactCounts is the stored form of Counts; its movements are arena[first:end].
+type compactCounts struct {
+	entries, exits, reads, writes int32
+	first, end                    int32
+}
+
+func newCountsTable() *countsTable {
+	return &countsTable{ids: map[*ssa.Function]int32{}}
+}
+
+// add stores the local counts of fn, dropping functions without movements.
+func (t *countsTable) add(fn *ssa.Function, c Counts) {
+	if len(c.Movements) == 0 {
+		return
+	}
+	t.ids[fn] = int32(len(t.counts))
+	first := int32(len(t.movements))
+	t.movements = append(t.movements, c.Movements...)
+	t.counts = append(t.counts, compactCounts{
+		entries: int32(c.Entries), exits: int32(c.Exits), reads: int32(c.Reads), writes: int32(c.Writes),
+		first: first, end: int32(len(t.movements)),
+	})
+}
+
+// id returns the function ID of fn; ok is false when fn has no movements.
+func (t *countsTable) id(fn *ssa.Function) (id int32, ok bool) {
+	id, ok = t.ids[fn]
+	return id, ok
+}
+
+// tag adds tag to the local movements of fn in place, leaving them out of its
+// counts when tag is one of uncounted.
+func (t *countsTable) tag(fn *ssa.Function, tag string, uncounted []string) {
+	id, ok := t.ids[fn]
+	if !ok {
+		return
+	}
+	cc := &t.counts[id]
+	// uncounts are the movements counted so far that tag leaves out
+	var uncounts Counts
+	for i := cc.first; i < cc.end; i++ {
+		m := &t.movements[i]
+		if hasTag(*m, tag) {
+			continue
+		}
+		if slices.Contains(uncounted, tag) && !hasAnyTag(*m, uncounted) {
+			uncounts.addMovement(*m)
+		}
+		m.Tags = append(m.Tags[:len(m.Tags):len(m.Tags)], tag)
+	}
+	cc.entries -= int32(uncounts.Entries)
+	cc.exits -= int32(uncounts.Exits)
+	cc.reads -= int32(uncounts.Reads)
+	cc.writes -= int32(uncounts.Writes)
+}
+
+// get returns the local counts of function id. The movements alias the arena.
+func (t *countsTable) get(id int32) Counts {
+	cc := t.counts[id]
+	return Counts{
+		Entries: int(cc.entries), Exits: int(cc.exits), Reads: int(cc.reads), Writes: int(cc.writes),
+		Movements: t.movements[cc.first:cc.end:cc.end],
+	}
 }
 
 // Output is the overall JSON structure.
 type Output struct {
-	TotalEntries int             `json:"total_entries"`
-	TotalExits   int             `json:"total_exits"`
-	TotalReads   int             `json:"total_reads"`
-	TotalWrites  int             `json:"total_writes"`
-	Processes    []ProcessReport `json:"processes"`
+	Summary
+	Processes []ProcessReport `json:"processes"`
+}
+
+// Summary is the measurement-wide part of an Output, which is also the
+// Header handed to the sinks before the processes.
+type Summary struct {
+	TotalEntries int `json:"total_entries"`
+	TotalExits   int `json:"total_exits"`
+	TotalReads   int `json:"total_reads"`
+	TotalWrites  int `json:"total_writes"`
+	// TotalSystemEntries sums the processes' SystemEntries.
+	TotalSystemEntries int `json:"total_system_entries,omitempty"`
+	// Chains are the processes composed through subprocesses (-compose).
+	Chains []ProcessChain `json:"chains,omitempty"`
+	// Grade is "heuristic" when the code could not be type-checked and was
+	// measured from its syntax; GradeReason says why and Bounds how far the
+	// size may be off.
+	Grade       string     `json:"grade,omitempty"`
+	GradeReason string     `json:"grade_reason,omitempty"`
+	Bounds      *CFPBounds `json:"cfp_bounds,omitempty"`
+	// Handwritten are the totals without the movements of generated code,
+	// given when a process counts some.
+	Handwritten *MovementTotals `json:"handwritten,omitempty"`
+	// CFPUpperBound sums the processes' CFPUpperBound (-overapproximate).
+	CFPUpperBound int `json:"cfp_upper_bound,omitempty"`
+	// Teams are the sizes by owning team, given when a process is owned.
+	Teams []TeamTotal `json:"teams,omitempty"`
+	// RulesVersion and RulesDigest identify the rules of the measurement
+	// (see ruleSet).
+	RulesVersion int    `json:"rules_version,omitempty"`
+	RulesDigest  string `json:"rules_digest,omitempty"`
+	// Tool is the build of the tool that made the measurement.
+	Tool *ToolVersion `json:"tool,omitempty"`
 }
 
 var (
@@ -48,6 +298,106 @@ var (
 			"HandleFunc": true,
 			"Handle":     true,
 		},
+		// Legacy framework pack: beego function routes and iris Party routes.
+		"github.com/beego/beego/v2/server/web":    httpVerbRoutes("Any", "Handler"),
+		"github.com/astaxie/beego":                httpVerbRoutes("Any", "Handler"),
+		"github.com/kataras/iris/v12/core/router": httpVerbRoutes("Any", "Handle", "HandleMany", "Connect", "Trace"),
+		"github.com/gin-gonic/gin": {
+			"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "HEAD": true, "OPTIONS": true,
+			"Any": true, "Handle": true,
+		},
+		// CloudWeGo Hertz route groups; go-kratos HTTP routers and servers.
+		"github.com/cloudwego/hertz/pkg/route": {
+			"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "HEAD": true, "OPTIONS": true,
+			"Any": true, "Handle": true,
+		},
+		"github.com/go-kratos/kratos/v2/transport/http": {
+			"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "HEAD": true, "OPTIONS": true,
+			"CONNECT": true, "TRACE": true, "Handle": true, "HandlePrefix": true,
+		},
+		// Event handlers: CloudEvents receivers (knative services), serverless functions.
+		"github.com/cloudevents/sdk-go/v2/client": {
+			"StartReceiver": true,
+		},
+		"github.com/GoogleCloudPlatform/functions-framework-go/functions": {
+			"CloudEvent": true,
+			"HTTP":       true,
+		},
+		"github.com/aws/aws-lambda-go/lambda": {
+			"Start":            true,
+			"StartWithOptions": true,
+			"StartHandler":     true,
+			"StartWithContext": true,
+			"StartHandlerFunc": true,
+		},
+	}
+
+	// Schedulers run a job on a cron spec or interval. Map of package path ->
+	// method name -> index of the spec argument (not counting the receiver), or
+	// -1 when the schedule is built on the receiver (gocron's Every(1).Hour()).
+	scheduleRegistrations = map[string]map[string]int{
+		"github.com/robfig/cron": {
+			"AddFunc": 0,
+			"AddJob":  0,
+		},
+		"github.com/robfig/cron/v3": {
+			"AddFunc": 0,
+			"AddJob":  0,
+		},
+		"github.com/go-co-op/gocron": {
+			"Do": -1,
+		},
+	}
+
+	// Route group constructors derive a router whose routes share a path prefix.
+	// Map of package path -> method name -> index of the prefix argument (not
+	// counting the receiver), or -1 when the prefix is the receiver's own.
+	routeGroups = map[string]map[string]int{
+		"github.com/gorilla/mux": {
+			"PathPrefix": 0,
+			"Subrouter":  -1,
+		},
+		"github.com/gin-gonic/gin": {
+			"Group": 0,
+		},
+		"github.com/kataras/iris/v12/core/router": {
+			"Party": 0,
+		},
+		"github.com/cloudwego/hertz/pkg/route": {
+			"Group": 0,
+		},
+		"github.com/go-kratos/kratos/v2/transport/http": {
+			"Route": 0,
+			"Group": 0,
+		},
+	}
+
+	// registrationNameSuffixes are function name suffixes treated as registrations
+	// in any package, for in-house wrappers around net/http style routers.
+	registrationNameSuffixes = []string{"HandleFunc", "Handle"}
+
+	// Controller registrations take a controller value whose own exported methods
+	// are each an action entry point. Map of package path -> function name -> index
+	// of the controller argument (not counting a method receiver).
+	controllerRegistrations = map[string]map[string]int{
+		"github.com/beego/beego/v2/server/web": {
+			"Router":     1,
+			"AutoRouter": 0,
+		},
+		"github.com/astaxie/beego": {
+			"Router":     1,
+			"AutoRouter": 0,
+		},
+	}
+
+	// controllerLifecycleMethods are framework hooks on controllers which are not actions.
+	controllerLifecycleMethods = map[string]bool{
+		"Init":       true,
+		"Prepare":    true,
+		"Finish":     true,
+		"URLMapping": true,
+		"Before":     true,
+		"After":      true,
 	}
 
 	// Read-like functions by package path
@@ -59,11 +409,86 @@ var (
 		"io/ioutil": {
 			"ReadFile": true,
 		},
+		aferoPkgPath: aferoReads,
+		ioFSPkgPath:  ioFSReads,
 		"database/sql": {
-			"Query":    true,
-			"QueryRow": true,
-			"Scan":     true,
+			"Query":           true,
+			"QueryContext":    true,
+			"QueryRow":        true,
+			"QueryRowContext": true,
+			"Scan":            true,
+		},
+		// sqlx queries, as methods of DB, Tx and Stmt and as package functions.
+		"github.com/jmoiron/sqlx": {
+			"Get":               true,
+			"GetContext":        true,
+			"Select":            true,
+			"SelectContext":     true,
+			"Queryx":            true,
+			"QueryxContext":     true,
+			"QueryRowx":         true,
+			"QueryRowxContext":  true,
+			"NamedQuery":        true,
+			"NamedQueryContext": true,
+		},
+		// GORM finisher methods; the data group is resolved through the chain.
+		"gorm.io/gorm": {
+			"Find":          true,
+			"FindInBatches": true,
+			"First":         true,
+			"Take":          true,
+			"Last":          true,
+			"Scan":          true,
+			"Count":         true,
+			"Pluck":         true,
+			"Row":           true,
+			"Rows":          true,
+		},
+		// File formats of data integration: CSV, Excel workbooks, XML.
+		"encoding/csv": {
+			"Read":    true,
+			"ReadAll": true,
+		},
+		"github.com/xuri/excelize/v2": {
+			"OpenFile":     true,
+			"GetRows":      true,
+			"GetCols":      true,
+			"GetCellValue": true,
+			"Rows":         true,
+		},
+		"github.com/360EntSecGroup-Skylar/excelize/v2": {
+			"OpenFile":     true,
+			"GetRows":      true,
+			"GetCellValue": true,
+		},
+		"encoding/xml": {
+			"Decode":        true,
+			"DecodeElement": true,
 		},
+		"archive/zip": {
+			"OpenReader": true,
+		},
+		// AWS object and table storage, also through the aws-sdk-go interfaces.
+		"github.com/aws/aws-sdk-go/service/s3":                     s3Reads,
+		"github.com/aws/aws-sdk-go/service/s3/s3iface":             s3Reads,
+		"github.com/aws/aws-sdk-go/service/s3/s3manager":           s3TransferReads,
+		"github.com/aws/aws-sdk-go-v2/service/s3":                  s3Reads,
+		"github.com/aws/aws-sdk-go-v2/feature/s3/manager":          s3TransferReads,
+		"github.com/aws/aws-sdk-go/service/dynamodb":               dynamoDBReads,
+		"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface": dynamoDBReads,
+		"github.com/aws/aws-sdk-go-v2/service/dynamodb":            dynamoDBReads,
+		// etcd keys, through the KV and Watcher interfaces of the client.
+		"go.etcd.io/etcd/client/v3":       etcdReads,
+		"go.etcd.io/etcd/clientv3":        etcdReads,
+		"github.com/coreos/etcd/clientv3": etcdReads,
+		// embedded key-value stores
+		"go.etcd.io/bbolt":                    bboltReads,
+		"github.com/boltdb/bolt":              bboltReads,
+		"github.com/dgraph-io/badger":         badgerReads,
+		"github.com/dgraph-io/badger/v2":      badgerReads,
+		"github.com/dgraph-io/badger/v3":      badgerReads,
+		"github.com/dgraph-io/badger/v4":      badgerReads,
+		"github.com/syndtr/goleveldb/leveldb": levelDBReads,
 	}
 
 	// Write-like functions by package path
@@ -75,9 +500,67 @@ var (
 		"io/ioutil": {
 			"WriteFile": true,
 		},
+		aferoPkgPath: aferoWrites,
 		"database/sql": {
-			"Exec": true,
+			"Exec":        true,
+			"ExecContext": true,
+		},
+		"github.com/jmoiron/sqlx": {
+			"NamedExec":        true,
+			"NamedExecContext": true,
+			"MustExec":         true,
+			"MustExecContext":  true,
+		},
+		"gorm.io/gorm": {
+			"Create":          true,
+			"CreateInBatches": true,
+			"Save":            true,
+			"Update":          true,
+			"Updates":         true,
+			"UpdateColumn":    true,
+			"UpdateColumns":   true,
+			"Delete":          true,
+			"Exec":            true,
+		},
+		"encoding/csv": {
+			"Write":    true,
+			"WriteAll": true,
 		},
+		"github.com/xuri/excelize/v2": {
+			"SetCellValue": true,
+			"SetSheetRow":  true,
+			"SetSheetCol":  true,
+			"SaveAs":       true,
+			"Save":         true,
+			"Write":        true,
+		},
+		"github.com/360EntSecGroup-Skylar/excelize/v2": {
+			"SetCellValue": true,
+			"SaveAs":       true,
+			"Save":         true,
+		},
+		"encoding/xml": {
+			"Encode":        true,
+			"EncodeElement": true,
+		},
+		"github.com/aws/aws-sdk-go/service/s3":                     s3Writes,
+		"github.com/aws/aws-sdk-go/service/s3/s3iface":             s3Writes,
+		"github.com/aws/aws-sdk-go/service/s3/s3manager":           s3TransferWrites,
+		"github.com/aws/aws-sdk-go-v2/service/s3":                  s3Writes,
+		"github.com/aws/aws-sdk-go-v2/feature/s3/manager":          s3TransferWrites,
+		"github.com/aws/aws-sdk-go/service/dynamodb":               dynamoDBWrites,
+		"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface": dynamoDBWrites,
+		"github.com/aws/aws-sdk-go-v2/service/dynamodb":            dynamoDBWrites,
+		"go.etcd.io/etcd/client/v3":                                etcdWrites,
+		"go.etcd.io/etcd/clientv3":                                 etcdWrites,
+		"github.com/coreos/etcd/clientv3":                          etcdWrites,
+		"go.etcd.io/bbolt":                                         bboltWrites,
+		"github.com/boltdb/bolt":                                   bboltWrites,
+		"github.com/dgraph-io/badger":                              badgerWrites,
+		"github.com/dgraph-io/badger/v2":                           badgerWrites,
+		"github.com/dgraph-io/badger/v3":                           badgerWrites,
+		"github.com/dgraph-io/badger/v4":                           badgerWrites,
+		"github.com/syndtr/goleveldb/leveldb":                      levelDBWrites,
 	}
 
 	// Exit-like functions by package path
@@ -86,13 +569,259 @@ var (
 			"Exit": true,
 		},
 	}
+
+	// Subprocess launches by package path: function name -> index of the
+	// program argument. The arguments passed to the program are an Exit, with
+	// the program's base name as data group.
+	execFuncs = map[string]map[string]int{
+		"os/exec": {
+			"Command":        0,
+			"CommandContext": 1,
+		},
+	}
+
+	// Receive-like functions by package path: data entering over a connection
+	// held open with the user (WebSocket messages). They are Entries, not Reads.
+	receiveFuncs = map[string]map[string]bool{
+		"github.com/gorilla/websocket": {
+			"ReadMessage": true,
+			"ReadJSON":    true,
+			"NextReader":  true,
+		},
+		"nhooyr.io/websocket": {
+			"Read":   true,
+			"Reader": true,
+		},
+		"nhooyr.io/websocket/wsjson": {
+			"Read": true,
+		},
+		"github.com/coder/websocket": {
+			"Read":   true,
+			"Reader": true,
+		},
+		"github.com/coder/websocket/wsjson": {
+			"Read": true,
+		},
+		"golang.org/x/net/websocket": {
+			"Read":    true,
+			"Receive": true,
+		},
+	}
+
+	// Send-like functions by package path: data leaving over such a connection (Exits).
+	sendFuncs = map[string]map[string]bool{
+		"github.com/gorilla/websocket": {
+			"WriteMessage":         true,
+			"WriteJSON":            true,
+			"NextWriter":           true,
+			"WritePreparedMessage": true,
+		},
+		"nhooyr.io/websocket": {
+			"Write":  true,
+			"Writer": true,
+		},
+		"nhooyr.io/websocket/wsjson": {
+			"Write": true,
+		},
+		"github.com/coder/websocket": {
+			"Write":  true,
+			"Writer": true,
+		},
+		"github.com/coder/websocket/wsjson": {
+			"Write": true,
+		},
+		"golang.org/x/net/websocket": {
+			"Write": true,
+			"Send":  true,
+		},
+		"github.com/eclipse/paho.mqtt.golang": {
+			"Publish": true,
+		},
+		"cloud.google.com/go/pubsub": {
+			"Publish": true,
+		},
+		"cloud.google.com/go/pubsub/v2": {
+			"Publish": true,
+		},
+		"github.com/aws/aws-sdk-go/service/sqs": {
+			"SendMessage":            true,
+			"SendMessageWithContext": true,
+			"SendMessageBatch":       true,
+		},
+		"github.com/aws/aws-sdk-go-v2/service/sqs": {
+			"SendMessage":      true,
+			"SendMessageBatch": true,
+		},
+		// Message producers of Kafka, NATS and AMQP brokers.
+		"github.com/IBM/sarama":                               saramaSends,
+		"github.com/Shopify/sarama":                           saramaSends,
+		"github.com/segmentio/kafka-go":                       {"WriteMessages": true},
+		"github.com/confluentinc/confluent-kafka-go/kafka":    {"Produce": true},
+		"github.com/confluentinc/confluent-kafka-go/v2/kafka": {"Produce": true},
+		"github.com/nats-io/nats.go":                          natsSends,
+		"github.com/nats-io/nats.go/jetstream":                natsSends,
+		"github.com/rabbitmq/amqp091-go":                      amqpSends,
+		"github.com/streadway/amqp":                           amqpSends,
+		// Email sent to users.
+		"net/smtp":                        {"SendMail": true, "Data": true},
+		"gopkg.in/gomail.v2":              gomailSends,
+		"github.com/go-gomail/gomail":     gomailSends,
+		"github.com/wneessen/go-mail":     {"Send": true, "DialAndSend": true, "DialAndSendWithContext": true},
+		"github.com/sendgrid/sendgrid-go": {"Send": true, "SendWithContext": true},
+		"github.com/aws/aws-sdk-go/service/ses": {
+			"SendEmail": true, "SendEmailWithContext": true,
+			"SendRawEmail": true, "SendRawEmailWithContext": true,
+			"SendTemplatedEmail": true, "SendTemplatedEmailWithContext": true,
+			"SendBulkTemplatedEmail": true, "SendBulkTemplatedEmailWithContext": true,
+		},
+		"github.com/aws/aws-sdk-go-v2/service/ses": {
+			"SendEmail": true, "SendRawEmail": true, "SendTemplatedEmail": true, "SendBulkTemplatedEmail": true,
+		},
+		"github.com/aws/aws-sdk-go-v2/service/sesv2": {"SendEmail": true, "SendBulkEmail": true},
+	}
+
+	// gomailSends are the functions and Dialer methods of gomail sending mail.
+	gomailSends = map[string]bool{"Send": true, "DialAndSend": true}
+
+	// WebSocket upgrades by package path; a function upgrading the connection
+	// handles a WebSocket session and is a process unless an entry reaches it.
+	upgradeFuncs = map[string]map[string]bool{
+		"github.com/gorilla/websocket": {
+			"Upgrade": true,
+		},
+		"nhooyr.io/websocket": {
+			"Accept": true,
+		},
+		"github.com/coder/websocket": {
+			"Accept": true,
+		},
+	}
+
+	// Reads of the clock and of the OS random source, Entries from these
+	// functional users (-system-entries).
+	systemEntryFuncs = map[string]map[string]bool{
+		"time": {
+			"Now":   true,
+			"Since": true,
+			"Until": true,
+		},
+		"crypto/rand": {
+			"Read":  true,
+			"Int":   true,
+			"Prime": true,
+			"Text":  true,
+		},
+	}
+
+	// systemUsers names the data group of the system Entries by package path.
+	systemUsers = map[string]string{
+		"time":        "clock",
+		"crypto/rand": "random",
+	}
+
+	// Service registration functions which take an implementation value whose exported
+	// methods are each an entry point. Map of package path -> function name -> index of
+	// the implementation argument (not counting a method receiver).
+	serviceRegistrations = map[string]map[string]int{
+		"net/rpc": {
+			"Register":     0,
+			"RegisterName": 1,
+		},
+	}
+
+	// infrastructurePackages are middleware packages (by path prefix) whose
+	// movements are cross-cutting infrastructure rather than functional.
+	infrastructurePackages = []string{
+		// authentication
+		"github.com/golang-jwt/jwt",
+		"github.com/dgrijalva/jwt-go",
+		"github.com/auth0/go-jwt-middleware",
+		"github.com/coreos/go-oidc",
+		"golang.org/x/oauth2",
+		// CORS
+		"github.com/rs/cors",
+		"github.com/go-chi/cors",
+		"github.com/gin-contrib/cors",
+		// rate limiting
+		"golang.org/x/time/rate",
+		"github.com/go-chi/httprate",
+		"github.com/ulule/limiter",
+		"github.com/didip/tollbooth",
+		// tracing
+		"go.opentelemetry.io/",
+		"github.com/opentracing/",
+		"github.com/uber/jaeger-client-go",
+		"gopkg.in/DataDog/dd-trace-go.v1",
+		// framework middleware collections
+		"github.com/gorilla/handlers",
+		"github.com/go-chi/chi/middleware",
+		"github.com/go-chi/chi/v5/middleware",
+		"github.com/labstack/echo/v4/middleware",
+		"github.com/gofiber/fiber/v2/middleware",
+		"github.com/gin-contrib/",
+	}
+
+	// sqlTableRe extracts the table name from a SQL statement used as a data group.
+	sqlTableRe = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+["'\x60]?([A-Za-z_][A-Za-z0-9_.]*)`)
 )
 
 func main() {
 	log.SetFlags(0)
-	ptrMode := flag.Bool("ptr", false, "enable pointer analysis + callgraph (resolves indirect/interface calls)")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "affected":
+			runAffected(os.Args[2:])
+			return
+		case "budgets":
+			runBudgets(os.Args[2:])
+			return
+		case "callers":
+			runCallers(os.Args[2:])
+			return
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		case "flags":
+			runFlags(os.Args[2:])
+			return
+		case "entries":
+			runEntries(os.Args[2:])
+			return
+		case "rules":
+			runRules(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "trend":
+			runTrend(os.Args[2:])
+			return
+		case "version":
+			runVersion(os.Args[2:])
+			return
+		}
+	}
+	an := &Analyzer{}
+	an.register(flag.CommandLine)
+	stubsDir := flag.String("stubs", "", "write per-process Markdown documentation stubs into this directory")
+	detail := flag.Bool("detail", false, "include the individual data movements of each process in the JSON output")
+	format := flag.String("format", "json", "output format: json, markdown for a report to paste into a PR description or wiki page, or html for a single-file report")
+	sourceURL := flag.String("source-url", "", "link the source locations of the html report to this URL, with {path} relative to the module root and {line}, instead of the local files")
+	reqifFile := flag.String("reqif", "", "also export the measurement as ReqIF to this file")
+	var recipients recipientsFlag
+	flag.Var(&recipients, "encrypt-recipient", "encrypt the JSON output and the -reqif file to this age public key (age1...); may be repeated")
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-ptr] <module-root-or-package-pattern>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <module-root-or-package-pattern>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s affected [flags] <file[:line]> [module-root-or-package-pattern]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s callers [flags] <callee> [module-root-or-package-pattern]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s flags [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s entries [-assist] [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s rules suggest|learn [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s compare [-tolerance 5%%] [-patch file] <a.json|manual.csv> <b.json>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s budgets -config <file> <baseline.json> <current.json>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s serve [-addr host:port] [-root dir] [flags]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s trend [-commits 30] [-window 10] [-z 3] [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s version [-check]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -100,8 +829,175 @@ func main() {
 		flag.Usage()
 		os.Exit(2)
 	}
-	root := flag.Arg(0)
+	if *format == "html" {
+		an.functions = true
+	}
+	if err := an.init(); err != nil {
+		log.Fatal(err)
+	}
+	if len(recipients) > 0 && *stubsDir != "" {
+		log.Fatalf("-encrypt-recipient cannot be used with -stubs, whose documentation is edited in place")
+	}
+	stdout, err := encryptingWriter(os.Stdout, recipients)
+	if err != nil {
+		log.Fatalf("-encrypt-recipient: %v", err)
+	}
+	var sinks []Sink
+	switch *format {
+	case "json":
+		sinks = append(sinks, closingSink{NewJSONSink(stdout, *detail), stdout})
+	case "markdown", "md":
+		sinks = append(sinks, closingSink{NewMarkdownSink(stdout, *detail), stdout})
+	case "html":
+		root := flag.Arg(0)
+		if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+			root = "."
+		}
+		sinks = append(sinks, closingSink{NewHTMLSink(stdout, root, *sourceURL), stdout})
+	default:
+		log.Fatalf("-format must be json, markdown or html, not %q", *format)
+	}
+	if *stubsDir != "" {
+		sinks = append(sinks, NewStubsSink(*stubsDir))
+	}
+	if *reqifFile != "" {
+		sinks = append(sinks, NewReqIFSink(*reqifFile, recipients...))
+	}
+	out, err := an.Measure(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteSinks(out, sinks...); err != nil {
+		log.Fatalf("write output: %v", err)
+	}
+}
+
+// analysisConfig selects how a program is loaded and which entry points are detected.
+type analysisConfig struct {
+	ptr, deps, init, library bool
+	excludeInfra             bool
+	noLogExits               bool
+	excludeGenerated         bool
+	overapproximate          bool
+	attributeLoaders         bool
+	tests                    bool
+	dedupe                   bool
+	systemEntries            bool
+	metrics                  bool
+	configFile               string
+	cli                      bool
+	// configuration is how configuration reads count: "read", "entry" or,
+	// when empty, not at all.
+	configuration string
+	// heuristicFallback measures code that does not type-check from its
+	// syntax instead of failing; only the measurement itself has a fallback.
+	heuristicFallback bool
+}
+
+// uncounted returns the tags of the movements left out of the counts.
+func (c analysisConfig) uncounted() []string {
+	var tags []string
+	if c.excludeInfra {
+		tags = append(tags, TagInfrastructure)
+	}
+	if c.excludeGenerated {
+		tags = append(tags, TagGenerated)
+	}
+	if c.noLogExits {
+		tags = append(tags, TagLogging)
+	}
+	if !c.systemEntries {
+		tags = append(tags, TagSystem)
+	}
+	if !c.metrics {
+		tags = append(tags, TagMetrics)
+	}
+	if c.configuration == "" {
+		tags = append(tags, TagConfiguration)
+	}
+	if !c.cli {
+		tags = append(tags, TagCommandLine)
+	}
+	return tags
+}
+
+// register defines the analysis flags on fs.
+func (c *analysisConfig) register(fs *flag.FlagSet) {
+	fs.BoolVar(&c.ptr, "ptr", false, "enable pointer analysis + callgraph (resolves indirect/interface calls)")
+	fs.BoolVar(&c.deps, "deps", false, "load full syntax of all dependencies so traversal follows calls through their bodies (implied by -ptr); the movements are the same, functions_included also counts the dependencies' functions")
+	fs.BoolVar(&c.init, "init", false, "measure package initialization (init functions and package-level var initializers) as startup processes")
+	fs.BoolVar(&c.excludeInfra, "exclude-infra", false, "do not count infrastructure (middleware) movements in the CFP; they stay in the -detail output")
+	fs.BoolVar(&c.noLogExits, "no-log-exits", false, "do not count the movements of logging packages (log, slog, zap, zerolog, logrus and the logger_packages of -config) in the CFP; they stay in the -detail output")
+	fs.BoolVar(&c.metrics, "metrics", false, "count the movements of metrics instrumentation packages (Prometheus, OpenTelemetry metrics, statsd and the metrics_packages of -config), such as delivering the metrics to their collector; they are in the -detail output either way")
+	fs.BoolVar(&c.excludeGenerated, "exclude-generated", false, "do not count the movements of generated code (files marked \"Code generated ... DO NOT EDIT.\", such as OpenAPI and protobuf server stubs) in the CFP; they stay in the -detail output")
+	fs.BoolVar(&c.attributeLoaders, "attribute-loaders", false, "count dataloader batch functions in the processes calling Load instead of as processes of their own")
+	fs.BoolVar(&c.tests, "tests", false, "load the test packages and measure each Test, Benchmark and Fuzz function as a process instead of the production entry points")
+	fs.BoolVar(&c.dedupe, "dedupe", false, "count each named data group once per movement type and process (the COSMIC rule); repeated movements stay in the -detail output")
+	fs.BoolVar(&c.overapproximate, "overapproximate", false, "also traverse every implementation of the interfaces called (class hierarchy analysis) and report the size so reached as cfp_upper_bound")
+	fs.BoolVar(&c.library, "library", false, "treat the exported functions and methods of packages without main or registrations as processes")
+	fs.BoolVar(&c.systemEntries, "system-entries", false, "count reading the clock (time.Now) and the OS random source (crypto/rand) as Entries from these functional users; they are reported as system_entries either way")
+	fs.BoolVar(&c.cli, "cli", false, "measure as a command-line program: parsing and declaring flags (flag, pflag) and reading the arguments count as Entries from the user; they are in the -detail output either way")
+	fs.StringVar(&c.configuration, "configuration", "", "count reading environment variables and configuration (os.Getenv, envconfig, viper) as Reads (read) or as Entries from the operator (entry); they are in the -detail output either way")
+	fs.StringVar(&c.configFile, "config", "", "JSON file adjusting the classification policies (e.g. {\"codecs\": \"movements\"} to count compression and archive reads and writes)")
+}
+
+// analysis is a loaded and scanned program: its analyzed packages, its entry
+// functions in a deterministic order with their triggers, the local counts of
+// every scanned function and the traversal policy.
+type analysis struct {
+	prog        *ssa.Program
+	pkgs        []*ssa.Package
+	entries     []*ssa.Function
+	entryPoints map[*ssa.Function]entryPoint
+	localCounts *countsTable
+	succ        func(*ssa.Function) []*ssa.Function
+	// upper adds the implementations of the interfaces called to succ, with
+	// overapproximate.
+	upper     func(*ssa.Function) []*ssa.Function
+	generated generatedCode
+	// owners are the ownership rules of the code.
+	owners codeOwners
+	// overrides are the //cosmic: comments of the code.
+	overrides overrides
+	// redactions are the redact patterns of the -config file.
+	redactions []*regexp.Regexp
+	// code are the paths of the analyzed packages and of the modules loaded
+	// with them, whose names -anonymize hashes.
+	code []string
+	// untyped says why the code could not be type-checked, with
+	// heuristicFallback; the analysis then only has the directory of the code.
+	untyped string
+	dir     string
+}
+
+// analyze loads the packages at root, builds their SSA form and scans every
+// function for data movements and entry points.
+func analyze(root string, cfg analysisConfig) *analysis {
+	conf, err := readSettings(cfg.configFile)
+	if err != nil {
+		log.Fatalf("-config: %v", err)
+	}
+	if err := cfg.validate(); err != nil {
+		log.Fatal(err)
+	}
+	a, err := loadAnalysis(root, cfg, conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return a
+}
+
+// validate checks the flag values of c.
+func (c analysisConfig) validate() error {
+	switch c.configuration {
+	case "", "read", "entry":
+		return nil
+	}
+	return fmt.Errorf("-configuration must be \"read\" or \"entry\", not %q", c.configuration)
+}
 
+// loadAnalysis is analyze with the settings conf, returning the errors.
+func loadAnalysis(root string, cfg analysisConfig, conf settings) (*analysis, error) {
 	// Convert path to package pattern and determine Dir for packages.Load
 	pattern := "./..."
 	dir := root
@@ -115,269 +1011,1645 @@ func main() {
 		}
 	}
 
+	// Only the analyzed packages need syntax and type info; dependencies are
+	// typed from export data unless their function bodies are needed. The
+	// movements are only classified in the analyzed packages, so they do not
+	// change, but without the bodies the traversal stops at the dependencies
+	// and functions_included only counts the functions of the analyzed code.
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports |
+		packages.NeedTypes | packages.NeedTypesSizes | packages.NeedSyntax | packages.NeedTypesInfo
+	if cfg.deps || cfg.ptr {
+		mode = packages.LoadAllSyntax
+	}
+	mode |= packages.NeedModule
+
 	fset := token.NewFileSet()
-	cfg := &packages.Config{
-		Mode:  packages.LoadAllSyntax,
+	loadCfg := &packages.Config{
+		Mode:  mode,
 		Fset:  fset,
 		Dir:   dir,
 		Env:   os.Environ(),
-		Tests: false,
+		Tests: cfg.tests,
+	}
+	pkgs, err := packages.Load(loadCfg, pattern)
+	if err != nil && !cfg.heuristicFallback {
+		return nil, fmt.Errorf("packages.Load: %v", err)
 	}
-	pkgs, err := packages.Load(cfg, pattern)
+	reason := typeCheckFailure(pkgs)
 	if err != nil {
-		log.Fatalf("packages.Load: %v", err)
+		reason = err.Error()
+	} else {
+		packages.PrintErrors(pkgs)
 	}
-	if packages.PrintErrors(pkgs) > 0 {
-		log.Printf("warning: packages had load errors; results may be incomplete")
+	if reason != "" {
+		// Ill-typed code has no sound SSA form.
+		if !cfg.heuristicFallback {
+			return nil, fmt.Errorf("the code does not type-check (%s); only the measurement can fall back to a heuristic grade", reason)
+		}
+		if dir == "" {
+			dir = "."
+		}
+		owners, err := readCodeOwners(dir, conf.owners)
+		if err != nil {
+			return nil, fmt.Errorf("CODEOWNERS: %v", err)
+		}
+		log.Printf("warning: %s; measuring from syntax alone (heuristic grade)", reason)
+		return &analysis{untyped: reason, dir: dir, owners: owners, redactions: conf.redactions}, nil
 	}
 
 	// Build SSA program
 	prog := ssa.NewProgram(fset, ssa.SanityCheckFunctions)
+	// Every imported package must be created before building; only the
+	// requested (root) packages are scanned for processes.
+	created := map[*packages.Package]*ssa.Package{}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil {
+			created[pkg] = prog.CreatePackage(pkg.Types, pkg.Syntax, pkg.TypesInfo, true)
+		}
+	})
+	// Dependencies loaded from export data have no packages.Package of their own.
+	for _, s := range created {
+		createImports(prog, s.Pkg)
+	}
 	var ssaPkgs []*ssa.Package
+	rootPkgs := map[*ssa.Package]bool{}
 	for _, pkg := range pkgs {
-		if pkg.Types == nil {
-			continue
+		if s, ok := created[pkg]; ok {
+			ssaPkgs = append(ssaPkgs, s)
+			rootPkgs[s] = true
 		}
-		s := prog.CreatePackage(pkg.Types, pkg.Syntax, pkg.TypesInfo, true)
-		ssaPkgs = append(ssaPkgs, s)
 	}
 	prog.Build()
+	generated := generatedFiles(fset, pkgs)
+	owners, err := readCodeOwners(dir, conf.owners)
+	if err != nil {
+		return nil, fmt.Errorf("CODEOWNERS: %v", err)
+	}
+	overridden := collectOverrides(fset, pkgs)
+	stubs := grpcStubPackages(pkgs)
 
-	// localCounts maps each function to counts found by scanning its instructions.
-	type Counts struct{ Entries, Exits, Reads, Writes int }
-	localCounts := map[*ssa.Function]Counts{}
+	// localCounts holds the counts found by scanning each function's instructions.
+	localCounts := newCountsTable()
 
-	// entryFuncsSet collects functions identified as entry points (main.main and handlers)
-	entryFuncsSet := map[*ssa.Function]bool{}
+	// entryFuncsSet collects functions identified as entry points (main.main and handlers),
+	// mapped to a description of their triggering event.
+	entryFuncsSet := map[*ssa.Function]entryPoint{}
+	// spawned records, per function, the long-running workers it starts with
+	// go statements; they are processes of their own, not part of the spawner.
+	spawned := map[*ssa.Function]map[*ssa.Function]bool{}
+	// batchFuncs are the dataloader batch functions; loads maps a function to
+	// the batch functions of the loaders it loads from.
+	batchFuncs := map[*ssa.Function]bool{}
+	loads := map[*ssa.Function][]*ssa.Function{}
+	polls := pollingLoops{}
+	// upgraders are the functions upgrading a connection to a WebSocket.
+	upgraders := map[*ssa.Function]bool{}
 
-	// Scan all functions to collect local counts and find registrations / main.
+	// Route tables: functions stored into struct fields and maps anywhere in the
+	// analyzed packages, so handlers registered while iterating a table can be
+	// traced back to the table's elements.
+	routes := newRouteTable()
 	for _, ssaPkg := range ssaPkgs {
-		for _, mem := range ssaPkg.Members {
-			if fn, ok := mem.(*ssa.Function); ok {
-				// identify main.main
-				if fn.Pkg != nil && fn.Pkg.Pkg != nil && fn.Pkg.Pkg.Path() == "main" && fn.Name() == "main" {
-					entryFuncsSet[fn] = true
-				}
+		for _, fn := range packageFunctions(prog, ssaPkg) {
+			routes.collect(fn)
+		}
+	}
 
-				var c Counts
-				for _, b := range fn.Blocks {
-					for _, instr := range b.Instrs {
-						switch ins := instr.(type) {
-						case *ssa.Call, *ssa.Defer, *ssa.Go:
-							var callCommon *ssa.CallCommon
-							switch v := ins.(type) {
-							case *ssa.Call:
-								callCommon = v.Common()
-							case *ssa.Defer:
-								callCommon = v.Common()
-							case *ssa.Go:
-								callCommon = v.Common()
-							}
-							if callCommon == nil {
-								continue
-							}
-							// Registration detection and handler extraction
-							if sc := callCommon.StaticCallee(); sc != nil {
-								if isRegistrationFunction(sc) {
-									// search args for handler functions or closures
-									for i := 0; i < len(callCommon.Args); i++ {
-										arg := callCommon.Args[i]
-										if hf := extractFunctionFromValue(arg); hf != nil {
-											entryFuncsSet[hf] = true
-										}
-									}
-									c.Entries++
-								}
-							} else {
-								// For dynamic call sites we cannot know statically here.
-								// Pointer analysis mode will resolve many of these.
-							}
-							// Count read/write/exit based on static callee if available
-							if sc := callCommon.StaticCallee(); sc != nil {
-								if matchesExit(sc) {
-									c.Exits++
-								}
-								if matchesRead(sc) {
-									c.Reads++
-								}
-								if matchesWrite(sc) {
-									c.Writes++
-								}
-							}
-						}
-					}
-				}
-				localCounts[fn] = c
-			}
+	// Message subscriptions: every subscribed handler is a process whose
+	// received message is an Entry of the handler itself. Walk callbacks are
+	// called on behalf of the walking function and read one data group.
+	received := map[*ssa.Function][]Movement{}
+	callbacks := map[*ssa.Function][]*ssa.Function{}
+	fileGroups := map[*ssa.Function]string{}
+	// mounts are the routes of service methods mounted by generated code.
+	mounts := mountedRoutes{}
+	for _, ssaPkg := range ssaPkgs {
+		for _, fn := range packageFunctions(prog, ssaPkg) {
+			collectSubscriptions(fn, routes, entryFuncsSet, received)
+			collectCallbacks(fn, routes, callbacks, fileGroups)
+			mounts.collectGoa(fn)
+			mounts.collectKratos(fn)
 		}
 	}
 
-	// Build the output by traversing from entry functions.
-	out := Output{}
+	// Repository interfaces are classified at their call sites, like ports.
+	// conf shares the Ports of the Analyzer's settings, which concurrent
+	// loads must not append into.
+	if conf.Repositories != "declared" {
+		conf.Ports = append(slices.Clip(conf.Ports), discoverRepositories(prog, ssaPkgs)...)
+	}
 
-	if *ptrMode {
-		// Run pointer analysis to build callgraph (resolves interfaces & indirect calls).
-		cfg := &pointer.Config{
-			Mains: ssaPkgs,
-			BuildCallGraph: true,
-		}
-		res, err := pointer.Analyze(cfg)
-		if err != nil {
-			log.Fatalf("pointer.Analyze: %v", err)
+	// Scan all functions to collect local counts and find registrations / main.
+	for _, ssaPkg := range ssaPkgs {
+		if isEntGenerated(ssaPkg) {
+			continue // classified where it is called
 		}
-		cg := res.CallGraph
-		// Build mapping from *ssa.Function -> *callgraph.Node
-		funcToNode := map[*ssa.Function]*callgraph.Node{}
-		for _, n := range cg.Nodes {
-			if n.Func != nil {
-				funcToNode[n.Func] = n
+		for _, fn := range packageFunctions(prog, ssaPkg) {
+			// identify main.main
+			if isMainFunc(fn) {
+				entryFuncsSet[fn] = entryPoint{trigger: "program start (main.main)"}
 			}
-		}
-
-		for fn := range entryFuncsSet {
-			// find callgraph node
-			node := funcToNode[fn]
-			// if node is nil, fall back to static traversal (we'll handle below)
-			if node == nil {
-				// fallback static traversal
-				pr := traverseStatic(fn, localCounts)
-				out.Processes = append(out.Processes, pr)
-				out.TotalEntries += pr.Entries
-				out.TotalExits += pr.Exits
-				out.TotalReads += pr.Reads
-				out.TotalWrites += pr.Writes
-				continue
+			if isRevelAction(fn) {
+				entryFuncsSet[fn] = entryPoint{trigger: "revel controller action"}
 			}
-			// BFS over callgraph nodes reachable from node
-			visited := map[*callgraph.Node]bool{}
-			queue := []*callgraph.Node{node}
-			pr := ProcessReport{
-				Name:   fmt.Sprintf("%s.%s", fn.Pkg.Pkg.Path(), fn.Name()),
-				Source: fn.String(),
-			}
-			for len(queue) > 0 {
-				n := queue[0]
-				queue = queue[1:]
-				if n == nil || visited[n] {
-					continue
-				}
-				visited[n] = true
-				if n.Func != nil {
-					if lc, ok := localCounts[n.Func]; ok {
-						pr.Entries += lc.Entries
-						pr.Exits += lc.Exits
-						pr.Reads += lc.Reads
-						pr.Writes += lc.Writes
-					}
-					pr.Funcs++
-				}
-				// enqueue outgoing callees
-				for _, e := range n.Out {
-					if e == nil || e.Callee == nil {
-						continue
-					}
-					if !visited[e.Callee] {
-						queue = append(queue, e.Callee)
-					}
-				}
+			if cfg.init && isPackageInit(fn) {
+				entryFuncsSet[fn] = entryPoint{trigger: startupTrigger}
 			}
-			out.Processes = append(out.Processes, pr)
-			out.TotalEntries += pr.Entries
-			out.TotalExits += pr.Exits
-			out.TotalReads += pr.Reads
-			out.TotalWrites += pr.Writes
-		}
-	} else {
-		// Non-pointer static traversal (previous behavior)
-		for fn := range entryFuncsSet {
-			pr := traverseStatic(fn, localCounts)
-			out.Processes = append(out.Processes, pr)
-			out.TotalEntries += pr.Entries
-			out.TotalExits += pr.Exits
-			out.TotalReads += pr.Reads
-			out.TotalWrites += pr.Writes
-		}
-	}
-
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(out); err != nil {
-		log.Fatalf("encode output: %v", err)
-	}
-}
 
-// traverseStatic performs a DFS following StaticCallee edges from fn (fallback/static mode).
-func traverseStatic(fn *ssa.Function, localCounts map[*ssa.Function]struct{ Entries, Exits, Reads, Writes int }) ProcessReport {
-	visited := map[*ssa.Function]bool{}
-	stack := []*ssa.Function{fn}
-	pr := ProcessReport{
-		Name:   fmt.Sprintf("%s.%s", fn.Pkg.Pkg.Path(), fn.Name()),
-		Source: fn.String(),
-	}
-	for len(stack) > 0 {
-		n := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-		if n == nil || visited[n] {
-			continue
-		}
-		visited[n] = true
-		if lc, ok := localCounts[n]; ok {
-			pr.Entries += lc.Entries
-			pr.Exits += lc.Exits
-			pr.Reads += lc.Reads
-			pr.Writes += lc.Writes
-		}
-		pr.Funcs++
-		// push static callees
-		for _, b := range n.Blocks {
-			for _, instr := range b.Instrs {
-				switch ins := instr.(type) {
-				case *ssa.Call, *ssa.Defer, *ssa.Go:
-					var callCommon *ssa.CallCommon
-					switch v := ins.(type) {
-					case *ssa.Call:
-						callCommon = v.Common()
-					case *ssa.Defer:
-						callCommon = v.Common()
-					case *ssa.Go:
-						callCommon = v.Common()
-					}
-					if callCommon == nil {
-						continue
+			var c Counts
+			for _, m := range received[fn] {
+				c.addMovement(m)
+			}
+			signals := false
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					// Receiving from a chan os.Signal (registered with signal.Notify)
+					// is the entry of an OS signal.
+					for n := signalReceives(instr); n > 0; n-- {
+						c.record(MovementEntry, "os/signal.Notify", "os.Signal", prog.Fset.Position(instr.Pos()))
+						signals = true
 					}
-					if sc := callCommon.StaticCallee(); sc != nil {
-						if !visited[sc] {
-							stack = append(stack, sc)
+					// Long-running loops started by fn are processes of their own.
+					if call, ok := instr.(ssa.CallInstruction); ok {
+						if w := call.Common().StaticCallee(); w != nil && w != fn {
+							_, isGo := instr.(*ssa.Go)
+							var trigger string
+							switch {
+							case polls.of(w):
+								trigger = "queue polling loop started by " + fn.String()
+							case isGo && receivesSignals(w), isGo && isWorkerLoop(w) && !upgradesConnection(fn):
+								// the message loops of a WebSocket session are part of its process
+								trigger = "goroutine started by " + fn.String()
+							}
+							if trigger != "" {
+								if _, isEntry := entryFuncsSet[w]; !isEntry {
+									entryFuncsSet[w] = entryPoint{trigger: trigger, schedule: tickerInterval(w)}
+								}
+								if spawned[fn] == nil {
+									spawned[fn] = map[*ssa.Function]bool{}
+								}
+								spawned[fn][w] = true
+							}
 						}
 					}
-				}
-			}
-		}
-	}
-	return pr
-}
-
-// isRegistrationFunction returns true if the function is a known registration entry point.
+					switch ins := instr.(type) {
+					case *ssa.Call, *ssa.Defer, *ssa.Go:
+						var callCommon *ssa.CallCommon
+						switch v := ins.(type) {
+						case *ssa.Call:
+							callCommon = v.Common()
+						case *ssa.Defer:
+							callCommon = v.Common()
+						case *ssa.Go:
+							callCommon = v.Common()
+						}
+						if callCommon == nil {
+							continue
+						}
+						if o, ok := overridden.at(callCommon); ok {
+							// classified by the //cosmic: comment alone
+							if o.movement != movementNone {
+								c.record(o.movement, calleeName(callCommon), o.group, prog.Fset.Position(callCommon.Pos()), TagOverride)
+							}
+							continue
+						}
+						// Registration detection and handler extraction
+						if sc := callCommon.StaticCallee(); sc != nil {
+							// the receiver of a method registration is the router itself
+							recv, args := ssa.Value(nil), callCommon.Args
+							if sc.Signature.Recv() != nil && len(args) > 0 {
+								recv, args = args[0], args[1:]
+							}
+							// The routes of a generated go-kratos HTTP registration call
+							// wrappers of the implementation, whose methods are the entries.
+							if isRegistrationFunction(sc) && !isGeneratedServerRegistration(fn) {
+								result, _ := instr.(ssa.Value)
+								ep := entryPoint{
+									trigger: fmt.Sprintf("registered via %s", sc),
+									route:   routeOf(recv, args),
+									methods: routeMethods(sc.Name(), args, result),
+								}
+								// search args for handler functions or closures
+								var handlers []*ssa.Function
+								for i := 0; i < len(callCommon.Args); i++ {
+									for _, hf := range extractFunctionsFromValue(callCommon.Args[i], routes) {
+										addEntry(entryFuncsSet, hf, ep)
+										handlers = append(handlers, hf)
+									}
+								}
+								// and for http.Handler values
+								for _, arg := range args {
+									if hf := serveHTTPMethod(prog, arg, rootPkgs); hf != nil {
+										addEntry(entryFuncsSet, hf, ep)
+									}
+								}
+								for _, dg := range entryDataGroups(handlers) {
+									c.record(MovementEntry, sc.String(), dg, prog.Fset.Position(callCommon.Pos()))
+								}
+							}
+							// Service registrations expose every exported method of the implementation.
+							if impl, api := serviceImplementation(sc, callCommon); impl != nil {
+								for _, m := range exportedMethods(prog, impl.Type(), api) {
+									method, route := splitMethod(mounts[sc.String()][m.Name()])
+									ep := entryPoint{trigger: fmt.Sprintf("registered via %s", sc), route: route}
+									if method != "" {
+										ep.methods = []string{method}
+									}
+									// a method served over gRPC and HTTP keeps its HTTP route
+									if prev, ok := entryFuncsSet[m]; !ok || ep.route != "" || prev.route == "" {
+										entryFuncsSet[m] = ep
+									}
+									c.record(MovementEntry, sc.String(), "", prog.Fset.Position(callCommon.Pos()))
+								}
+							}
+							// Controller registrations expose the controller's own action methods.
+							if ctrl := controllerArgument(sc, callCommon); ctrl != nil {
+								for _, m := range controllerActions(prog, ctrl.Type()) {
+									entryFuncsSet[m] = entryPoint{trigger: fmt.Sprintf("registered via %s", sc), route: routeOf(recv, args)}
+									c.record(MovementEntry, sc.String(), "", prog.Fset.Position(callCommon.Pos()))
+								}
+							}
+							// Scheduled jobs are timer-triggered processes.
+							if idx, ok := scheduleRegistration(sc); ok {
+								ep := entryPoint{trigger: fmt.Sprintf("scheduled via %s", sc), schedule: scheduleOf(recv, args, idx)}
+								for i, arg := range args {
+									if i == idx {
+										continue
+									}
+									for _, jf := range extractFunctionsFromValue(arg, routes) {
+										entryFuncsSet[jf] = ep
+									}
+									if jf := interfaceMethod(prog, arg, "Run", rootPkgs); jf != nil {
+										entryFuncsSet[jf] = ep
+									}
+								}
+							}
+							// gqlgen schemas expose the resolvers of their resolver root.
+							if root, api := gqlgenResolverRoot(sc, callCommon); root != nil {
+								for _, m := range gqlgenResolvers(prog, root, api) {
+									entryFuncsSet[m] = entryPoint{trigger: fmt.Sprintf("GraphQL resolver registered via %s", sc)}
+									c.record(MovementEntry, sc.String(), "", prog.Fset.Position(callCommon.Pos()))
+								}
+							}
+							// Dataloader batch functions, and the loaders each function loads from.
+							if isLoaderConstructor(sc) && len(callCommon.Args) > 0 {
+								for _, bf := range extractFunctionsFromValue(callCommon.Args[0], routes) {
+									batchFuncs[bf] = true
+								}
+							}
+							if isLoaderLoad(sc) && len(callCommon.Args) > 0 {
+								loads[fn] = append(loads[fn], extractFunctionsFromValue(callCommon.Args[0], routes)...)
+							}
+						} else if callCommon.IsInvoke() && isRegistrationMethod(callCommon.Method) {
+							// Registrations through router interfaces (e.g. iris Party).
+							result, _ := instr.(ssa.Value)
+							ep := entryPoint{
+								trigger: fmt.Sprintf("registered via %s", callCommon.Method.FullName()),
+								route:   routeOf(callCommon.Value, callCommon.Args),
+								methods: routeMethods(callCommon.Method.Name(), callCommon.Args, result),
+							}
+							var handlers []*ssa.Function
+							for i := 0; i < len(callCommon.Args); i++ {
+								for _, hf := range extractFunctionsFromValue(callCommon.Args[i], routes) {
+									addEntry(entryFuncsSet, hf, ep)
+									handlers = append(handlers, hf)
+								}
+								if hf := serveHTTPMethod(prog, callCommon.Args[i], rootPkgs); hf != nil {
+									addEntry(entryFuncsSet, hf, ep)
+								}
+							}
+							for _, dg := range entryDataGroups(handlers) {
+								c.record(MovementEntry, callCommon.Method.FullName(), dg, prog.Fset.Position(callCommon.Pos()))
+							}
+						} else {
+							// For dynamic call sites we cannot know statically here.
+							// Pointer analysis mode will resolve many of these.
+						}
+						// Sends and receives through client interfaces (MQTT, message brokers).
+						if callCommon.IsInvoke() {
+							pos := prog.Fset.Position(callCommon.Pos())
+							if typ, group := conf.portMovement(callCommon.Method); typ != "" {
+								c.record(typ, callCommon.Method.FullName(), group, pos)
+							} else if typ := conf.ruleFor(callCommon.Method.Pkg(), callCommon.Method.Name(), callCommon, fn); typ != "" {
+								if typ != movementNone {
+									c.record(typ, callCommon.Method.FullName(), dataGroupOf(callCommon), pos)
+								}
+							} else if group, ok := conf.contextValueEntry(fn, callCommon); ok {
+								c.record(MovementEntry, callCommon.Method.FullName(), group, pos)
+							} else if group, ok := requestInvoke(callCommon); ok {
+								c.record(MovementEntry, callCommon.Method.FullName(), group, pos, TagRequest)
+							} else if writesResponse(callCommon) {
+								// marshalled bytes are the Exit of their Marshal
+								if !writesMarshalled(callCommon) {
+									c.record(MovementExit, callCommon.Method.FullName(), "", pos)
+								}
+							} else if isHTTPDoer(callCommon.Method) {
+								host := httpHost(callCommon.Args)
+								c.record(MovementExit, callCommon.Method.FullName(), host, pos)
+								c.record(MovementEntry, callCommon.Method.FullName(), host, pos)
+							} else if typs, service := stubs.clientCall(callCommon.Method.Name(), callCommon.Method.Type().(*types.Signature)); typs != nil {
+								for _, typ := range typs {
+									c.record(typ, callCommon.Method.FullName(), service, pos)
+								}
+							} else if typ, group := redisCommand(callCommon); typ != "" {
+								c.record(typ, callCommon.Method.FullName(), group, pos)
+							} else if matchesMethodTable(callCommon.Method, receiveFuncs) {
+								c.record(MovementEntry, callCommon.Method.FullName(), dataGroupOf(callCommon), pos)
+							} else if matchesMethodTable(callCommon.Method, sendFuncs) {
+								c.record(MovementExit, callCommon.Method.FullName(), dataGroupOf(callCommon), pos)
+							} else if isFilesystemPackage(callCommon.Method.Pkg()) && inMemoryFilesystem(callCommon) {
+								// files in memory or compiled into the program
+							} else if matchesMethodTable(callCommon.Method, readFuncs) {
+								c.record(MovementRead, callCommon.Method.FullName(), dataGroupOf(callCommon), pos)
+							} else if matchesMethodTable(callCommon.Method, writeFuncs) {
+								c.record(MovementWrite, callCommon.Method.FullName(), dataGroupOf(callCommon), pos)
+							}
+						}
+						// Search engine APIs, also called through function-typed fields.
+						if typ, via, index := elasticsearchCall(callCommon); typ != "" {
+							c.record(typ, via, index, prog.Fset.Position(callCommon.Pos()))
+						}
+						// Count read/write/exit based on static callee if available
+						if sc := callCommon.StaticCallee(); sc != nil {
+							pos := prog.Fset.Position(callCommon.Pos())
+							var tags []string
+							if isInfrastructure(sc.Pkg) {
+								tags = []string{TagInfrastructure}
+							}
+							if conf.isLogger(sc.Pkg) {
+								tags = append(tags, TagLogging)
+							}
+							if conf.isMetrics(sc.Pkg) {
+								tags = append(tags, TagMetrics)
+							}
+							if isUpgrade(sc) {
+								upgraders[fn] = true
+							}
+							rpc, service := stubs.clientCall(sc.Name(), sc.Signature)
+							page, renders := templateExit(sc, callCommon)
+							file, loads := configFileLoad(fn, sc, callCommon)
+							result, _ := instr.(ssa.Value)
+							encoded, marshals, exits := marshalExit(sc, callCommon, result)
+							requested, reads := requestRead(sc, callCommon)
+							switch {
+							case sc.Pkg != nil && conf.ruleFor(sc.Pkg.Pkg, sc.Name(), callCommon, fn) != "":
+								if typ := conf.ruleFor(sc.Pkg.Pkg, sc.Name(), callCommon, fn); typ != movementNone {
+									c.record(typ, sc.String(), dataGroupOf(callCommon), pos, tags...)
+								}
+							case isEntGenerated(sc.Pkg):
+								if typ, entity := entMovement(sc); typ != "" {
+									c.record(typ, sc.String(), entity, pos, tags...)
+								}
+							case isElasticsearchClient(sc):
+								// classified above, by the API called
+							case isRedisClient(sc):
+								if typ, group := redisCommand(callCommon); typ != "" {
+									c.record(typ, sc.String(), group, pos, tags...)
+								}
+							case isCloudStorageClient(sc):
+								if typ, group := cloudStorageCall(sc, callCommon); typ != "" {
+									c.record(typ, sc.String(), group, pos, tags...)
+								}
+							case isHTTPClientCall(sc):
+								// the request sent and the response received
+								host := httpHost(callCommon.Args)
+								c.record(MovementExit, sc.String(), host, pos, tags...)
+								c.record(MovementEntry, sc.String(), host, pos, tags...)
+							case rpc != nil:
+								// a call of a generated gRPC client stub
+								for _, typ := range rpc {
+									c.record(typ, sc.String(), service, pos, tags...)
+								}
+							case renders:
+								// a page rendered into the response or a file
+								c.record(MovementExit, sc.String(), page, pos, tags...)
+							case loads:
+								// a configuration file read from storage
+								c.record(MovementRead, sc.String(), file, pos, tags...)
+							case execProgram(sc, callCommon.Args) != "":
+								c.record(MovementExit, sc.String(), execProgram(sc, callCommon.Args), pos, tags...)
+							case configurationSource(sc) != "":
+								c.record(cfg.configurationMovement(), sc.String(), configurationSource(sc), pos, append(tags, TagConfiguration)...)
+							case parsesCommandLine(sc):
+								c.record(MovementEntry, sc.String(), commandLineGroup, pos, append(tags, TagCommandLine)...)
+							case matchesTable(sc, systemEntryFuncs):
+								c.record(MovementEntry, sc.String(), systemUsers[sc.Pkg.Pkg.Path()], pos, append(tags, TagSystem)...)
+							case conf.isLocalCodec(sc.Pkg) && !matchesTable(sc, readFuncs):
+								// compression and archive streams only transform the data
+								// moved by the underlying source or sink
+							case isCacheCall(sc):
+								if typ, group := conf.cacheMovement(sc, callCommon); typ != "" {
+									c.record(typ, sc.String(), group, pos, tags...)
+								}
+							case isHashOrCipher(sc):
+								// hashing and encryption are data manipulation too
+							case sc.Pkg != nil && isFilesystemPackage(sc.Pkg.Pkg) && inMemoryFilesystem(callCommon):
+								// files in memory or compiled into the program
+							case reads:
+								// an attribute of the request of the handler
+								c.record(MovementEntry, sc.String(), requested, pos, append(tags, TagRequest)...)
+							case writesResponse(callCommon):
+								// the response of the handler; marshalled bytes are
+								// the Exit of their Marshal
+								if !writesMarshalled(callCommon) {
+									c.record(MovementExit, sc.String(), "", pos, tags...)
+								}
+							case readsResponseBody(callCommon.Args):
+								// part of the Entry of the response
+							case matchesTable(sc, receiveFuncs) || matchesTable(sc, pollFuncs):
+								// messages exchanged with the user over an open connection
+								c.record(MovementEntry, sc.String(), dataGroupOf(callCommon), pos, tags...)
+							case matchesTable(sc, sendFuncs):
+								c.record(MovementExit, sc.String(), dataGroupOf(callCommon), pos, tags...)
+							case marshals:
+								// the encoded bytes move where they are written
+								if exits {
+									c.record(MovementExit, sc.String(), encoded, pos, tags...)
+								}
+							default:
+								if kind, group, ok := streamMovement(sc, callCommon); ok {
+									// a stream of known origin, wrapped or not
+									if kind != "" {
+										c.record(kind, sc.String(), group, pos, tags...)
+									}
+									break
+								}
+								if matchesExit(sc) {
+									c.record(MovementExit, sc.String(), "", pos, tags...)
+								}
+								if matchesRead(sc) {
+									c.record(MovementRead, sc.String(), dataGroupOf(callCommon), pos, tags...)
+								}
+								if matchesWrite(sc) {
+									c.record(MovementWrite, sc.String(), dataGroupOf(callCommon), pos, tags...)
+								}
+							}
+						}
+					}
+				}
+			}
+			if group, ok := fileGroups[fn]; ok {
+				c.nameFileReads(group)
+			}
+			if signals && !isMainFunc(fn) {
+				entryFuncsSet[fn] = entryPoint{trigger: "OS signal (os/signal.Notify)"}
+			}
+			if isInfrastructure(fn.Pkg) {
+				c.tag(TagInfrastructure)
+			}
+			if conf.isMetrics(fn.Pkg) {
+				// the delivery of metrics, traversed with -deps
+				c.tag(TagMetrics)
+			}
+			if generated.declares(fn) {
+				c.tag(TagGenerated)
+			}
+			c.uncount(cfg.uncounted()...)
+			localCounts.add(fn, c)
+		}
+	}
+
+	// Entry points declared in the -config file, by name or signature.
+	if len(conf.Entries) > 0 || len(conf.signatures) > 0 {
+		for _, ssaPkg := range ssaPkgs {
+			for _, fn := range packageFunctions(prog, ssaPkg) {
+				if _, isEntry := entryFuncsSet[fn]; isEntry {
+					continue
+				}
+				if e, ok := conf.declared(fn); ok {
+					entryFuncsSet[fn] = entryPoint{trigger: e.trigger()}
+				} else if sig := conf.entrySignatureOf(fn); sig != "" && fn.Parent() == nil {
+					entryFuncsSet[fn] = entryPoint{trigger: "matches entry signature " + sig}
+				}
+			}
+		}
+	}
+
+	// Batch functions run on the loader's goroutine: they are either processes
+	// of their own or attributed to the functions loading from them.
+	for bf := range batchFuncs {
+		if _, isEntry := entryFuncsSet[bf]; !isEntry && !cfg.attributeLoaders {
+			entryFuncsSet[bf] = entryPoint{trigger: "dataloader batch function"}
+		}
+	}
+
+	// Library mode: packages that are neither programs nor register handlers
+	// are sized through their exported API.
+	if cfg.library {
+		withEntries := map[*ssa.Package]bool{}
+		for fn := range entryFuncsSet {
+			withEntries[fn.Pkg] = true
+		}
+		for _, ssaPkg := range ssaPkgs {
+			if withEntries[ssaPkg] || ssaPkg.Pkg.Name() == "main" {
+				continue
+			}
+			for fn, trigger := range libraryAPI(prog, ssaPkg) {
+				entryFuncsSet[fn] = entryPoint{trigger: trigger}
+			}
+		}
+	}
+
+	// Test mode sizes the test code on its own: the test functions are the
+	// only processes.
+	if cfg.tests {
+		entryFuncsSet = map[*ssa.Function]entryPoint{}
+		for _, ssaPkg := range ssaPkgs {
+			for _, mem := range ssaPkg.Members {
+				if fn, ok := mem.(*ssa.Function); ok {
+					if kind := testFunctionKind(fn); kind != "" {
+						entryFuncsSet[fn] = entryPoint{trigger: "go test (" + kind + ")"}
+					}
+				}
+			}
+		}
+	}
+
+	// Build the output by traversing from entry functions.
+	entryFuncs := make([]*ssa.Function, 0, len(entryFuncsSet))
+	for fn := range entryFuncsSet {
+		entryFuncs = append(entryFuncs, fn)
+	}
+	sort.Slice(entryFuncs, func(i, j int) bool { return entryFuncs[i].String() < entryFuncs[j].String() })
+
+	// Non-pointer static traversal (previous behavior) follows StaticCallee edges.
+	succ := staticCallees
+	if cfg.ptr {
+		// Run pointer analysis to build callgraph (resolves interfaces & indirect calls).
+		ptrCfg := &pointer.Config{
+			Mains:          ssaPkgs,
+			BuildCallGraph: true,
+		}
+		res, err := pointer.Analyze(ptrCfg)
+		if err != nil {
+			return nil, fmt.Errorf("pointer.Analyze: %v", err)
+		}
+		cg := res.CallGraph
+		conf.withoutPortCalls(cg)
+		// Build mapping from *ssa.Function -> *callgraph.Node
+		funcToNode := map[*ssa.Function]*callgraph.Node{}
+		for _, n := range cg.Nodes {
+			if n.Func != nil {
+				funcToNode[n.Func] = n
+			}
+		}
+		succ = pointerCallees(funcToNode)
+	}
+	if cfg.init {
+		succ = withoutImportedInits(succ)
+	}
+	if len(spawned) > 0 {
+		succ = withoutSpawnedWorkers(succ, spawned)
+	}
+	// The closures of middleware are part of the processes they wrap.
+	for _, closures := range routes.wrappers {
+		for _, c := range closures {
+			localCounts.tag(c, TagInfrastructure, cfg.uncounted())
+		}
+	}
+	if len(routes.wrappers) > 0 {
+		succ = withCallees(succ, routes.wrappers)
+	}
+	if cfg.attributeLoaders && len(loads) > 0 {
+		succ = withCallees(succ, loads)
+	}
+	if len(callbacks) > 0 {
+		succ = withCallees(succ, callbacks)
+	}
+	if len(upgraders) > 0 && !cfg.tests {
+		// WebSocket handlers not reached from a detected entry are entries themselves.
+		g := newCallGraph(entryFuncs, succ)
+		for fn := range upgraders {
+			if _, reached := g.index[fn]; !reached {
+				entryFuncsSet[fn] = entryPoint{trigger: "WebSocket upgrade"}
+				entryFuncs = append(entryFuncs, fn)
+			}
+		}
+		sort.Slice(entryFuncs, func(i, j int) bool { return entryFuncs[i].String() < entryFuncs[j].String() })
+	}
+	var upper func(*ssa.Function) []*ssa.Function
+	if cfg.overapproximate {
+		upper = withCallees(succ, conf.interfaceCallees(prog))
+	}
+
+	return &analysis{
+		prog:        prog,
+		pkgs:        ssaPkgs,
+		entries:     entryFuncs,
+		entryPoints: entryFuncsSet,
+		localCounts: localCounts,
+		succ:        succ,
+		upper:       upper,
+		generated:   generated,
+		owners:      owners,
+		overrides:   overridden,
+		redactions:  conf.redactions,
+		code:        codePaths(pkgs),
+	}, nil
+}
+
+// processName returns the name of the process rooted at entry function fn,
+// with the function literals named by their role among entries.
+func processName(fn *ssa.Function, ep entryPoint, entries map[*ssa.Function]entryPoint) string {
+	name := fmt.Sprintf("%s.%s", fn.Pkg.Pkg.Path(), stableFuncName(fn, entries))
+	route := ep.route
+	if len(ep.methods) > 0 {
+		route = strings.TrimSpace(strings.Join(ep.methods, ",") + " " + route)
+	}
+	if route != "" {
+		name = route + " -> " + name
+	}
+	return name
+}
+
+// createImports creates, without function bodies, the SSA packages for the
+// transitive imports of tp that have not been created from syntax.
+func createImports(prog *ssa.Program, tp *types.Package) {
+	for _, imp := range tp.Imports() {
+		if prog.Package(imp) == nil {
+			prog.CreatePackage(imp, nil, nil, true)
+			createImports(prog, imp)
+		}
+	}
+}
+
+// traverseAll runs traverse for every entry function on up to workers goroutines.
+// Reports are returned in the order of fns.
+func traverseAll(fns []*ssa.Function, workers int, traverse func(*ssa.Function) ProcessReport) []ProcessReport {
+	reports := make([]ProcessReport, len(fns))
+	if workers < 1 {
+		workers = 1
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(fns); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				reports[i] = traverse(fns[i])
+			}
+		}()
+	}
+	for i := range fns {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return reports
+}
+
+// add accumulates the local counts of one function into the process report.
+func (pr *ProcessReport) add(c Counts) {
+	pr.Entries += c.Entries
+	pr.Exits += c.Exits
+	pr.Reads += c.Reads
+	pr.Writes += c.Writes
+	for _, m := range c.Movements {
+		if hasTag(m, TagSystem) {
+			pr.SystemEntries++
+		}
+		if m.DataGroup != "" {
+			pr.DataGroups = appendUnique(pr.DataGroups, m.DataGroup)
+		}
+	}
+	pr.Movements = append(pr.Movements, c.Movements...)
+}
+
+// record counts one data movement of the given type caused by a call to callee at pos.
+func (c *Counts) record(typ, callee, dataGroup string, pos token.Position, tags ...string) {
+	m := Movement{Type: typ, DataGroup: dataGroup, Callee: callee, Tags: tags}
+	if pos.IsValid() {
+		m.Pos = pos.String()
+	}
+	c.addMovement(m)
+}
+
+// addMovement counts movement m.
+func (c *Counts) addMovement(m Movement) {
+	switch m.Type {
+	case MovementEntry:
+		c.Entries++
+	case MovementExit:
+		c.Exits++
+	case MovementRead:
+		c.Reads++
+	case MovementWrite:
+		c.Writes++
+	}
+	c.Movements = append(c.Movements, m)
+}
+
+// tag adds tag to all movements recorded so far.
+func (c *Counts) tag(tag string) {
+	for i := range c.Movements {
+		c.Movements[i].Tags = appendUnique(c.Movements[i].Tags, tag)
+	}
+}
+
+// uncount removes the movements carrying one of tags from the counts. The
+// movements themselves are kept, so they remain visible in the detailed output.
+func (c *Counts) uncount(tags ...string) {
+	for _, m := range c.Movements {
+		if !hasAnyTag(m, tags) {
+			continue
+		}
+		switch m.Type {
+		case MovementEntry:
+			c.Entries--
+		case MovementExit:
+			c.Exits--
+		case MovementRead:
+			c.Reads--
+		case MovementWrite:
+			c.Writes--
+		}
+	}
+}
+
+// hasTag reports whether m carries tag.
+func hasTag(m Movement, tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyTag reports whether m carries one of tags.
+func hasAnyTag(m Movement, tags []string) bool {
+	for _, t := range tags {
+		if hasTag(m, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInfrastructure reports whether pkg is a known middleware package, or a
+// package of the analyzed code named middleware.
+func isInfrastructure(pkg *ssa.Package) bool {
+	if pkg == nil || pkg.Pkg == nil {
+		return false
+	}
+	p := pkg.Pkg.Path()
+	if path.Base(p) == "middleware" {
+		return true
+	}
+	for _, prefix := range infrastructurePackages {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRegistrationFunction returns true if the function is a known registration entry point.
 func isRegistrationFunction(fn *ssa.Function) bool {
 	if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg == nil {
 		return false
 	}
-	pkgPath := fn.Pkg.Pkg.Path()
+	pkgPath := fn.Pkg.Pkg.Path()
+	name := fn.Name()
+	if m, ok := entryRegistrations[pkgPath]; ok {
+		if m[name] {
+			return true
+		}
+	}
+	for _, suffix := range registrationNameSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveHTTPMethod returns the ServeHTTP method of the concrete type of v when v is
+// an http.Handler value such as &MyHandler{}. Only methods declared in the
+// analyzed packages count: routers and middleware from dependencies (including
+// nested *http.ServeMux values) also implement ServeHTTP but are not processes.
+func serveHTTPMethod(prog *ssa.Program, v ssa.Value, pkgs map[*ssa.Package]bool) *ssa.Function {
+	return interfaceMethod(prog, v, "ServeHTTP", pkgs)
+}
+
+// interfaceMethod returns the method name of the concrete type of interface
+// value v, if it is declared in one of pkgs.
+func interfaceMethod(prog *ssa.Program, v ssa.Value, name string, pkgs map[*ssa.Package]bool) *ssa.Function {
+	mi, ok := v.(*ssa.MakeInterface)
+	if !ok {
+		return nil
+	}
+	sel := prog.MethodSets.MethodSet(mi.X.Type()).Lookup(nil, name)
+	if sel == nil {
+		return nil
+	}
+	fn := prog.MethodValue(sel)
+	if fn == nil || !pkgs[fn.Pkg] {
+		return nil
+	}
+	return fn
+}
+
+// routeOf returns the full route path of a registration on router recv whose
+// first constant string argument is the (relative) path, or "" if there is none.
+// A leading HTTP method argument, as in gin Handle("GET", "/orders", h), is
+// skipped, as is the method of a pattern such as "GET /orders/{id}".
+func routeOf(recv ssa.Value, args []ssa.Value) string {
+	for _, arg := range args {
+		if path, ok := constString(arg); ok {
+			if httpMethods[path] {
+				continue
+			}
+			_, path = splitMethod(path)
+			prefix := routePrefix(recv)
+			if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, "/") {
+				prefix = prefix[:len(prefix)-1]
+			}
+			return prefix + path
+		}
+	}
+	return ""
+}
+
+// httpMethods are the request methods of HTTP.
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "CONNECT": true, "OPTIONS": true, "TRACE": true,
+}
+
+// splitMethod splits a route pattern such as "GET /orders/{id}" (net/http
+// since Go 1.22) into its HTTP method and path; the method is "" if absent.
+func splitMethod(pattern string) (method, path string) {
+	if m, rest, ok := strings.Cut(pattern, " "); ok && httpMethods[m] {
+		return m, strings.TrimLeft(rest, " ")
+	}
+	return "", pattern
+}
+
+// routeMethods returns the HTTP methods a route registration is restricted to:
+// the method named by the registration (gin GET, beego Get), a leading method
+// argument (Handle("GET", path, h)), the method of a pattern ("GET /orders")
+// or the methods set on the registered route (gorilla/mux Methods("GET")).
+// result is the value of the registration call, nil for go and defer.
+func routeMethods(name string, args []ssa.Value, result ssa.Value) []string {
+	if m := strings.ToUpper(name); httpMethods[m] {
+		return []string{m}
+	}
+	for _, arg := range args {
+		if s, ok := constString(arg); ok {
+			if httpMethods[s] {
+				return []string{s}
+			}
+			if m, _ := splitMethod(s); m != "" {
+				return []string{m}
+			}
+			break
+		}
+	}
+	if result == nil || result.Referrers() == nil {
+		return nil
+	}
+	var methods []string
+	for _, ref := range *result.Referrers() {
+		call, ok := ref.(*ssa.Call)
+		if !ok {
+			continue
+		}
+		sc := call.Call.StaticCallee()
+		if sc == nil || sc.Pkg == nil || sc.Pkg.Pkg.Path() != "github.com/gorilla/mux" || sc.Name() != "Methods" || len(call.Call.Args) != 2 {
+			continue
+		}
+		for _, m := range variadicStrings(call.Call.Args[1]) {
+			methods = appendUnique(methods, strings.ToUpper(m))
+		}
+	}
+	return methods
+}
+
+// variadicStrings returns the constant strings passed as the variadic
+// arguments of a call, as in Methods("GET", "HEAD").
+func variadicStrings(v ssa.Value) []string {
+	var strs []string
+	for _, arg := range variadicArgs(v) {
+		if s, ok := constString(arg); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// variadicArgs returns the values passed as the variadic arguments of a call,
+// in order, without their conversion to the element type of an ...any.
+func variadicArgs(v ssa.Value) []ssa.Value {
+	sl, ok := v.(*ssa.Slice)
+	if !ok {
+		return nil
+	}
+	alloc, ok := sl.X.(*ssa.Alloc)
+	if !ok {
+		return nil
+	}
+	var args []ssa.Value
+	for _, ref := range *alloc.Referrers() {
+		ia, ok := ref.(*ssa.IndexAddr)
+		if !ok {
+			continue
+		}
+		for _, r := range *ia.Referrers() {
+			if st, ok := r.(*ssa.Store); ok && st.Addr == ia {
+				val := st.Val
+				if mi, ok := val.(*ssa.MakeInterface); ok {
+					val = mi.X
+				}
+				args = append(args, val)
+			}
+		}
+	}
+	return args
+}
+
+// routePrefix returns the path prefix of router value v accumulated through
+// route group constructors such as r.PathPrefix("/api").Subrouter() or
+// r.Group("/v1").
+func routePrefix(v ssa.Value) string {
+	switch vv := v.(type) {
+	case *ssa.Call:
+		common := vv.Common()
+		var pkg *types.Package
+		var name string
+		recv, args := common.Value, common.Args
+		if common.IsInvoke() {
+			pkg, name = common.Method.Pkg(), common.Method.Name()
+		} else if sc := common.StaticCallee(); sc != nil && sc.Signature.Recv() != nil && len(args) > 0 && sc.Pkg != nil {
+			pkg, name = sc.Pkg.Pkg, sc.Name()
+			recv, args = args[0], args[1:]
+		}
+		if pkg == nil {
+			return ""
+		}
+		idx, ok := routeGroups[pkg.Path()][name]
+		if !ok {
+			return ""
+		}
+		prefix := routePrefix(recv)
+		if idx >= 0 && idx < len(args) {
+			if path, ok := constString(args[idx]); ok {
+				prefix += path
+			}
+		}
+		return prefix
+	case *ssa.FieldAddr:
+		// embedded router group, e.g. gin.Engine.RouterGroup
+		return routePrefix(vv.X)
+	case *ssa.MakeInterface:
+		return routePrefix(vv.X)
+	case *ssa.ChangeType:
+		return routePrefix(vv.X)
+	}
+	return ""
+}
+
+// scheduleRegistration returns the index of the spec argument if fn is a job
+// registration method of a scheduler.
+func scheduleRegistration(fn *ssa.Function) (int, bool) {
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil || fn.Signature.Recv() == nil {
+		return 0, false
+	}
+	idx, ok := scheduleRegistrations[fn.Pkg.Pkg.Path()][fn.Name()]
+	return idx, ok
+}
+
+// scheduleOf returns the schedule of a job registration: the constant spec
+// argument at idx, or for gocron the schedule built on the receiver.
+func scheduleOf(recv ssa.Value, args []ssa.Value, idx int) string {
+	if idx >= 0 {
+		if idx < len(args) {
+			spec, _ := constString(args[idx])
+			return spec
+		}
+		return ""
+	}
+	return gocronSchedule(recv)
+}
+
+// gocronSchedule renders a gocron schedule built by a call chain on the
+// scheduler, such as s.Every(5).Minutes() ("every 5 minutes") or
+// s.Cron("0 * * * *") ("0 * * * *").
+func gocronSchedule(v ssa.Value) string {
+	var words []string
+	for {
+		call, ok := v.(*ssa.Call)
+		if !ok {
+			break
+		}
+		sc := call.Call.StaticCallee()
+		if sc == nil || sc.Signature.Recv() == nil || len(call.Call.Args) == 0 {
+			break
+		}
+		recv, args := call.Call.Args[0], call.Call.Args[1:]
+		switch {
+		case sc.Name() == "Cron" || sc.Name() == "CronWithSeconds":
+			if spec, ok := constString(args[0]); ok {
+				return spec
+			}
+			return ""
+		case sc.Name() == "Every" && len(args) == 1:
+			every := "every"
+			arg := args[0]
+			if mi, ok := arg.(*ssa.MakeInterface); ok {
+				arg = mi.X
+			}
+			if c, ok := arg.(*ssa.Const); ok && c.Value != nil {
+				if isNamedType(c.Type(), "time", "Duration") {
+					if n, ok := constant.Int64Val(c.Value); ok {
+						every += " " + time.Duration(n).String()
+					}
+				} else {
+					every += " " + c.Value.ExactString()
+				}
+			}
+			return strings.Join(append([]string{every}, words...), " ")
+		case len(args) == 0 && sc.Name() != "SingletonMode":
+			words = append([]string{strings.ToLower(sc.Name())}, words...)
+		case sc.Name() == "At" && len(args) == 1:
+			if at, ok := constString(args[0]); ok {
+				words = append([]string{"at " + at}, words...)
+			}
+		}
+		v = recv
+	}
+	return strings.Join(words, " ")
+}
+
+// tickerInterval returns the interval of the first ticker or timer fn creates
+// with a constant duration, as "every 1m0s", or "".
+func tickerInterval(fn *ssa.Function) string {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			sc := call.Call.StaticCallee()
+			if sc == nil || sc.Pkg == nil || sc.Pkg.Pkg.Path() != "time" || len(call.Call.Args) != 1 {
+				continue
+			}
+			switch sc.Name() {
+			case "NewTicker", "Tick", "NewTimer", "After":
+				if c, ok := call.Call.Args[0].(*ssa.Const); ok && c.Value != nil {
+					if n, ok := constant.Int64Val(c.Value); ok {
+						return "every " + time.Duration(n).String()
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// constString returns the value of v if it is a constant string.
+func constString(v ssa.Value) (string, bool) {
+	c, ok := v.(*ssa.Const)
+	if !ok || c.Value == nil || c.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(c.Value), true
+}
+
+// isRegistrationMethod reports whether an interface method invoked dynamically
+// is a known registration entry point.
+func isRegistrationMethod(m *types.Func) bool {
+	if m == nil || m.Pkg() == nil {
+		return false
+	}
+	return entryRegistrations[m.Pkg().Path()][m.Name()]
+}
+
+// entryDataGroups returns the data groups entering through the given handlers:
+// one per CloudEvents event type they handle, or a single unnamed group.
+func entryDataGroups(handlers []*ssa.Function) []string {
+	var groups []string
+	for _, fn := range handlers {
+		for _, t := range cloudEventTypes(fn) {
+			groups = appendUnique(groups, t)
+		}
+	}
+	if len(groups) == 0 {
+		return []string{""}
+	}
+	return groups
+}
+
+// cloudEventTypes returns the event types fn compares the Type() of a
+// CloudEvent with, as in `if e.Type() == "com.example.order.created"` or a
+// switch on e.Type().
+func cloudEventTypes(fn *ssa.Function) []string {
+	isEventType := func(v ssa.Value) bool {
+		call, ok := v.(*ssa.Call)
+		if !ok {
+			return false
+		}
+		sc := call.Call.StaticCallee()
+		return sc != nil && sc.Name() == "Type" && sc.Signature.Recv() != nil &&
+			isNamedType(sc.Signature.Recv().Type(), cloudEventsEventPkg, "Event")
+	}
+	var found []string
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			bin, ok := instr.(*ssa.BinOp)
+			if !ok || bin.Op != token.EQL && bin.Op != token.NEQ {
+				continue
+			}
+			if s, ok := constString(bin.Y); ok && isEventType(bin.X) {
+				found = appendUnique(found, s)
+			} else if s, ok := constString(bin.X); ok && isEventType(bin.Y) {
+				found = appendUnique(found, s)
+			}
+		}
+	}
+	return found
+}
+
+// httpVerbRoutes returns the registration set of a router exposing one method per
+// HTTP verb (Get, Post, ...), plus the given extra names.
+func httpVerbRoutes(extra ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, n := range []string{"Get", "Post", "Put", "Delete", "Patch", "Head", "Options"} {
+		set[n] = true
+	}
+	for _, n := range extra {
+		set[n] = true
+	}
+	return set
+}
+
+// controllerArgument returns the controller value passed to a controller
+// registration (beego Router/AutoRouter), or nil if fn is not one.
+func controllerArgument(fn *ssa.Function, call *ssa.CallCommon) ssa.Value {
+	if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return nil
+	}
+	idx, ok := controllerRegistrations[fn.Pkg.Pkg.Path()][fn.Name()]
+	if !ok {
+		return nil
+	}
+	if fn.Signature.Recv() != nil {
+		idx++
+	}
+	if idx >= len(call.Args) {
+		return nil
+	}
+	v := call.Args[idx]
+	if mi, ok := v.(*ssa.MakeInterface); ok {
+		v = mi.X
+	}
+	return v
+}
+
+// controllerActions returns the exported methods declared on the controller type t
+// itself; methods promoted from the embedded framework controller and lifecycle
+// hooks are not actions.
+func controllerActions(prog *ssa.Program, t types.Type) []*ssa.Function {
+	var fns []*ssa.Function
+	if types.IsInterface(t) {
+		return nil
+	}
+	mset := prog.MethodSets.MethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		if !sel.Obj().Exported() || len(sel.Index()) > 1 || controllerLifecycleMethods[sel.Obj().Name()] {
+			continue
+		}
+		if fn := prog.MethodValue(sel); fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// cloudEventsEventPkg is the import path of the CloudEvents SDK event package.
+const cloudEventsEventPkg = "github.com/cloudevents/sdk-go/v2/event"
+
+// revelPkgPath is the import path of the revel framework.
+const revelPkgPath = "github.com/revel/revel"
+
+// grpcPkgPath is the import path of grpc-go.
+const grpcPkgPath = "google.golang.org/grpc"
+
+// kratosHTTPPkgPath is the import path of the go-kratos HTTP transport.
+const kratosHTTPPkgPath = "github.com/go-kratos/kratos/v2/transport/http"
+
+// startupTrigger is the trigger of the package initialization processes (-init).
+const startupTrigger = "startup (package initialization)"
+
+// isPackageInit reports whether fn is the synthetic initializer of its package,
+// which runs the package-level var initializers and the init functions.
+func isPackageInit(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Synthetic == "package initializer"
+}
+
+// withoutImportedInits drops the calls of a package initializer to the
+// initializers of the packages it imports, so that every package's startup is
+// its own process instead of being counted again in each importer.
+func withoutImportedInits(succ func(*ssa.Function) []*ssa.Function) func(*ssa.Function) []*ssa.Function {
+	return func(fn *ssa.Function) []*ssa.Function {
+		callees := succ(fn)
+		if !isPackageInit(fn) {
+			return callees
+		}
+		kept := callees[:0:0]
+		for _, callee := range callees {
+			if callee == nil || !isPackageInit(callee) {
+				kept = append(kept, callee)
+			}
+		}
+		return kept
+	}
+}
+
+// isWorkerLoop reports whether fn looks like a long-running goroutine body: it
+// contains a loop and receives from a channel (a work queue, a ticker) or selects.
+func isWorkerLoop(fn *ssa.Function) bool {
+	receives := false
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch ins := instr.(type) {
+			case *ssa.Select:
+				receives = true
+			case *ssa.UnOp:
+				receives = receives || ins.Op == token.ARROW
+			}
+		}
+	}
+	return receives && hasLoop(fn)
+}
+
+// hasLoop reports whether fn contains a loop, i.e. a jump back to an earlier block.
+func hasLoop(fn *ssa.Function) bool {
+	for _, b := range fn.Blocks {
+		for _, succ := range b.Succs {
+			if succ.Index <= b.Index {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isMainFunc reports whether fn is the main function of a program.
+func isMainFunc(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Pkg.Pkg != nil && fn.Pkg.Pkg.Name() == "main" && fn.Name() == "main" && fn.Signature.Recv() == nil
+}
+
+// signalReceives returns the number of receives from an os.Signal channel
+// performed by instr: a <-ch, or the receive cases of a select.
+func signalReceives(instr ssa.Instruction) int {
+	isSignalChan := func(v ssa.Value) bool {
+		ch, ok := v.Type().Underlying().(*types.Chan)
+		return ok && isNamedType(ch.Elem(), "os", "Signal")
+	}
+	n := 0
+	switch ins := instr.(type) {
+	case *ssa.UnOp:
+		if ins.Op == token.ARROW && isSignalChan(ins.X) {
+			n++
+		}
+	case *ssa.Select:
+		for _, st := range ins.States {
+			if st.Dir == types.RecvOnly && isSignalChan(st.Chan) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// receivesSignals reports whether fn receives from an os.Signal channel.
+func receivesSignals(fn *ssa.Function) bool {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if signalReceives(instr) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withoutSpawnedWorkers drops the edges from functions to the workers they
+// start, so that a worker's movements are counted in its own process only.
+func withoutSpawnedWorkers(succ func(*ssa.Function) []*ssa.Function, spawned map[*ssa.Function]map[*ssa.Function]bool) func(*ssa.Function) []*ssa.Function {
+	return func(fn *ssa.Function) []*ssa.Function {
+		callees := succ(fn)
+		workers := spawned[fn]
+		if workers == nil {
+			return callees
+		}
+		kept := callees[:0:0]
+		for _, callee := range callees {
+			if !workers[callee] {
+				kept = append(kept, callee)
+			}
+		}
+		return kept
+	}
+}
+
+// testFunctionKind returns "test", "benchmark" or "fuzz test" if fn is a test
+// function run by go test, and "" otherwise.
+func testFunctionKind(fn *ssa.Function) string {
+	if fn.Signature.Recv() != nil || fn.Signature.Params().Len() != 1 || fn.Signature.Results().Len() != 0 {
+		return ""
+	}
+	if !strings.HasSuffix(fn.Prog.Fset.Position(fn.Pos()).Filename, "_test.go") {
+		return ""
+	}
+	param := fn.Signature.Params().At(0).Type()
+	for _, k := range []struct{ prefix, typ, kind string }{
+		{"Test", "T", "test"},
+		{"Benchmark", "B", "benchmark"},
+		{"Fuzz", "F", "fuzz test"},
+	} {
+		rest, ok := strings.CutPrefix(fn.Name(), k.prefix)
+		if !ok || rest != "" && unicode.IsLower([]rune(rest)[0]) {
+			continue
+		}
+		if _, ptr := param.(*types.Pointer); ptr && isNamedType(param, "testing", k.typ) {
+			return k.kind
+		}
+	}
+	return ""
+}
+
+// isRevelAction reports whether fn is an action of a revel controller: an exported
+// method declared on a type embedding revel.Controller and returning revel.Result.
+// Revel dispatches actions from its routes file, so there is no registration call.
+func isRevelAction(fn *ssa.Function) bool {
+	recv := fn.Signature.Recv()
+	if recv == nil || !token.IsExported(fn.Name()) || fn.Synthetic != "" {
+		return false
+	}
+	res := fn.Signature.Results()
+	if res.Len() != 1 || !isNamedType(res.At(0).Type(), revelPkgPath, "Result") {
+		return false
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && isNamedType(f.Type(), revelPkgPath, "Controller") {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamedType reports whether t (or the type it points to) is the named type pkgPath.name.
+func isNamedType(t types.Type, pkgPath, name string) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkgPath && named.Obj().Name() == name
+}
+
+// serviceImplementation returns the implementation value passed to a service
+// registration (net/rpc Register/RegisterName, Twirp NewXxxServer, connect-go
+// NewXxxServiceHandler, gRPC and go-kratos RegisterXxxServer, goa
+// NewEndpoints), or nil if fn is not one. When the parameter is a
+// generated service interface it is returned too, naming the RPC methods.
+func serviceImplementation(fn *ssa.Function, call *ssa.CallCommon) (ssa.Value, *types.Interface) {
+	if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return nil, nil
+	}
+	idx, ok := serviceRegistrations[fn.Pkg.Pkg.Path()][fn.Name()]
+	if !ok {
+		switch {
+		case isTwirpConstructor(fn) || isConnectHandlerConstructor(fn):
+			idx = 0
+		case isGeneratedServerRegistration(fn):
+			idx = 1
+		case isGoaEndpointsConstructor(fn):
+			idx = 0
+		default:
+			return nil, nil
+		}
+	}
+	var api *types.Interface
+	if params := fn.Signature.Params(); idx < params.Len() {
+		if iface, ok := params.At(idx).Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+			api = iface
+		}
+	}
+	if fn.Signature.Recv() != nil {
+		idx++
+	}
+	if idx >= len(call.Args) {
+		return nil, nil
+	}
+	v := call.Args[idx]
+	if mi, ok := v.(*ssa.MakeInterface); ok {
+		v = mi.X
+	}
+	return v, api
+}
+
+// isTwirpConstructor reports whether fn is a Twirp generated server constructor,
+// i.e. NewXxxServer returning the generated TwirpServer interface.
+func isTwirpConstructor(fn *ssa.Function) bool {
+	name := fn.Name()
+	if !strings.HasPrefix(name, "New") || !strings.HasSuffix(name, "Server") {
+		return false
+	}
+	res := fn.Signature.Results()
+	if res.Len() != 1 {
+		return false
+	}
+	named, ok := res.At(0).Type().(*types.Named)
+	return ok && named.Obj().Name() == "TwirpServer"
+}
+
+// isConnectHandlerConstructor reports whether fn is a connect-go generated
+// handler constructor, i.e. NewXxxServiceHandler returning (path, http.Handler).
+func isConnectHandlerConstructor(fn *ssa.Function) bool {
 	name := fn.Name()
-	if m, ok := entryRegistrations[pkgPath]; ok {
-		if m[name] {
-			return true
+	if !strings.HasPrefix(name, "New") || !strings.HasSuffix(name, "ServiceHandler") {
+		return false
+	}
+	res := fn.Signature.Results()
+	if res.Len() != 2 {
+		return false
+	}
+	named, ok := res.At(1).Type().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "net/http" && named.Obj().Name() == "Handler"
+}
+
+// isGeneratedServerRegistration reports whether fn is a generated server
+// registration taking the server and the implementation: gRPC
+// RegisterXxxServer(grpc.ServiceRegistrar, XxxServer), which go-kratos gRPC
+// services use too, or go-kratos RegisterXxxHTTPServer(*http.Server, XxxHTTPServer).
+func isGeneratedServerRegistration(fn *ssa.Function) bool {
+	name := fn.Name()
+	params := fn.Signature.Params()
+	if !strings.HasPrefix(name, "Register") || !strings.HasSuffix(name, "Server") || fn.Signature.Recv() != nil || params.Len() != 2 {
+		return false
+	}
+	srv := params.At(0).Type()
+	return isNamedType(srv, grpcPkgPath, "ServiceRegistrar") || isNamedType(srv, grpcPkgPath, "Server") ||
+		isNamedType(srv, kratosHTTPPkgPath, "Server")
+}
+
+// mountedRoutes maps a service registration function (by name) to the route
+// pattern ("GET /path") of each method of the implementation, for generated registrations which mount
+// routes on wrappers of the implementation (goa, go-kratos HTTP).
+type mountedRoutes map[string]map[string]string
+
+// add records the first route of method of the services registered by reg.
+func (r mountedRoutes) add(reg, method, route string) {
+	if r[reg] == nil {
+		r[reg] = map[string]string{}
+	}
+	if _, seen := r[reg][method]; !seen {
+		r[reg][method] = route
+	}
+}
+
+// kratosHandlerRe matches the handler wrappers of go-kratos generated HTTP
+// servers, _Service_Method0_HTTP_Handler, capturing the method.
+var kratosHandlerRe = regexp.MustCompile(`^_[A-Za-z0-9]+_([A-Za-z0-9]+?)[0-9]+_HTTP_Handler$`)
+
+// collectKratos records the routes registered by fn if it is a go-kratos
+// generated RegisterXxxHTTPServer.
+func (r mountedRoutes) collectKratos(fn *ssa.Function) {
+	params := fn.Signature.Params()
+	if !isGeneratedServerRegistration(fn) || !isNamedType(params.At(0).Type(), kratosHTTPPkgPath, "Server") {
+		return
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			sc := call.Common().StaticCallee()
+			if !isRegistrationFunction(sc) {
+				continue
+			}
+			args := call.Common().Args
+			if len(args) == 0 {
+				continue
+			}
+			route := routeOf(args[0], args[1:])
+			if methods := routeMethods(sc.Name(), args[1:], nil); len(methods) == 1 && route != "" {
+				route = methods[0] + " " + route
+			}
+			for _, arg := range args[1:] {
+				if ct, ok := arg.(*ssa.ChangeType); ok {
+					arg = ct.X
+				}
+				h, ok := arg.(*ssa.Call)
+				if !ok || h.Call.StaticCallee() == nil {
+					continue
+				}
+				if m := kratosHandlerRe.FindStringSubmatch(h.Call.StaticCallee().Name()); m != nil && route != "" {
+					r.add(fn.String(), m[1], route)
+				}
+			}
 		}
 	}
-	combined := fmt.Sprintf("%s.%s", pkgPath, name)
-	for pk, m := range entryRegistrations {
-		for mn := range m {
-			if strings.HasSuffix(combined, fmt.Sprintf("%s.%s", pk, mn)) || strings.HasSuffix(name, mn) {
-				return true
+}
+
+// exportedMethods returns the functions implementing the exported methods of type t,
+// restricted to the methods of api when it is non-nil.
+func exportedMethods(prog *ssa.Program, t types.Type, api *types.Interface) []*ssa.Function {
+	var fns []*ssa.Function
+	if types.IsInterface(t) {
+		return nil
+	}
+	mset := prog.MethodSets.MethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		if !sel.Obj().Exported() {
+			continue
+		}
+		if api != nil {
+			if obj, _, _ := types.LookupFieldOrMethod(api, false, sel.Obj().Pkg(), sel.Obj().Name()); obj == nil {
+				continue
 			}
 		}
+		if fn := prog.MethodValue(sel); fn != nil {
+			fns = append(fns, fn)
+		}
 	}
-	return false
+	return fns
+}
+
+// libraryAPI returns the exported top-level functions of pkg and the exported
+// methods of its exported types, mapped to their trigger description.
+func libraryAPI(prog *ssa.Program, pkg *ssa.Package) map[*ssa.Function]string {
+	api := map[*ssa.Function]string{}
+	for name, mem := range pkg.Members {
+		if !token.IsExported(name) {
+			continue
+		}
+		switch m := mem.(type) {
+		case *ssa.Function:
+			api[m] = "library API (exported function)"
+		case *ssa.Type:
+			if _, ok := m.Type().(*types.Named); !ok {
+				continue
+			}
+			// Methods promoted to *T are wrappers; the declared ones are in T or *T.
+			for _, t := range []types.Type{m.Type(), types.NewPointer(m.Type())} {
+				for _, fn := range exportedMethods(prog, t, nil) {
+					if fn.Pkg == pkg && fn.Synthetic == "" {
+						api[fn] = "library API (exported method)"
+					}
+				}
+			}
+		}
+	}
+	return api
+}
+
+// packageFunctions returns the functions, methods and closures defined in pkg.
+func packageFunctions(prog *ssa.Program, pkg *ssa.Package) []*ssa.Function {
+	var fns []*ssa.Function
+	seen := map[*ssa.Function]bool{}
+	var add func(fn *ssa.Function)
+	add = func(fn *ssa.Function) {
+		if fn == nil || seen[fn] || fn.Pkg != pkg {
+			return
+		}
+		seen[fn] = true
+		fns = append(fns, fn)
+		for _, anon := range fn.AnonFuncs {
+			add(anon)
+		}
+	}
+	for _, mem := range pkg.Members {
+		switch m := mem.(type) {
+		case *ssa.Function:
+			add(m)
+		case *ssa.Type:
+			if named, ok := m.Type().(*types.Named); !ok || types.IsInterface(named) || named.TypeParams().Len() > 0 {
+				continue
+			}
+			for _, t := range []types.Type{m.Type(), types.NewPointer(m.Type())} {
+				mset := prog.MethodSets.MethodSet(t)
+				for i := 0; i < mset.Len(); i++ {
+					add(prog.MethodValue(mset.At(i)))
+				}
+			}
+		}
+	}
+	return fns
 }
 
 // matchesRead checks static callee against read function heuristics.
@@ -392,10 +2664,13 @@ func matchesRead(fn *ssa.Function) bool {
 			return true
 		}
 	}
-	if n == "Read" || n == "Scan" || n == "Query" || n == "QueryRow" {
-		return true
-	}
-	return false
+	return isReadName(n)
+}
+
+// isReadName reports whether a function or method name reads whatever its
+// package or receiver, as Read or Scan do.
+func isReadName(n string) bool {
+	return n == "Read" || n == "Scan" || n == "Query" || n == "QueryRow"
 }
 
 // matchesWrite checks static callee against write function heuristics.
@@ -410,12 +2685,92 @@ func matchesWrite(fn *ssa.Function) bool {
 			return true
 		}
 	}
-	if n == "Write" || n == "WriteString" || n == "Encode" || n == "Respond" || n == "Print" || n == "Printf" {
-		return true
+	return isWriteName(n)
+}
+
+// isWriteName reports whether a function or method name writes whatever its
+// package or receiver, as Write or Encode do.
+func isWriteName(n string) bool {
+	return n == "Write" || n == "WriteString" || n == "Encode" || n == "Respond" || n == "Print" || n == "Printf"
+}
+
+var (
+	// cryptoPackages are hashing and encryption packages, by path prefix.
+	cryptoPackages = []string{"crypto/", "golang.org/x/crypto/", "hash/"}
+	// secureTransports are the crypto packages whose connections move data.
+	secureTransports = []string{"crypto/tls", "golang.org/x/crypto/ssh"}
+)
+
+// isHashOrCipher reports whether fn belongs to a hashing or encryption package
+// (other than TLS and SSH connections) or is a method of a hash (a type with
+// the hash.Hash methods Sum and BlockSize, such as xxhash.Digest), whose Write
+// only feeds the digest.
+func isHashOrCipher(fn *ssa.Function) bool {
+	if fn.Pkg != nil && fn.Pkg.Pkg != nil {
+		p := fn.Pkg.Pkg.Path()
+		for _, prefix := range secureTransports {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return false
+			}
+		}
+		for _, prefix := range cryptoPackages {
+			if strings.HasPrefix(p, prefix) {
+				return true
+			}
+		}
+	}
+	recv := fn.Signature.Recv()
+	if recv == nil {
+		return false
+	}
+	for _, name := range []string{"Sum", "BlockSize"} {
+		if obj, _, _ := types.LookupFieldOrMethod(recv.Type(), true, nil, name); obj == nil {
+			return false
+		} else if _, ok := obj.(*types.Func); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesTable reports whether fn is listed in a classification table.
+func matchesTable(fn *ssa.Function, table map[string]map[string]bool) bool {
+	if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return false
+	}
+	return table[fn.Pkg.Pkg.Path()][fn.Name()]
+}
+
+// matchesMethodTable reports whether interface method m is listed in a classification table.
+func matchesMethodTable(m *types.Func, table map[string]map[string]bool) bool {
+	return m != nil && m.Pkg() != nil && table[m.Pkg().Path()][m.Name()]
+}
+
+// inPaths reports whether import path p is one of paths.
+func inPaths(p string, paths []string) bool {
+	for _, path := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyPrefix reports whether import path p starts with one of prefixes.
+func hasAnyPrefix(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
 	}
 	return false
 }
 
+// isUpgrade reports whether fn upgrades an HTTP connection to a WebSocket.
+func isUpgrade(fn *ssa.Function) bool {
+	return matchesTable(fn, upgradeFuncs)
+}
+
 // matchesExit checks static callee against exit heuristics.
 func matchesExit(fn *ssa.Function) bool {
 	if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg == nil {
@@ -434,21 +2789,374 @@ func matchesExit(fn *ssa.Function) bool {
 	return false
 }
 
-// extractFunctionFromValue attempts to find an *ssa.Function referenced by v.
-// It handles direct functions or closures (MakeClosure).
-func extractFunctionFromValue(v ssa.Value) *ssa.Function {
+// maxMiddlewareDepth bounds the middleware calls followed into their return
+// values to find the handlers they return.
+const maxMiddlewareDepth = 4
+
+// extractFunctionsFromValue attempts to find the *ssa.Functions referenced by v.
+// It handles direct functions, closures (MakeClosure), conversions to named
+// function types, the slices built for variadic handler chains, and struct
+// fields or map values read from route tables. The handlers wrapped by
+// middleware calls are recorded in routes.
+func extractFunctionsFromValue(v ssa.Value, routes *routeTable) []*ssa.Function {
+	return extractFunctions(v, routes, maxMiddlewareDepth, map[*ssa.Function]bool{})
+}
+
+// extractFunctions is extractFunctionsFromValue following up to depth
+// middleware calls into their return values, and not into those of the
+// middleware being followed.
+func extractFunctions(v ssa.Value, routes *routeTable, depth int, following map[*ssa.Function]bool) []*ssa.Function {
 	if v == nil {
 		return nil
 	}
 	switch vv := v.(type) {
 	case *ssa.MakeClosure:
 		if fn, ok := vv.Fn.(*ssa.Function); ok {
-			return fn
+			return []*ssa.Function{fn}
 		}
 	case *ssa.Function:
-		return vv
+		return []*ssa.Function{vv}
+	case *ssa.ChangeType:
+		// conversion to a named handler function type
+		return extractFunctions(vv.X, routes, depth, following)
+	case *ssa.MakeInterface:
+		// handler function passed as interface{} (CloudEvents, Lambda)
+		if _, ok := vv.X.Type().Underlying().(*types.Signature); ok {
+			return extractFunctions(vv.X, routes, depth, following)
+		}
+	case *ssa.Slice:
+		// variadic arguments: new [n]T; store each element; slice
+		alloc, ok := vv.X.(*ssa.Alloc)
+		if !ok {
+			return nil
+		}
+		var fns []*ssa.Function
+		for _, ref := range *alloc.Referrers() {
+			ia, ok := ref.(*ssa.IndexAddr)
+			if !ok {
+				continue
+			}
+			for _, r := range *ia.Referrers() {
+				if st, ok := r.(*ssa.Store); ok && st.Addr == ia {
+					fns = append(fns, extractFunctions(st.Val, routes, depth, following)...)
+				}
+			}
+		}
+		return fns
+	case *ssa.Field:
+		// r.Handler of a route table element copied by a range loop
+		if st, ok := vv.X.Type().Underlying().(*types.Struct); ok {
+			return routes.field(st.Field(vv.Field))
+		}
+	case *ssa.UnOp:
+		// routes[i].Handler
+		if fa, ok := vv.X.(*ssa.FieldAddr); ok && vv.Op == token.MUL {
+			return routes.field(fieldOf(fa))
+		}
+	case *ssa.Extract:
+		// value of a range over a map table
+		if next, ok := vv.Tuple.(*ssa.Next); ok && vv.Index == 2 {
+			if rng, ok := next.Iter.(*ssa.Range); ok {
+				return routes.mapValues(rng.X.Type())
+			}
+		}
+	case *ssa.Lookup:
+		// table[key]
+		return routes.mapValues(vv.X.Type())
+	case *ssa.Call:
+		// middleware(handler): the wrapped handlers are the processes, and
+		// the handlers the middleware returns, when its body is available,
+		// wrap them (see routeTable.wrappers); they are the processes
+		// themselves when the middleware wraps no known handler.
+		var wrapped, returned []*ssa.Function
+		for _, arg := range vv.Call.Args {
+			if _, ok := arg.Type().Underlying().(*types.Signature); ok {
+				wrapped = append(wrapped, extractFunctions(arg, routes, depth, following)...)
+			}
+		}
+		if callee := vv.Call.StaticCallee(); callee != nil && depth > 0 && !following[callee] {
+			following[callee] = true
+			for _, b := range callee.Blocks {
+				if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok && len(ret.Results) == 1 {
+					returned = append(returned, extractFunctions(ret.Results[0], routes, depth-1, following)...)
+				}
+			}
+			delete(following, callee)
+		}
+		if len(wrapped) == 0 {
+			return returned
+		}
+		if routes != nil {
+			for _, fn := range wrapped {
+				routes.wrap(fn, returned)
+			}
+		}
+		return wrapped
 	default:
 		// not directly resolvable here
 	}
 	return nil
 }
+
+// routeTable indexes the functions stored into struct fields (by field) and into
+// maps (by map type) by composite literals such as
+// `var routes = []Route{{"/a", handlerA}}` or `map[string]http.HandlerFunc{...}`.
+type routeTable struct {
+	fields map[*types.Var][]*ssa.Function
+	maps   map[string][]*ssa.Function
+	// wrappers maps the handlers passed to middleware, as in
+	// logging(getOrder), to the closures the middleware returns. A closure
+	// is part of the processes of the handlers it wraps, as infrastructure,
+	// rather than a process of its own.
+	wrappers map[*ssa.Function][]*ssa.Function
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		fields:   map[*types.Var][]*ssa.Function{},
+		maps:     map[string][]*ssa.Function{},
+		wrappers: map[*ssa.Function][]*ssa.Function{},
+	}
+}
+
+// wrap records that the middleware closures wrap handler fn.
+func (t *routeTable) wrap(fn *ssa.Function, closures []*ssa.Function) {
+	for _, c := range closures {
+		if c != fn && !slices.Contains(t.wrappers[fn], c) {
+			t.wrappers[fn] = append(t.wrappers[fn], c)
+		}
+	}
+}
+
+// collect records the functions fn stores into struct fields and maps.
+func (t *routeTable) collect(fn *ssa.Function) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch in := instr.(type) {
+			case *ssa.Store:
+				if fa, ok := in.Addr.(*ssa.FieldAddr); ok {
+					if f := fieldOf(fa); f != nil {
+						t.fields[f] = append(t.fields[f], extractFunctionsFromValue(in.Val, nil)...)
+					}
+				}
+			case *ssa.MapUpdate:
+				key := types.TypeString(in.Map.Type().Underlying(), nil)
+				t.maps[key] = append(t.maps[key], extractFunctionsFromValue(in.Value, nil)...)
+			}
+		}
+	}
+}
+
+// field returns the functions stored into field f; t may be nil.
+func (t *routeTable) field(f *types.Var) []*ssa.Function {
+	if t == nil || f == nil {
+		return nil
+	}
+	return t.fields[f]
+}
+
+// mapValues returns the functions stored into maps of type m; t may be nil.
+func (t *routeTable) mapValues(m types.Type) []*ssa.Function {
+	if t == nil {
+		return nil
+	}
+	return t.maps[types.TypeString(m.Underlying(), nil)]
+}
+
+// fieldOf returns the struct field addressed by fa.
+func fieldOf(fa *ssa.FieldAddr) *types.Var {
+	ptr, ok := fa.X.Type().Underlying().(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	st, ok := ptr.Elem().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	return st.Field(fa.Field)
+}
+
+// fieldStores returns the values stored into field name of the struct literal
+// v (a struct or a pointer to one, as in &s3.GetObjectInput{Bucket: b}, or a
+// slice of them).
+func fieldStores(v ssa.Value, name string) []ssa.Value {
+	switch vv := v.(type) {
+	case *ssa.MakeInterface:
+		return fieldStores(vv.X, name)
+	case *ssa.UnOp:
+		return fieldStores(vv.X, name)
+	case *ssa.Slice:
+		// the elements of a slice literal, as the variadic arguments of
+		// w.WriteMessages(ctx, kafka.Message{Topic: t})
+		var vals []ssa.Value
+		if refs := vv.X.Referrers(); refs != nil {
+			for _, ref := range *refs {
+				elem, ok := ref.(*ssa.IndexAddr)
+				if !ok || elem.Referrers() == nil {
+					continue
+				}
+				for _, r := range *elem.Referrers() {
+					if st, ok := r.(*ssa.Store); ok && st.Addr == elem {
+						vals = append(vals, fieldStores(st.Val, name)...)
+					}
+				}
+			}
+		}
+		return vals
+	}
+	alloc, ok := v.(*ssa.Alloc)
+	if !ok || alloc.Referrers() == nil {
+		return nil
+	}
+	var vals []ssa.Value
+	for _, ref := range *alloc.Referrers() {
+		fa, ok := ref.(*ssa.FieldAddr)
+		if !ok || fieldOf(fa) == nil || fieldOf(fa).Name() != name {
+			continue
+		}
+		for _, r := range *fa.Referrers() {
+			if st, ok := r.(*ssa.Store); ok && st.Addr == fa {
+				vals = append(vals, st.Val)
+			}
+		}
+	}
+	return vals
+}
+
+// dataGroupOf names the data group moved by a classified call, using the first
+// constant string argument (a file name, SQL statement or key). SQL statements
+// are reduced to the table they operate on. Returns "" when nothing can be named.
+func dataGroupOf(call *ssa.CallCommon) string {
+	if group, ok := websocketMessage(call); ok {
+		return group
+	}
+	if sc := call.StaticCallee(); sc != nil && isGormMethod(sc) {
+		return gormDataGroup(call)
+	}
+	if isAWSCall(call) {
+		if name := awsResource(call.Args, 0); name != "" {
+			return name
+		}
+	}
+	if isEtcdCall(call) {
+		return etcdKeyGroup(call.Args)
+	}
+	if isEmbeddedKVCall(call) {
+		return embeddedKVGroup(call)
+	}
+	if isProducerCall(call) {
+		return producerTopic(call.Args)
+	}
+	format := formatArg(call)
+	for i, arg := range call.Args {
+		s, ok := constString(arg)
+		if !ok || i == format {
+			continue
+		}
+		if m := sqlTableRe.FindStringSubmatch(s); m != nil {
+			return m[1]
+		}
+		return s
+	}
+	// a method of a handle obtained by name, e.g. client.Topic("orders").Publish(...),
+	// or of a reader or writer on a named file, e.g. csv.NewReader(f).ReadAll()
+	if sc := call.StaticCallee(); sc != nil && sc.Signature.Recv() != nil && len(call.Args) > 0 {
+		if name := receiverName(call.Args[0]); name != "" {
+			return name
+		}
+		return openedFile(call.Args[0], 0)
+	}
+	return ""
+}
+
+// formatArg returns the index in call.Args of the format string of a
+// printf-like callee, such as log.Printf or (*zap.SugaredLogger).Infof, or
+// -1: a format describes the message, it does not name a data group.
+func formatArg(call *ssa.CallCommon) int {
+	sig := call.Signature()
+	params := sig.Params()
+	n := params.Len()
+	if !sig.Variadic() || n < 2 {
+		return -1
+	}
+	if b, ok := params.At(n - 2).Type().Underlying().(*types.Basic); !ok || b.Kind() != types.String {
+		return -1
+	}
+	name := ""
+	if call.IsInvoke() {
+		name = call.Method.Name()
+	} else if sc := call.StaticCallee(); sc != nil {
+		name = sc.Name()
+	}
+	if params.At(n-2).Name() != "format" && !strings.HasSuffix(name, "f") {
+		return -1
+	}
+	// a static method call passes its receiver as the first argument
+	if !call.IsInvoke() && sig.Recv() != nil {
+		return n - 1
+	}
+	return n - 2
+}
+
+// openedFile returns the constant name of the file a reader or writer was
+// built on, following constructors such as csv.NewReader(f) or
+// xml.NewDecoder(bufio.NewReader(f)) back to an os.Open or os.Create call,
+// or to the Open or Create of a filesystem abstraction.
+func openedFile(v ssa.Value, depth int) string {
+	if depth > 8 {
+		return ""
+	}
+	switch vv := v.(type) {
+	case *ssa.MakeInterface:
+		return openedFile(vv.X, depth+1)
+	case *ssa.ChangeInterface:
+		return openedFile(vv.X, depth+1)
+	case *ssa.Extract:
+		return openedFile(vv.Tuple, depth+1)
+	case *ssa.Call:
+		if m := vv.Call.Method; vv.Call.IsInvoke() && isFilesystemPackage(m.Pkg()) && streamOpeners[m.Pkg().Path()][m.Name()] != "" && len(vv.Call.Args) > 0 {
+			name, _ := constString(vv.Call.Args[0])
+			return name
+		}
+		sc := vv.Call.StaticCallee()
+		if sc == nil || sc.Pkg == nil || sc.Signature.Recv() != nil || len(vv.Call.Args) == 0 {
+			return ""
+		}
+		if sc.Pkg.Pkg.Path() == "os" && (sc.Name() == "Open" || sc.Name() == "Create" || sc.Name() == "OpenFile") {
+			name, _ := constString(vv.Call.Args[0])
+			return name
+		}
+		if strings.HasPrefix(sc.Name(), "New") {
+			return openedFile(vv.Call.Args[0], depth+1)
+		}
+	}
+	return ""
+}
+
+// receiverName returns the constant name a handle was obtained with, as in
+// client.Topic("orders") or client.Subscription("orders-sub"), or "".
+func receiverName(v ssa.Value) string {
+	call, ok := v.(*ssa.Call)
+	if !ok {
+		return ""
+	}
+	args := call.Call.Args
+	if sc := call.Call.StaticCallee(); sc != nil && sc.Signature.Recv() != nil && len(args) > 0 {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	name, _ := constString(args[0])
+	return name
+}
+
+// appendUnique appends s to list unless it is already present.
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
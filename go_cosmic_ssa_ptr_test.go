@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// TestNestedCallArguments checks that the movements made in the arguments of
+// a call are counted with the call's, each at the parenthesis of its own
+// call: w.Write(must(os.ReadFile(r.PathValue("id")))) on one line is an
+// Entry, a Read and an Exit.
+func TestNestedCallArguments(t *testing.T) {
+	pr := processBySource(t, measureFixture(t, "nested"), "example.com/nested.getItem")
+	if pr.Entries != 1 || pr.Exits != 1 || pr.Reads != 1 || pr.Writes != 0 {
+		t.Errorf("E, X, R, W = %d, %d, %d, %d, want 1, 1, 1, 0", pr.Entries, pr.Exits, pr.Reads, pr.Writes)
+	}
+	file, err := filepath.Abs("testdata/nested/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"(*net/http.Request).PathValue":   fmt.Sprintf("%s:16:38", file),
+		"os.ReadFile":                     fmt.Sprintf("%s:16:26", file),
+		"(net/http.ResponseWriter).Write": fmt.Sprintf("%s:16:9", file),
+	}
+	for _, m := range pr.Movements {
+		if pos, ok := want[m.Callee]; ok {
+			if m.Pos != pos {
+				t.Errorf("%s at %s, want %s", m.Callee, m.Pos, pos)
+			}
+			delete(want, m.Callee)
+		}
+	}
+	for callee := range want {
+		t.Errorf("no movement of %s", callee)
+	}
+}
+
+// TestFormatStringDataGroup checks that the format of a printf-like call, as
+// the log.Printf("%s %s", ...) of the middleware fixture, is not taken for its
+// data group.
+func TestFormatStringDataGroup(t *testing.T) {
+	pr := processBySource(t, measureFixture(t, "middleware"), "example.com/shop.getOrder")
+	for _, m := range pr.Movements {
+		if m.Callee == "log.Printf" && m.DataGroup != "" {
+			t.Errorf("log.Printf has data group %q", m.DataGroup)
+		}
+	}
+	for _, g := range pr.DataGroups {
+		if strings.Contains(g, "%") {
+			t.Errorf("data group %q is a format", g)
+		}
+	}
+}
+
+// TestCountsTableTag checks that tagging the movements of a function updates
+// its entry of the table in place, once however often it is tagged.
+func TestCountsTableTag(t *testing.T) {
+	wrapper, handler := &ssa.Function{}, &ssa.Function{}
+	table := newCountsTable()
+	// the write is left out of the counts already
+	table.add(wrapper, Counts{Entries: 1, Exits: 1, Movements: []Movement{
+		{Type: MovementEntry, Callee: "(*net/http.Request).FormValue"},
+		{Type: MovementWrite, Callee: "(*database/sql.DB).Exec", Tags: []string{TagInfrastructure}},
+		{Type: MovementExit, Callee: "log.Printf", Tags: []string{TagLogging}},
+	}})
+	table.add(handler, Counts{Reads: 1, Movements: []Movement{{Type: MovementRead, Callee: "os.ReadFile"}}})
+	for range 2 {
+		table.tag(wrapper, TagInfrastructure, []string{TagInfrastructure})
+	}
+	if len(table.counts) != 2 || len(table.movements) != 4 {
+		t.Errorf("the table grew to %d functions and %d movements, want 2 and 4", len(table.counts), len(table.movements))
+	}
+	id, _ := table.id(wrapper)
+	c := table.get(id)
+	if c.Entries != 0 || c.Exits != 0 || c.Writes != 0 {
+		t.Errorf("E, X, W = %d, %d, %d, want all left out", c.Entries, c.Exits, c.Writes)
+	}
+	var tags []string
+	for _, m := range c.Movements {
+		tags = append(tags, strings.Join(m.Tags, "+"))
+	}
+	if want := []string{TagInfrastructure, TagInfrastructure, TagLogging + "+" + TagInfrastructure}; strings.Join(tags, " ") != strings.Join(want, " ") {
+		t.Errorf("tags %v, want %v", tags, want)
+	}
+	id, _ = table.id(handler)
+	if c := table.get(id); c.Reads != 1 || len(c.Movements[0].Tags) != 0 {
+		t.Errorf("the handler is tagged too")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// Cloud object storage support: Google Cloud Storage and Azure Blob Storage.
+// Objects are persistent storage, read and written through handles derived
+// from a client, as in client.Bucket("invoices").Object(name).NewReader(ctx);
+// the data group is the bucket or container the handle was derived from.
+// Only the operations listed move data: reading from the returned
+// *storage.Reader is part of the Read that opened it.
+
+var (
+	// cloudStorageReads and cloudStorageWrites are the object operations by
+	// package path.
+	cloudStorageReads = map[string]map[string]bool{
+		"cloud.google.com/go/storage": {
+			"NewReader":      true,
+			"NewRangeReader": true,
+			"Attrs":          true,
+			"Objects":        true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob": {
+			"DownloadBuffer":        true,
+			"DownloadFile":          true,
+			"DownloadStream":        true,
+			"NewListBlobsFlatPager": true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob": {
+			"DownloadBuffer": true,
+			"DownloadFile":   true,
+			"DownloadStream": true,
+			"GetProperties":  true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob": {
+			"DownloadBuffer": true,
+			"DownloadFile":   true,
+			"DownloadStream": true,
+			"GetProperties":  true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container": {
+			"NewListBlobsFlatPager":      true,
+			"NewListBlobsHierarchyPager": true,
+		},
+		"github.com/Azure/azure-storage-blob-go/azblob": {
+			"DownloadBlobToBuffer": true,
+			"DownloadBlobToFile":   true,
+			"Download":             true,
+			"ListBlobsFlatSegment": true,
+		},
+	}
+	cloudStorageWrites = map[string]map[string]bool{
+		"cloud.google.com/go/storage": {
+			"NewWriter": true,
+			"Delete":    true,
+			"Update":    true,
+			"Run":       true, // Copier and Composer
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob": {
+			"UploadBuffer": true,
+			"UploadFile":   true,
+			"UploadStream": true,
+			"DeleteBlob":   true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob": {
+			"Delete":           true,
+			"SetMetadata":      true,
+			"StartCopyFromURL": true,
+			"CopyFromURL":      true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob": {
+			"Upload":          true,
+			"UploadBuffer":    true,
+			"UploadFile":      true,
+			"UploadStream":    true,
+			"StageBlock":      true,
+			"CommitBlockList": true,
+			"Delete":          true,
+		},
+		"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob": {
+			"AppendBlock": true,
+		},
+		"github.com/Azure/azure-storage-blob-go/azblob": {
+			"UploadBufferToBlockBlob": true,
+			"UploadFileToBlockBlob":   true,
+			"UploadStreamToBlockBlob": true,
+			"Upload":                  true,
+			"Delete":                  true,
+			"StageBlock":              true,
+			"CommitBlockList":         true,
+		},
+	}
+
+	// storageContainerHandles are the methods deriving a handle from a bucket
+	// or container name.
+	storageContainerHandles = map[string]bool{"Bucket": true, "NewContainerClient": true, "NewContainerURL": true}
+)
+
+// isCloudStorageClient reports whether fn belongs to a cloud storage client package.
+func isCloudStorageClient(fn *ssa.Function) bool {
+	if fn.Pkg == nil || fn.Pkg.Pkg == nil {
+		return false
+	}
+	p := fn.Pkg.Pkg.Path()
+	return cloudStorageReads[p] != nil || cloudStorageWrites[p] != nil
+}
+
+// cloudStorageCall returns the movement type and data group of a call of a
+// cloud storage operation, or "" for the other functions of the clients.
+func cloudStorageCall(sc *ssa.Function, call *ssa.CallCommon) (typ, group string) {
+	switch {
+	case matchesTable(sc, cloudStorageReads):
+		typ = MovementRead
+	case matchesTable(sc, cloudStorageWrites):
+		typ = MovementWrite
+	default:
+		return "", ""
+	}
+	if sc.Signature.Recv() != nil && len(call.Args) > 0 {
+		if group = storageContainer(call.Args[0], 0); group != "" {
+			return typ, group
+		}
+	}
+	// azblob.Client.UploadBuffer(ctx, "container", "blob", ...)
+	return typ, dataGroupOf(call)
+}
+
+// storageContainer returns the bucket or container name a storage handle v
+// was derived from, following Object, NewBlockBlobClient and the like up to
+// Bucket("invoices") or NewContainerClient("invoices"), or "".
+func storageContainer(v ssa.Value, depth int) string {
+	call, ok := v.(*ssa.Call)
+	if !ok || depth > 8 {
+		return ""
+	}
+	sc := call.Call.StaticCallee()
+	args := call.Call.Args
+	if sc == nil || sc.Signature.Recv() == nil || len(args) == 0 || !isCloudStorageClient(sc) {
+		return ""
+	}
+	if storageContainerHandles[sc.Name()] && len(args) > 1 {
+		s, _ := constString(args[1])
+		return s
+	}
+	return storageContainer(args[0], depth+1)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Streams. The name heuristics count any Read or Write method as a Read or
+// Write, whatever the receiver: a bytes.Buffer filled in memory counts, and
+// bufio's ReadString on a file does not. A method reading or writing a value
+// whose type implements io.Reader or io.Writer, or that wraps one (bufio,
+// gzip, csv, a JSON encoder), is instead classified by the stream at the end
+// of the wrapping: a file is a Read or Write, the standard input an Entry and
+// the standard output an Exit, a network connection an Entry or Exit, and an
+// in-memory buffer no movement. A stream of unknown origin, such as a
+// parameter, is left to the name heuristics.
+
+// Stream origins.
+const (
+	streamMemory = "memory"
+	streamStdin  = "stdin"
+	streamStdout = "stdout"
+	streamFile   = "file"
+	streamConn   = "conn"
+	// streamResponse is an HTTP response.
+	streamResponse = "response"
+)
+
+var (
+	ioReaderType = ioInterface("Read")
+	ioWriterType = ioInterface("Write")
+)
+
+// ioInterface returns the interface of io.Reader or io.Writer, with the method
+// name.
+func ioInterface(name string) *types.Interface {
+	p := types.NewVar(0, nil, "p", types.NewSlice(types.Typ[types.Byte]))
+	results := types.NewTuple(types.NewVar(0, nil, "n", types.Typ[types.Int]), types.NewVar(0, nil, "err", types.Universe.Lookup("error").Type()))
+	sig := types.NewSignatureType(nil, nil, nil, types.NewTuple(p), results, false)
+	return types.NewInterfaceType([]*types.Func{types.NewFunc(0, nil, name, sig)}, nil).Complete()
+}
+
+// isStream reports whether a value of type t or *t is an io.Reader or
+// io.Writer.
+func isStream(t types.Type) bool {
+	for _, iface := range []*types.Interface{ioReaderType, ioWriterType} {
+		if types.Implements(t, iface) {
+			return true
+		}
+		if _, ok := t.Underlying().(*types.Interface); !ok && types.Implements(types.NewPointer(t), iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryStreams are the in-memory readers and writers, by package path.
+var memoryStreams = map[string]map[string]bool{
+	"bytes":   {"Buffer": true, "Reader": true},
+	"strings": {"Reader": true, "Builder": true},
+}
+
+// streamOpeners are the functions opening a stream, by package path, with its
+// origin.
+var streamOpeners = map[string]map[string]string{
+	"os": {"Open": streamFile, "OpenFile": streamFile, "Create": streamFile, "CreateTemp": streamFile},
+	"net": {
+		"Dial": streamConn, "DialTimeout": streamConn, "DialTCP": streamConn, "DialUDP": streamConn,
+		"DialUnix": streamConn, "Accept": streamConn, "AcceptTCP": streamConn, "AcceptUnix": streamConn,
+		"DialContext": streamConn,
+	},
+	"crypto/tls": {"Dial": streamConn, "DialWithDialer": streamConn, "Client": streamConn, "Server": streamConn},
+	"bytes":      {"NewBuffer": streamMemory, "NewBufferString": streamMemory, "NewReader": streamMemory},
+	"strings":    {"NewReader": streamMemory},
+	aferoPkgPath: {"Open": streamFile, "OpenFile": streamFile, "Create": streamFile},
+	ioFSPkgPath:  {"Open": streamFile},
+}
+
+// stdStreams are the os variables of the standard streams, with their origin.
+var stdStreams = map[string]string{"Stdin": streamStdin, "Stdout": streamStdout, "Stderr": streamStdout}
+
+// streamMovement returns the movement of the call of method sc reading or
+// writing a stream of known origin and its data group, "" if the stream is
+// in memory, and whether the stream's origin decided it. An encoder writing
+// into a stream of unknown origin is no movement either.
+func streamMovement(sc *ssa.Function, call *ssa.CallCommon) (kind, group string, ok bool) {
+	if sc.Signature.Recv() == nil || len(call.Args) == 0 {
+		return "", "", false
+	}
+	n := sc.Name()
+	reads := strings.HasPrefix(n, "Read") || isReadName(n) || n == "Decode"
+	writes := strings.HasPrefix(n, "Write") || isWriteName(n)
+	if !reads && !writes {
+		return "", "", false
+	}
+	// the files are named by their opening; a message after its type
+	message := dataGroupOf(call)
+	if isEncode(sc) {
+		if g := encodedGroup(sc, call); g != "" {
+			message = g
+		}
+	}
+	switch streamOrigin(call.Args[0], 0) {
+	case streamMemory:
+		return "", "", true
+	case streamFile:
+		if reads {
+			return MovementRead, dataGroupOf(call), true
+		}
+		return MovementWrite, dataGroupOf(call), true
+	case streamStdin:
+		if reads {
+			return MovementEntry, message, true
+		}
+	case streamStdout, streamResponse:
+		if writes {
+			return MovementExit, message, true
+		}
+	case streamConn:
+		if reads {
+			return MovementEntry, message, true
+		}
+		return MovementExit, message, true
+	}
+	if isEncode(sc) {
+		return "", "", true
+	}
+	return "", "", false
+}
+
+// streamOrigin follows the stream v through conversions and wrapping
+// constructors to the stream it reads or writes, and returns its origin, or
+// "" if unknown.
+func streamOrigin(v ssa.Value, depth int) string {
+	if depth > 8 || v == nil {
+		return ""
+	}
+	if named, ok := derefType(v.Type()).(*types.Named); ok && named.Obj().Pkg() != nil && memoryStreams[named.Obj().Pkg().Path()][named.Obj().Name()] {
+		return streamMemory
+	}
+	if isResponseWriter(v.Type()) {
+		return streamResponse
+	}
+	switch v := v.(type) {
+	case *ssa.MakeInterface:
+		return streamOrigin(v.X, depth+1)
+	case *ssa.ChangeInterface:
+		return streamOrigin(v.X, depth+1)
+	case *ssa.ChangeType:
+		return streamOrigin(v.X, depth+1)
+	case *ssa.TypeAssert:
+		return streamOrigin(v.X, depth+1)
+	case *ssa.Extract:
+		return streamOrigin(v.Tuple, depth+1)
+	case *ssa.UnOp:
+		if g, ok := v.X.(*ssa.Global); ok && g.Pkg != nil && g.Pkg.Pkg.Path() == "os" {
+			return stdStreams[g.Name()]
+		}
+	case *ssa.Phi:
+		origin := ""
+		for i, e := range v.Edges {
+			o := streamOrigin(e, depth+1)
+			if o == "" || i > 0 && o != origin {
+				return ""
+			}
+			origin = o
+		}
+		return origin
+	case *ssa.Call:
+		if m := v.Call.Method; v.Call.IsInvoke() && m.Pkg() != nil {
+			// Fs.Open, FS.Open
+			return streamOpeners[m.Pkg().Path()][m.Name()]
+		}
+		sc := v.Call.StaticCallee()
+		if sc == nil || sc.Pkg == nil {
+			return ""
+		}
+		if o := streamOpeners[sc.Pkg.Pkg.Path()][sc.Name()]; o != "" {
+			return o
+		}
+		// a constructor wrapping its first stream argument: bufio.NewReader,
+		// gzip.NewWriter, json.NewEncoder
+		if sc.Signature.Recv() != nil || !strings.HasPrefix(sc.Name(), "New") {
+			return ""
+		}
+		for _, arg := range v.Call.Args {
+			if isStream(arg.Type()) {
+				return streamOrigin(arg, depth+1)
+			}
+		}
+	}
+	return ""
+}
+
+// derefType returns the element type of pointer type t, or t.
+func derefType(t types.Type) types.Type {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
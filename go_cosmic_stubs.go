@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Analyst-editable regions in a stub are delimited by these markers. Regions are
+// keyed by name and carried over when the stubs are regenerated, so the
+// measured part of a stub always follows the code while the business
+// descriptions written by analysts are kept.
+const (
+	stubBeginMarker = "<!-- cosmic:begin %s -->"
+	stubEndMarker   = "<!-- cosmic:end %s -->"
+	stubTODO        = "_TODO: describe._"
+)
+
+var (
+	stubRegionRe = regexp.MustCompile(`(?s)<!-- cosmic:begin (\S+) -->\n(.*?)\n?<!-- cosmic:end (\S+) -->`)
+	stubNameRe   = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// stubsSink writes one Markdown documentation stub per functional process into
+// dir. The regions of a stub are carried over from the stub of the same
+// process ID, which is replaced once the process is renamed.
+type stubsSink struct {
+	dir string
+	// previous maps the process IDs to the file names of the stubs found in
+	// dir, written maps the file names of the stubs written to their IDs.
+	previous, written map[string]string
+}
+
+// NewStubsSink returns a Sink writing a documentation stub per process into dir.
+func NewStubsSink(dir string) Sink {
+	return &stubsSink{dir: dir, previous: map[string]string{}, written: map[string]string{}}
+}
+
+func (s *stubsSink) WriteHeader(Header) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		base, ok := strings.CutSuffix(e.Name(), ".md")
+		if i := strings.LastIndex(base, "-"); ok && i >= 0 {
+			s.previous[base[i+1:]] = e.Name()
+		}
+	}
+	return nil
+}
+
+func (s *stubsSink) WriteProcess(pr ProcessReport) error {
+	name := stubFileName(pr.Name, pr.ID)
+	if id, ok := s.written[name]; ok {
+		return fmt.Errorf("the stubs of processes %s and %s are both named %s", id, pr.ID, name)
+	}
+	s.written[name] = pr.ID
+	prev, ok := s.previous[pr.ID]
+	if !ok || pr.ID == "" {
+		prev = name
+	}
+	regions := map[string]string{}
+	if old, err := os.ReadFile(filepath.Join(s.dir, prev)); err == nil {
+		regions = readStubRegions(string(old))
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, name), []byte(renderStub(pr, regions)), 0o644); err != nil {
+		return err
+	}
+	if _, ok := s.written[prev]; !ok {
+		return os.Remove(filepath.Join(s.dir, prev))
+	}
+	return nil
+}
+
+func (s *stubsSink) WriteMovement(ProcessReport, Movement) error { return nil }
+
+func (s *stubsSink) Close() error { return nil }
+
+// stubFileName maps a process name and ID to a file name that is safe on all
+// platforms. The ID tells apart the processes whose names only differ in the
+// characters replaced.
+func stubFileName(name, id string) string {
+	base := strings.Trim(stubNameRe.ReplaceAllString(name, "_"), "_")
+	if id != "" {
+		base += "-" + id
+	}
+	return base + ".md"
+}
+
+// readStubRegions extracts the analyst-editable regions of an existing stub.
+func readStubRegions(doc string) map[string]string {
+	regions := map[string]string{}
+	for _, m := range stubRegionRe.FindAllStringSubmatch(doc, -1) {
+		if m[1] == m[3] {
+			regions[m[1]] = m[2]
+		}
+	}
+	return regions
+}
+
+// renderStub renders the stub of one process, reusing previously written regions.
+func renderStub(pr ProcessReport, regions map[string]string) string {
+	var b strings.Builder
+	region := func(key string) {
+		text, ok := regions[key]
+		if !ok || strings.TrimSpace(text) == "" {
+			text = stubTODO
+		}
+		fmt.Fprintf(&b, stubBeginMarker+"\n%s\n"+stubEndMarker+"\n", key, text, key)
+	}
+
+	fmt.Fprintf(&b, "# Functional process: %s\n\n", pr.Name)
+	if pr.Source != "" {
+		fmt.Fprintf(&b, "Source: `%s`\n\n", pr.Source)
+	}
+	b.WriteString("## Description\n\n")
+	region("description")
+
+	b.WriteString("\n## Triggering event\n\n")
+	trigger := pr.Trigger
+	if trigger == "" {
+		trigger = "unknown"
+	}
+	fmt.Fprintf(&b, "Detected: %s\n\n", trigger)
+	if pr.Generated {
+		b.WriteString("Entered through generated code.\n\n")
+	}
+	if pr.Schedule != "" {
+		fmt.Fprintf(&b, "Schedule: `%s`\n\n", pr.Schedule)
+	}
+	region("trigger")
+
+	b.WriteString("\n## Data movements\n\n")
+	b.WriteString("| Type | Count |\n|------|------:|\n")
+	fmt.Fprintf(&b, "| Entries (E) | %d |\n", pr.Entries)
+	fmt.Fprintf(&b, "| Exits (X) | %d |\n", pr.Exits)
+	fmt.Fprintf(&b, "| Reads (R) | %d |\n", pr.Reads)
+	fmt.Fprintf(&b, "| Writes (W) | %d |\n", pr.Writes)
+	fmt.Fprintf(&b, "| **Total (CFP)** | **%d** |\n", pr.Entries+pr.Exits+pr.Reads+pr.Writes)
+	if pr.SystemEntries > 0 {
+		fmt.Fprintf(&b, "\nEntries from the clock and the random source: %d\n", pr.SystemEntries)
+	}
+	if h := pr.Handwritten; h != nil {
+		fmt.Fprintf(&b, "\nWithout the movements of generated code: %d CFP\n", h.Entries+h.Exits+h.Reads+h.Writes)
+	}
+	if pr.CFPUpperBound > 0 {
+		fmt.Fprintf(&b, "\nUpper bound, with every implementation of the interfaces called: %d CFP\n", pr.CFPUpperBound)
+	}
+	if pr.Bounds != nil {
+		fmt.Fprintf(&b, "\nHeuristic grade, measured without type information: between %d and %d CFP\n", pr.Bounds.Low, pr.Bounds.High)
+	}
+
+	b.WriteString("\n## Data groups\n\n")
+	if len(pr.DataGroups) == 0 {
+		b.WriteString("No data groups could be named from the code.\n\n")
+		region("data-groups")
+	}
+	for _, dg := range pr.DataGroups {
+		fmt.Fprintf(&b, "### %s\n\n", dg)
+		region("data-group:" + stubNameRe.ReplaceAllString(dg, "_"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
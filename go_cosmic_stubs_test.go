@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeStubs writes the stubs of prs into dir.
+func writeStubs(t *testing.T, dir string, prs ...ProcessReport) error {
+	t.Helper()
+	s := NewStubsSink(dir)
+	if err := s.WriteHeader(Header{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, pr := range prs {
+		if err := s.WriteProcess(pr); err != nil {
+			return err
+		}
+	}
+	return s.Close()
+}
+
+func TestStubsNames(t *testing.T) {
+	dir := t.TempDir()
+	colon := ProcessReport{Name: "GET /orders/:id -> example.com/shop.getOrder", ID: "1a2b3c4d5e6f"}
+	brace := ProcessReport{Name: "GET /orders/{id} -> example.com/shop.getOrder", ID: "6f5e4d3c2b1a"}
+	if err := writeStubs(t, dir, colon, brace); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Errorf("%d stubs, want one per process", len(files))
+	}
+	if err := writeStubs(t, t.TempDir(), colon, colon); err == nil {
+		t.Errorf("the stub of a process written twice was overwritten")
+	}
+}
+
+// TestStubsRename checks that the regions written by analysts survive a
+// rename of the route of their process.
+func TestStubsRename(t *testing.T) {
+	dir := t.TempDir()
+	pr := ProcessReport{Name: "GET /orders -> example.com/shop.getOrder", ID: "1a2b3c4d5e6f"}
+	if err := writeStubs(t, dir, pr); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(dir, stubFileName(pr.Name, pr.ID))
+	editFile(t, old, stubTODO, "Shows an order to its customer.")
+
+	pr.Name = "GET /v2/orders -> example.com/shop.getOrder"
+	if err := writeStubs(t, dir, pr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("the stub of the old route is kept")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, stubFileName(pr.Name, pr.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Shows an order to its customer.") {
+		t.Errorf("the description is lost:\n%s", data)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Server-side rendering. Executing an html/template or text/template into an
+// HTTP response or a file sends the rendered page out of the software: an
+// Exit, named after the template when its name is constant. Executing into a
+// buffer only manipulates data, which the later write of the buffer moves.
+
+// templatePkgPaths are the import paths of the template engines.
+var templatePkgPaths = []string{"html/template", "text/template"}
+
+// templateExit returns whether the call of fn renders a template out of the
+// software, and the template's name.
+func templateExit(fn *ssa.Function, call *ssa.CallCommon) (group string, ok bool) {
+	if fn.Pkg == nil || !inPaths(fn.Pkg.Pkg.Path(), templatePkgPaths) || fn.Signature.Recv() == nil || len(call.Args) < 2 {
+		return "", false
+	}
+	switch fn.Name() {
+	case "Execute":
+		group = templateName(call.Args[0], 0)
+	case "ExecuteTemplate":
+		if len(call.Args) > 2 {
+			group, _ = constString(call.Args[2])
+		}
+	default:
+		return "", false
+	}
+	return group, leavesSoftware(call.Args[1])
+}
+
+// leavesSoftware reports whether data written to w leaves the software: w is
+// an HTTP response or a file.
+func leavesSoftware(w ssa.Value) bool {
+	for {
+		switch v := w.(type) {
+		case *ssa.MakeInterface:
+			w = v.X
+			continue
+		case *ssa.ChangeInterface:
+			w = v.X
+			continue
+		}
+		break
+	}
+	return isNamedType(w.Type(), "os", "File") || isResponseWriter(w.Type())
+}
+
+// isResponseWriter reports whether t has the methods of an http.ResponseWriter,
+// as the response writers of web frameworks do.
+func isResponseWriter(t types.Type) bool {
+	for _, name := range []string{"Header", "Write", "WriteHeader"} {
+		if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name); obj == nil {
+			return false
+		} else if _, ok := obj.(*types.Func); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// templateName returns the constant name a template was created with, as in
+// template.Must(template.New("page").Parse(src)), or "".
+func templateName(v ssa.Value, depth int) string {
+	if ext, ok := v.(*ssa.Extract); ok {
+		v = ext.Tuple
+	}
+	call, ok := v.(*ssa.Call)
+	if !ok || depth > 8 {
+		return ""
+	}
+	sc := call.Call.StaticCallee()
+	args := call.Call.Args
+	if sc == nil || sc.Pkg == nil || !inPaths(sc.Pkg.Pkg.Path(), templatePkgPaths) || len(args) == 0 {
+		return ""
+	}
+	switch {
+	case sc.Name() == "New":
+		name, _ := constString(args[len(args)-1])
+		return name
+	case sc.Name() == "Must" || sc.Signature.Recv() != nil:
+		// Must(t, err), or a method returning its receiver (Parse, Funcs)
+		return templateName(args[0], depth+1)
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Trend mode. A functional size that grows by a few CFP a commit is the code
+// growing; one that jumps is scope creep nobody noticed, or a tool upgrade
+// detecting movements differently. "trend" measures the last commits of the
+// history of the analyzed code, each in a worktree of its own, and flags the
+// commits whose change of the total size is anomalous: its z-score against
+// the changes of the trailing window reaches -z. For each flagged commit it
+// lists the processes whose size changed most.
+
+// trendPoint is the measurement of a commit.
+type trendPoint struct {
+	commit, subject string
+	cfp             int
+	processes       map[string]ProcessReport
+}
+
+// runTrend implements "trend [flags] [module-root-or-package-pattern]": it
+// prints for each of the last -commits commits of the first-parent history
+// its total size, the change from the previous commit and the z-score of that
+// change, followed for anomalous commits by the processes responsible. It
+// exits with status 1 if a commit was anomalous and -fail is set.
+func runTrend(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	an := &Analyzer{}
+	an.register(fs)
+	commits := fs.Int("commits", 30, "number of commits measured, ending at -rev")
+	rev := fs.String("rev", "HEAD", "last commit measured")
+	window := fs.Int("window", 10, "number of preceding changes of the total size a change is scored against")
+	threshold := fs.Float64("z", 3, "least absolute z-score of an anomalous change")
+	top := fs.Int("top", 5, "number of processes listed as responsible for an anomalous change")
+	fail := fs.Bool("fail", false, "exit with status 1 if a commit is anomalous")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s trend [-commits 30] [-window 10] [-z 3] [flags] [module-root-or-package-pattern]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 || *commits < 2 || *window < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if an.changedFiles != "" || an.cacheFile != "" {
+		log.Fatalf("trend: -changed-files and -cache are not supported; every commit is measured")
+	}
+	if err := an.init(); err != nil {
+		log.Fatal(err)
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+
+	points, err := measureHistory(an, root, *rev, *commits)
+	if err != nil {
+		log.Fatalf("trend: %v", err)
+	}
+	anomalies := 0
+	for i, p := range points {
+		if i == 0 {
+			fmt.Printf("%s\t%d\t\t\t%s\n", p.commit, p.cfp, p.subject)
+			continue
+		}
+		delta := p.cfp - points[i-1].cfp
+		z, scored := trendScore(trendWindow(points, i, *window), delta)
+		if !scored {
+			fmt.Printf("%s\t%d\t%+d\t\t%s\n", p.commit, p.cfp, delta, p.subject)
+			continue
+		}
+		if math.Abs(z) < *threshold {
+			fmt.Printf("%s\t%d\t%+d\t%.1f\t%s\n", p.commit, p.cfp, delta, z, p.subject)
+			continue
+		}
+		anomalies++
+		fmt.Printf("%s\t%d\t%+d\t%.1f\t%s\tanomalous\n", p.commit, p.cfp, delta, z, p.subject)
+		for _, c := range responsibleProcesses(points[i-1].processes, p.processes, *top) {
+			fmt.Printf("\t%s\t%d\t%d\t%+d\n", c.name, c.before, c.after, c.after-c.before)
+		}
+	}
+	log.Printf("%d of %d commits changed the size anomalously (|z| >= %g over %d changes)", anomalies, len(points)-1, *threshold, *window)
+	if anomalies > 0 && *fail {
+		os.Exit(1)
+	}
+}
+
+// measureHistory measures root at the last n commits of the first-parent
+// history ending at rev, oldest first. Each commit is checked out in a
+// temporary worktree, removed once measured; a commit failing to measure is
+// left out.
+func measureHistory(an *Analyzer, root, rev string, n int) ([]trendPoint, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("git", "-C", abs, "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository", root)
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	top, prefix := lines[0], ""
+	if len(lines) > 1 {
+		prefix = lines[1]
+	}
+	out, err = exec.Command("git", "-C", top, "log", "--first-parent", "--reverse", "--format=%h %s", fmt.Sprintf("--max-count=%d", n), rev).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %v", rev, err)
+	}
+	tmp, err := os.MkdirTemp("", "cosmic-trend-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		os.RemoveAll(tmp)
+		exec.Command("git", "-C", top, "worktree", "prune").Run()
+	}()
+
+	var points []trendPoint
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		commit, subject, _ := strings.Cut(line, " ")
+		dir := filepath.Join(tmp, commit)
+		if out, err := exec.Command("git", "-C", top, "worktree", "add", "--detach", dir, commit).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("git worktree add %s: %v: %s", commit, err, strings.TrimSpace(string(out)))
+		}
+		m, err := an.Measure(filepath.Join(dir, filepath.FromSlash(prefix)))
+		exec.Command("git", "-C", top, "worktree", "remove", "--force", dir).Run()
+		if err != nil {
+			log.Printf("trend: %s: %v; left out", commit, err)
+			continue
+		}
+		p := trendPoint{commit: commit, subject: subject, processes: map[string]ProcessReport{}}
+		for _, pr := range m.Processes {
+			p.cfp += pr.cfp()
+			p.processes[cacheKey(pr.Source, pr.Method)] = pr
+		}
+		points = append(points, p)
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("%d commits measured; a trend needs 2", len(points))
+	}
+	return points, nil
+}
+
+// trendWindow returns the points whose changes the change of the size at
+// points[i] is scored against: the last window changes before it.
+func trendWindow(points []trendPoint, i, window int) []trendPoint {
+	return points[max(0, i-1-window):i]
+}
+
+// trendScore returns the z-score of delta against the changes of the total
+// size between the points of the trailing window, or false if it has fewer
+// than 2 changes. The standard deviation is at least 1 CFP, so that a
+// history changing by whole CFP is not flagged for its first change.
+func trendScore(window []trendPoint, delta int) (float64, bool) {
+	if len(window) < 3 {
+		return 0, false
+	}
+	var sum, sq float64
+	for i := 1; i < len(window); i++ {
+		d := float64(window[i].cfp - window[i-1].cfp)
+		sum += d
+		sq += d * d
+	}
+	n := float64(len(window) - 1)
+	mean := sum / n
+	sd := math.Max(math.Sqrt(math.Max(sq/n-mean*mean, 0)), 1)
+	return (float64(delta) - mean) / sd, true
+}
+
+// processChange is the change of the size of a process between two commits.
+type processChange struct {
+	name          string
+	before, after int
+}
+
+// responsibleProcesses returns the n processes whose size changed most from
+// before to after, appearing and disappearing processes included.
+func responsibleProcesses(before, after map[string]ProcessReport, n int) []processChange {
+	var changes []processChange
+	for k, pa := range after {
+		pb, ok := before[k]
+		if c := (processChange{pa.Name, pb.cfp(), pa.cfp()}); c.before != c.after || !ok {
+			changes = append(changes, c)
+		}
+	}
+	for k, pb := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, processChange{pb.Name, pb.cfp(), 0})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		di, dj := math.Abs(float64(changes[i].after-changes[i].before)), math.Abs(float64(changes[j].after-changes[j].before))
+		if di != dj {
+			return di > dj
+		}
+		return changes[i].name < changes[j].name
+	})
+	if len(changes) > n {
+		changes = changes[:n]
+	}
+	return changes
+}
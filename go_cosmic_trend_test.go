@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestTrendScore scores the changes of synthetic histories of total sizes
+// against trailing windows of 3 changes.
+func TestTrendScore(t *testing.T) {
+	unscored := math.NaN()
+	for _, c := range []struct {
+		desc  string
+		sizes []int
+		// z are the z-scores of the changes to the sizes from the second on
+		z []float64
+	}{
+		// the change to 140 is 28 standard deviations (of 1 CFP, the floor)
+		// above the previous ones; once it is in the window, it widens them
+		{"a jump", []int{100, 102, 104, 106, 108, 110, 140, 142, 144, 146, 148},
+			[]float64{unscored, unscored, 0, 0, 0, 28, -0.71, -0.71, -0.71, 0}},
+		// without the floor, a first change after a flat history would be
+		// infinitely anomalous
+		{"a flat history", []int{50, 50, 50, 50, 51, 51},
+			[]float64{unscored, unscored, 0, 1, -0.33}},
+		// a change of 3 CFP reaches the default -z of 3 against changes of 1
+		{"noise", []int{50, 51, 50, 51, 50, 53, 50},
+			[]float64{unscored, unscored, 1, -1.33, 3.33, -2.45}},
+	} {
+		points := make([]trendPoint, len(c.sizes))
+		for i, size := range c.sizes {
+			points[i].cfp = size
+		}
+		for i := 1; i < len(points); i++ {
+			z, scored := trendScore(trendWindow(points, i, 3), points[i].cfp-points[i-1].cfp)
+			want := c.z[i-1]
+			switch {
+			case math.IsNaN(want) && scored:
+				t.Errorf("%s: the change to %d is scored %.2f with fewer than 2 changes before it", c.desc, c.sizes[i], z)
+			case !math.IsNaN(want) && (!scored || math.Abs(z-want) > 0.005):
+				t.Errorf("%s: the change to %d is scored %.2f, %v, want %.2f", c.desc, c.sizes[i], z, scored, want)
+			}
+		}
+	}
+}
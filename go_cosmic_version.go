@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Tool versions. Measurements made by different versions of the tool on
+// different CI agents are not comparable, so every output records the build
+// of the tool that made it: the module version, the VCS revision and the Go
+// version, as the Go toolchain embeds them. "version -check" compares the
+// build with the latest release of the module on the module proxy.
+
+// ToolVersion identifies the build of the tool.
+type ToolVersion struct {
+	Module  string `json:"module,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Revision is the VCS commit the tool was built from; Modified is set
+	// when the working tree had uncommitted changes.
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go,omitempty"`
+}
+
+// toolVersion returns the build of the running tool, or nil if it was built
+// without module support.
+func toolVersion() *ToolVersion {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	v := &ToolVersion{Module: bi.Main.Path, Version: bi.Main.Version, GoVersion: bi.GoVersion}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Revision = s.Value
+		case "vcs.modified":
+			v.Modified = s.Value == "true"
+		}
+	}
+	return v
+}
+
+// String returns the version as "module version (revision, modified) go".
+func (v *ToolVersion) String() string {
+	s := v.Module + " " + v.Version
+	if v.Revision != "" {
+		rev := v.Revision
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		if v.Modified {
+			rev += ", modified"
+		}
+		s += " (" + rev + ")"
+	}
+	return s + " " + v.GoVersion
+}
+
+// readToolVersion returns the build of the tool that made the JSON
+// measurement at path, or nil if it is not recorded.
+func readToolVersion(path string) *ToolVersion {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var out struct{ Tool *ToolVersion }
+	if json.Unmarshal(data, &out) != nil {
+		return nil
+	}
+	return out.Tool
+}
+
+// runVersion implements "version [-check]": it prints the build of the tool
+// and, with -check, whether it is the latest release of its module, exiting
+// with status 1 if a later one is released.
+func runVersion(args []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	check := fs.Bool("check", false, "compare the version with the latest release on the module proxy")
+	proxy := fs.String("proxy", moduleProxy(), "module proxy asked for the latest release")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s version [-check]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	v := toolVersion()
+	if v == nil {
+		log.Fatalf("version: the tool was built without module support")
+	}
+	fmt.Println(v)
+	if !*check {
+		return
+	}
+	latest, err := latestRelease(*proxy, v.Module)
+	if err != nil {
+		log.Fatalf("version: %v", err)
+	}
+	switch {
+	case !isRelease(v.Version):
+		log.Printf("%s is not a release; the latest release is %s", v.Version, latest)
+	case compareVersions(v.Version, latest) < 0:
+		log.Printf("%s is outdated; the latest release is %s: go install %s@latest", v.Version, latest, v.Module)
+		os.Exit(1)
+	default:
+		log.Printf("%s is the latest release", v.Version)
+	}
+}
+
+// moduleProxy returns the first proxy of GOPROXY, or the public Go module
+// proxy.
+func moduleProxy() string {
+	for _, p := range strings.FieldsFunc(os.Getenv("GOPROXY"), func(r rune) bool { return r == ',' || r == '|' }) {
+		if p != "direct" && p != "off" {
+			return p
+		}
+	}
+	return "https://proxy.golang.org"
+}
+
+// latestRelease asks the module proxy for the latest release of module.
+func latestRelease(proxy, module string) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := strings.TrimSuffix(proxy, "/") + "/" + escapeModulePath(module) + "/@latest"
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	var info struct{ Version string }
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("%s: %v", url, err)
+	}
+	return info.Version, nil
+}
+
+// escapeModulePath escapes the upper-case letters of a module path for the
+// module proxy protocol, as !l.
+func escapeModulePath(module string) string {
+	var b strings.Builder
+	for _, r := range module {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pseudoVersion matches the suffix of a pseudo-version, the version of a
+// commit without a release tag.
+var pseudoVersion = regexp.MustCompile(`[-.]\d{14}-[0-9a-f]{12}(\+incompatible)?$`)
+
+// isRelease reports whether v is a release version, v1.2.3, rather than a
+// pseudo-version or a development build, (devel).
+func isRelease(v string) bool {
+	return strings.HasPrefix(v, "v") && strings.Count(v, ".") >= 2 && !pseudoVersion.MatchString(v)
+}
+
+// compareVersions compares the semantic versions a and b, returning -1, 0
+// or +1; a prerelease precedes its release.
+func compareVersions(a, b string) int {
+	split := func(v string) ([3]int, string) {
+		core, _, _ := strings.Cut(strings.TrimPrefix(v, "v"), "+")
+		core, pre, _ := strings.Cut(core, "-")
+		var n [3]int
+		for i, p := range strings.SplitN(core, ".", 3) {
+			n[i], _ = strconv.Atoi(p)
+		}
+		return n, pre
+	}
+	na, pa := split(a)
+	nb, pb := split(b)
+	for i := range na {
+		if na[i] != nb[i] {
+			if na[i] < nb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case pa == pb:
+		return 0
+	case pa == "":
+		return 1
+	case pb == "":
+		return -1
+	case pa < pb:
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// WebSocket messages. A WebSocket handler holds the connection open and
+// exchanges messages with the user: each read of a message is an Entry and
+// each write an Exit (receiveFuncs, sendFuncs). A JSON message is named after
+// the type it is decoded into or encoded from, so a session exchanging chat
+// messages and presence updates moves two data groups. The read and write
+// loops a handler starts on goroutines (readPump, writePump) belong to its
+// process instead of being workers of their own.
+
+// websocketJSON are the functions and methods exchanging JSON messages, by
+// package path, with the index of the message among the call arguments
+// (the receiver included).
+var websocketJSON = map[string]map[string]int{
+	"github.com/gorilla/websocket":      {"ReadJSON": 1, "WriteJSON": 1},
+	"nhooyr.io/websocket/wsjson":        {"Read": 2, "Write": 2},
+	"github.com/coder/websocket/wsjson": {"Read": 2, "Write": 2},
+	// websocket.JSON.Send(ws, v), websocket.JSON.Receive(ws, &v)
+	"golang.org/x/net/websocket": {"Send": 2, "Receive": 2},
+}
+
+// websocketMessage returns the data group of a WebSocket message exchanged by
+// call, and whether call exchanges a JSON message.
+func websocketMessage(call *ssa.CallCommon) (string, bool) {
+	sc := call.StaticCallee()
+	if sc == nil || sc.Pkg == nil {
+		return "", false
+	}
+	idx, ok := websocketJSON[sc.Pkg.Pkg.Path()][sc.Name()]
+	if !ok || idx >= len(call.Args) {
+		return "", false
+	}
+	return entityName(unwrapInterface(call.Args[idx]).Type()), true
+}
+
+// upgradesConnection reports whether fn upgrades a connection to a WebSocket.
+func upgradesConnection(fn *ssa.Function) bool {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok && isUpgrade(call.Common().StaticCallee()) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+import os
+import shutil
+import tempfile
+import unittest
+
+import go_cosmic_eloc_tokei as eloc
+
+TESTDATA = os.path.join(os.path.dirname(os.path.abspath(__file__)), "testdata", "ast")
+
+
+class ASTAnalyzerTest(unittest.TestCase):
+    """Runs the AST analyzer on the fixture modules of testdata/ast, in both
+    its syntactic and typed modes. Building it needs golang.org/x/tools."""
+
+    @classmethod
+    def setUpClass(cls):
+        cls.tmp = tempfile.mkdtemp()
+        eloc.AST_SOURCE_DIR = os.path.join(cls.tmp, "src")
+        eloc.AST_BINARY = os.path.join(cls.tmp, "go_cosmic_ast")
+        eloc.build_ast_analyzer()
+
+    @classmethod
+    def tearDownClass(cls):
+        shutil.rmtree(cls.tmp)
+
+    def count(self, fixture, typed):
+        eloc.AST_TYPED = typed
+        entries, exits, reads, writes = eloc.run_ast_analyzer(os.path.join(TESTDATA, fixture))
+        return {"entries": entries, "exits": exits, "reads": reads, "writes": writes}
+
+    def test_chained(self):
+        # json.NewEncoder(w).Encode(1) is one Write, not one per name
+        # containing Encode.
+        for typed in (False, True):
+            with self.subTest(typed=typed):
+                self.assertEqual(self.count("chained", typed), {"entries": 0, "exits": 0, "reads": 1, "writes": 2})
+
+    def test_nested(self):
+        # The Read in the arguments of the Write is counted too.
+        for typed in (False, True):
+            with self.subTest(typed=typed):
+                self.assertEqual(self.count("nested", typed), {"entries": 1, "exits": 0, "reads": 1, "writes": 1})
+
+
+if __name__ == "__main__":
+    unittest.main()
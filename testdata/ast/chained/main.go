@@ -0,0 +1,23 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+)
+
+type store struct{ db *sql.DB }
+
+// count is a Read through a nested selector.
+func (s *store) count() {
+	s.db.Query("SELECT count(*) FROM items")
+}
+
+// handler makes two Writes: the Encode on the encoder, not its NewEncoder,
+// and the Write.
+func handler(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(1)
+	w.Write(nil)
+}
+
+func main() {}
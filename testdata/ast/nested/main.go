@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// handler makes a Write of a Read, nested in its arguments.
+func handler(w http.ResponseWriter, r *http.Request) {
+	w.Write(must(os.ReadFile(r.FormValue("name"))))
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func main() {
+	http.HandleFunc("/", handler)
+}
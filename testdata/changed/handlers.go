@@ -0,0 +1,16 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+func getOrder(w http.ResponseWriter, r *http.Request) {
+	b, _ := os.ReadFile("orders/" + r.PathValue("id"))
+	w.Write(b)
+}
+
+func addOrder(w http.ResponseWriter, r *http.Request) {
+	os.WriteFile("orders/"+r.FormValue("id"), nil, 0o644)
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,9 @@
+package main
+
+import "net/http"
+
+func main() {
+	http.HandleFunc("GET /orders", getOrder)
+	http.HandleFunc("POST /orders", addOrder)
+	http.ListenAndServe(":8080", nil)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+)
+
+func logging(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		next(w, r)
+	}
+}
+
+func auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func getOrder(w http.ResponseWriter, r *http.Request) {
+	b, _ := os.ReadFile("orders/" + r.PathValue("id"))
+	w.Write(b)
+}
+
+func addOrder(w http.ResponseWriter, r *http.Request) {
+	os.WriteFile("orders/"+r.FormValue("id"), nil, 0o644)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func main() {
+	http.HandleFunc("GET /orders", logging(auth(getOrder)))
+	http.HandleFunc("POST /orders", logging(addOrder))
+	http.ListenAndServe(":8080", nil)
+}
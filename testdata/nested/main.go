@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+func main() {
+	http.HandleFunc("GET /items/{id}", getItem)
+	http.ListenAndServe(":8080", nil)
+}
+
+// getItem reads the item file named by the request and writes it back, with
+// every movement nested in the arguments of the next.
+func getItem(w http.ResponseWriter, r *http.Request) {
+	w.Write(must(os.ReadFile(r.PathValue("id"))))
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
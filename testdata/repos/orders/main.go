@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+type Order struct{ ID string }
+
+type OrderStore interface {
+	SaveOrder(o *Order) error
+}
+
+type fileStore struct{}
+
+func (fileStore) SaveOrder(o *Order) error {
+	return os.WriteFile(o.ID, nil, 0o644)
+}
+
+var store OrderStore = fileStore{}
+
+func main() {
+	http.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		store.SaveOrder(&Order{ID: r.FormValue("id")})
+		w.WriteHeader(http.StatusCreated)
+	})
+	http.ListenAndServe(":8080", nil)
+}
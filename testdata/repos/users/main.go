@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+type User struct{ Name string }
+
+type UserStore interface {
+	GetUser(id string) (*User, error)
+}
+
+type fileStore struct{}
+
+func (fileStore) GetUser(id string) (*User, error) {
+	b, err := os.ReadFile(id)
+	return &User{Name: string(b)}, err
+}
+
+var store UserStore = fileStore{}
+
+func main() {
+	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		u, _ := store.GetUser(r.URL.Query().Get("id"))
+		w.Write([]byte(u.Name))
+	})
+	http.ListenAndServe(":8080", nil)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+func main() {
+	http.HandleFunc("/charge", chargeCustomer)
+	http.ListenAndServe(":8080", nil)
+}
+
+func chargeCustomer(w http.ResponseWriter, r *http.Request) {
+	prices := loadSecretPricing()
+	w.Write(prices)
+}
+
+func loadSecretPricing() []byte {
+	b, _ := os.ReadFile("pricing.json")
+	return b
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+func main() {
+	http.HandleFunc("/orders", listOrders)
+	undefined()
+}
+
+func listOrders(w http.ResponseWriter, r *http.Request) {
+	b, _ := os.ReadFile("orders.json")
+	w.Write([]byte(strings.ToUpper(string(b))))
+}